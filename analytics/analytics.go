@@ -0,0 +1,300 @@
+package analytics
+
+import (
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mini_jump/logger"
+)
+
+// 统计维度
+const (
+	DimDomain  = "domain"  // 请求域名
+	DimRule    = "rule"    // 命中的规则 ID
+	DimReferer = "referer" // 来源页面的主机名（直接访问时为空）
+	DimUA      = "ua"      // 客户端类型：desktop、mobile、tablet、bot、unknown
+	DimCountry = "country" // 国家/地区代码（来自 CDN 请求头，未配置时为空）
+	DimStatus  = "status"  // 响应状态码
+)
+
+// Dimensions 支持的统计维度
+var Dimensions = []string{DimDomain, DimRule, DimReferer, DimUA, DimCountry, DimStatus}
+
+// 客户端类型
+const (
+	UABot     = "bot"
+	UAMobile  = "mobile"
+	UATablet  = "tablet"
+	UADesktop = "desktop"
+	UAUnknown = "unknown"
+)
+
+// botMarkers User-Agent 中表示爬虫或程序化客户端的关键字（小写）
+var botMarkers = []string{"bot", "spider", "crawl", "slurp", "curl", "wget", "python", "go-http-client", "java/", "httpclient", "headless", "okhttp"}
+
+// Key 汇总键：一小时内维度取值完全相同的访问合并为一条
+type Key struct {
+	Domain  string
+	Rule    string
+	Referer string
+	UA      string
+	Country string
+	Status  int
+}
+
+// KeyOf 从访问日志提取汇总键（域名去掉端口）
+func KeyOf(entry *logger.AccessLog) Key {
+	domain := strings.ToLower(entry.Domain)
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+	return Key{
+		Domain:  domain,
+		Rule:    entry.RuleID,
+		Referer: RefererHost(entry.Referer),
+		UA:      ClassifyUserAgent(entry.UserAgent),
+		Country: strings.ToUpper(entry.Country),
+		Status:  entry.StatusCode,
+	}
+}
+
+// value 返回维度的取值
+func (k Key) value(dim string) string {
+	switch dim {
+	case DimDomain:
+		return k.Domain
+	case DimRule:
+		return k.Rule
+	case DimReferer:
+		return k.Referer
+	case DimUA:
+		return k.UA
+	case DimCountry:
+		return k.Country
+	case DimStatus:
+		return strconv.Itoa(k.Status)
+	}
+	return ""
+}
+
+// RefererHost 返回来源页面的主机名（小写），无法解析时为空
+func RefererHost(referer string) string {
+	if referer == "" {
+		return ""
+	}
+	u, err := url.Parse(referer)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// ClassifyUserAgent 根据 User-Agent 粗略判断客户端类型
+func ClassifyUserAgent(ua string) string {
+	ua = strings.ToLower(ua)
+	if ua == "" {
+		return UAUnknown
+	}
+	for _, marker := range botMarkers {
+		if strings.Contains(ua, marker) {
+			return UABot
+		}
+	}
+	switch {
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet") ||
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return UATablet
+	case strings.Contains(ua, "mobi") || strings.Contains(ua, "iphone") || strings.Contains(ua, "android"):
+		return UAMobile
+	}
+	return UADesktop
+}
+
+// hourRollup 一小时内各汇总键的访问次数
+type hourRollup map[Key]int64
+
+// dayRollup 一天（UTC）内按小时的汇总，键为小时起始的 Unix 时间
+type dayRollup struct {
+	hours map[int64]hourRollup
+	dirty bool // 有未保存的修改
+}
+
+// Aggregator 访问统计汇总器
+// 按小时汇总访问日志，最近的数据保存在内存中，定期按天写入压缩文件，查询时无需扫描原始访问日志
+type Aggregator struct {
+	dir       string
+	retention int // 保留天数（0 表示永久保留）
+	mu        sync.Mutex
+	days      map[string]*dayRollup // UTC 日期 -> 内存中的汇总（最近两天及有未保存修改的日期）
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Open 打开统计目录（不存在时创建），每隔 interval 保存一次
+// interval 不大于 0 时只在 Close 时保存；retentionDays 为 0 时永久保留
+func Open(dir string, interval time.Duration, retentionDays int) (*Aggregator, error) {
+	if err := ensureDir(dir); err != nil {
+		return nil, err
+	}
+	a := &Aggregator{
+		dir:       dir,
+		retention: retentionDays,
+		days:      make(map[string]*dayRollup),
+		done:      make(chan struct{}),
+	}
+	if interval > 0 {
+		a.wg.Add(1)
+		go a.flushPeriodically(interval)
+	}
+	return a, nil
+}
+
+// Add 汇总一条访问日志（为 nil 时忽略）
+func (a *Aggregator) Add(entry *logger.AccessLog) {
+	if a == nil {
+		return
+	}
+	hour := entry.Timestamp.Truncate(time.Hour)
+	key := KeyOf(entry)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	day := a.dayLocked(dayOf(hour))
+	rollup := day.hours[hour.Unix()]
+	if rollup == nil {
+		rollup = make(hourRollup)
+		day.hours[hour.Unix()] = rollup
+	}
+	rollup[key]++
+	day.dirty = true
+}
+
+// dayLocked 返回内存中某天的汇总，不在内存中时从文件加载（需在锁内调用）
+func (a *Aggregator) dayLocked(date string) *dayRollup {
+	if day, ok := a.days[date]; ok {
+		return day
+	}
+	day := &dayRollup{hours: make(map[int64]hourRollup)}
+	hours, err := readDay(a.dir, date)
+	if err != nil {
+		// 保留损坏的文件以便排查，重新开始统计当天的数据
+		path := dayFile(a.dir, date)
+		log.Printf("Analytics: failed to read rollup %s, moved to %s.corrupt: %v\n", path, path, err)
+		os.Rename(path, path+".corrupt")
+	} else if hours != nil {
+		day.hours = hours
+	}
+	a.days[date] = day
+	return day
+}
+
+// Flush 保存有修改的汇总，释放不再写入的日期，并清理超过保留天数的文件
+func (a *Aggregator) Flush() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// 之前的日期一般不会再写入（时钟回拨或日志延迟时会重新加载）
+	keep := dayOf(time.Now().Add(-24 * time.Hour))
+	var firstErr error
+	for date, day := range a.days {
+		if day.dirty {
+			if err := writeDay(a.dir, date, day.hours); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			day.dirty = false
+		}
+		if date < keep {
+			delete(a.days, date)
+		}
+	}
+	if a.retention > 0 {
+		oldest := dayOf(time.Now().AddDate(0, 0, -a.retention))
+		if err := pruneDays(a.dir, oldest); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close 停止定期保存并保存最后一次
+func (a *Aggregator) Close() error {
+	if a == nil {
+		return nil
+	}
+	close(a.done)
+	a.wg.Wait()
+	return a.Flush()
+}
+
+// flushPeriodically 定期保存汇总
+func (a *Aggregator) flushPeriodically(interval time.Duration) {
+	defer a.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.Flush(); err != nil {
+				log.Printf("Analytics: failed to save rollups: %v\n", err)
+			}
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// scan 依次返回 [from, to) 内每小时的汇总（内存中的日期取副本，其余从文件读取）
+func (a *Aggregator) scan(from, to time.Time, fn func(hour time.Time, rollup hourRollup)) error {
+	for date := dayOf(from); date <= dayOf(to.Add(-time.Nanosecond)); date = nextDay(date) {
+		a.mu.Lock()
+		var hours map[int64]hourRollup
+		if day, ok := a.days[date]; ok {
+			hours = make(map[int64]hourRollup, len(day.hours))
+			for hour, rollup := range day.hours {
+				copied := make(hourRollup, len(rollup))
+				for key, n := range rollup {
+					copied[key] = n
+				}
+				hours[hour] = copied
+			}
+		}
+		a.mu.Unlock()
+
+		if hours == nil {
+			var err error
+			if hours, err = readDay(a.dir, date); err != nil {
+				return err
+			}
+		}
+		for unix, rollup := range hours {
+			hour := time.Unix(unix, 0)
+			if !hour.Before(from) && hour.Before(to) {
+				fn(hour, rollup)
+			}
+		}
+	}
+	return nil
+}
+
+// dayOf 返回时间所在的 UTC 日期
+func dayOf(t time.Time) string {
+	return t.UTC().Format(dateLayout)
+}
+
+// nextDay 返回下一个 UTC 日期
+func nextDay(date string) string {
+	t, _ := time.Parse(dateLayout, date)
+	return t.AddDate(0, 0, 1).Format(dateLayout)
+}
@@ -0,0 +1,281 @@
+package analytics
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"mini_jump/logger"
+)
+
+// base 测试数据的起始时间（UTC 整点，早于“昨天”，Flush 后不再留在内存中）
+var base = time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)
+
+// openTestAggregator 在临时目录中打开只在 Close 时保存的汇总器
+func openTestAggregator(t *testing.T, dir string, retentionDays int) *Aggregator {
+	t.Helper()
+	a, err := Open(dir, 0, retentionDays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+// addTestEntries 汇总测试用的访问日志：跨越两个 UTC 日期的三个小时
+func addTestEntries(a *Aggregator) {
+	for _, entry := range []*logger.AccessLog{
+		{Timestamp: base.Add(5 * time.Minute), Domain: "Example.com:8080", RuleID: "a", StatusCode: 301, UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0)", Referer: "https://Search.example.net/q", Country: "cn"},
+		{Timestamp: base.Add(10 * time.Minute), Domain: "example.com", RuleID: "a", StatusCode: 301, UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0)", Referer: "https://search.example.net/other", Country: "CN"},
+		{Timestamp: base.Add(50 * time.Minute), Domain: "shop.example.com", RuleID: "b", StatusCode: 302, UserAgent: "curl/8.0"},
+		{Timestamp: base.Add(time.Hour + time.Minute), Domain: "shop.example.com", RuleID: "b", StatusCode: 302, UserAgent: "Mozilla/5.0 (Windows NT 10.0)"},
+		{Timestamp: base.Add(2*time.Hour + time.Minute), Domain: "other.org", RuleID: "c", StatusCode: 404},
+		{Timestamp: base.Add(2*time.Hour + 2*time.Minute), Domain: "example.com", RuleID: "a", StatusCode: 301, UserAgent: "Googlebot/2.1"},
+	} {
+		a.Add(entry)
+	}
+}
+
+// query 校验并执行查询
+func query(t *testing.T, a *Aggregator, q Query, visible func(domain string) bool) *Result {
+	t.Helper()
+	if errs := q.Validate(time.Now()); len(errs) > 0 {
+		t.Fatalf("Validate = %v", errs)
+	}
+	result, err := a.Query(q, visible)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// groups 返回分组维度取值与访问量
+func groups(result *Result, dim string) map[string]int64 {
+	out := make(map[string]int64)
+	for _, row := range result.Rows {
+		out[row.Dimensions[dim]] += row.Hits
+	}
+	return out
+}
+
+func TestQuery(t *testing.T) {
+	a := openTestAggregator(t, t.TempDir(), 0)
+	addTestEntries(a)
+	from, to := base, base.Add(3*time.Hour)
+
+	for _, tt := range []struct {
+		name    string
+		q       Query
+		visible func(domain string) bool
+		total   int64
+		dim     string
+		want    map[string]int64
+	}{
+		{"by domain", Query{GroupBy: []string{DimDomain}}, nil, 6, DimDomain, map[string]int64{"example.com": 3, "shop.example.com": 2, "other.org": 1}},
+		{"by referer", Query{GroupBy: []string{DimReferer}}, nil, 6, DimReferer, map[string]int64{"search.example.net": 2, "": 4}},
+		{"by ua", Query{GroupBy: []string{DimUA}}, nil, 6, DimUA, map[string]int64{UAMobile: 2, UABot: 2, UADesktop: 1, UAUnknown: 1}},
+		{"by country", Query{GroupBy: []string{DimCountry}}, nil, 6, DimCountry, map[string]int64{"CN": 2, "": 4}},
+		{"by status", Query{GroupBy: []string{DimStatus}}, nil, 6, DimStatus, map[string]int64{"301": 3, "302": 2, "404": 1}},
+		{"subdomain filter", Query{Domain: "*.example.com", GroupBy: []string{DimRule}}, nil, 2, DimRule, map[string]int64{"b": 2}},
+		{"rule filter", Query{RuleID: "a", GroupBy: []string{DimDomain}}, nil, 3, DimDomain, map[string]int64{"example.com": 3}},
+		{"visible domains", Query{GroupBy: []string{DimDomain}}, func(domain string) bool { return domain != "example.com" }, 3, DimDomain, map[string]int64{"shop.example.com": 2, "other.org": 1}},
+		// 只返回访问量最高的分组，总数仍包括其余分组
+		{"limit", Query{GroupBy: []string{DimRule}, Limit: 1}, nil, 6, DimRule, map[string]int64{"a": 3}},
+		{"time range", Query{From: base.Add(time.Hour), To: base.Add(2 * time.Hour), GroupBy: []string{DimRule}}, nil, 1, DimRule, map[string]int64{"b": 1}},
+	} {
+		q := tt.q
+		if q.From.IsZero() {
+			q.From, q.To = from, to
+		}
+		result := query(t, a, q, tt.visible)
+		if result.Total != tt.total {
+			t.Errorf("%s: total = %d, want %d", tt.name, result.Total, tt.total)
+		}
+		if got := groups(result, tt.dim); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: rows = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// 分组按访问量从高到低排列
+	result := query(t, a, Query{From: from, To: to, GroupBy: []string{DimDomain}}, nil)
+	if result.Rows[0].Dimensions[DimDomain] != "example.com" || result.Rows[2].Dimensions[DimDomain] != "other.org" {
+		t.Errorf("rows not sorted by hits: %+v", result.Rows)
+	}
+}
+
+func TestQueryInterval(t *testing.T) {
+	a := openTestAggregator(t, t.TempDir(), 0)
+	addTestEntries(a)
+
+	// 按小时返回每个时间段，包括访问量为 0 的时间段
+	result := query(t, a, Query{From: base.Add(-time.Hour), To: base.Add(3 * time.Hour), Interval: IntervalHour}, nil)
+	var hourly []int64
+	for i, row := range result.Rows {
+		if !row.Time.Equal(base.Add(time.Duration(i-1) * time.Hour)) {
+			t.Errorf("row %d time = %v", i, row.Time)
+		}
+		hourly = append(hourly, row.Hits)
+	}
+	if want := []int64{0, 3, 1, 2}; !reflect.DeepEqual(hourly, want) {
+		t.Errorf("hourly hits = %v, want %v", hourly, want)
+	}
+
+	// 分组时只返回有访问的时间段
+	result = query(t, a, Query{From: base, To: base.Add(3 * time.Hour), Interval: IntervalHour, GroupBy: []string{DimRule}}, nil)
+	var cells []string
+	for _, row := range result.Rows {
+		cells = append(cells, row.Time.UTC().Format("15")+"/"+row.Dimensions[DimRule]+"="+strconv.FormatInt(row.Hits, 10))
+	}
+	if want := []string{"22/a=2", "22/b=1", "23/b=1", "00/a=1", "00/c=1"}; !reflect.DeepEqual(cells, want) {
+		t.Errorf("hourly cells = %v, want %v", cells, want)
+	}
+
+	// 按天时以服务器本地时区的日期划分
+	result = query(t, a, Query{From: base, To: base.Add(3 * time.Hour), Interval: IntervalDay}, nil)
+	want := make(map[time.Time]int64)
+	for hour, hits := range map[time.Duration]int64{0: 3, time.Hour: 1, 2 * time.Hour: 2} {
+		t := base.Add(hour).In(time.Local)
+		want[time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)] += hits
+	}
+	got := make(map[time.Time]int64)
+	for _, row := range result.Rows {
+		got[*row.Time] += row.Hits
+	}
+	if len(got) != len(want) {
+		t.Fatalf("daily rows = %v, want %v", got, want)
+	}
+	for day, hits := range want {
+		if got[day] != hits {
+			t.Errorf("hits on %s = %d, want %d", day.Format("2006-01-02"), got[day], hits)
+		}
+	}
+}
+
+func TestRollupPersistence(t *testing.T) {
+	dir := t.TempDir()
+	a, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addTestEntries(a)
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// 过去的日期写入文件后释放，查询时从文件读取
+	if len(a.days) != 0 {
+		t.Errorf("%d days still in memory after Flush", len(a.days))
+	}
+	for _, date := range []string{"2026-03-01", "2026-03-02"} {
+		if _, err := os.Stat(dayFile(dir, date)); err != nil {
+			t.Errorf("rollup of %s: %v", date, err)
+		}
+	}
+	q := Query{From: base, To: base.Add(3 * time.Hour), GroupBy: []string{DimRule}}
+	want := groups(query(t, a, q, nil), DimRule)
+
+	// 重新打开后数据不变，继续写入同一天时在已有数据上累加
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	a = openTestAggregator(t, dir, 0)
+	if got := groups(query(t, a, q, nil), DimRule); !reflect.DeepEqual(got, want) {
+		t.Errorf("after reopen: %v, want %v", got, want)
+	}
+	a.Add(&logger.AccessLog{Timestamp: base.Add(time.Minute), Domain: "example.com", RuleID: "a", StatusCode: 301})
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want["a"]++
+	if got := groups(query(t, a, q, nil), DimRule); !reflect.DeepEqual(got, want) {
+		t.Errorf("after adding to a saved day: %v, want %v", got, want)
+	}
+}
+
+func TestRollupRetentionAndCorruption(t *testing.T) {
+	dir := t.TempDir()
+	a := openTestAggregator(t, dir, 30)
+	old := time.Now().AddDate(0, 0, -40)
+	a.Add(&logger.AccessLog{Timestamp: old, Domain: "example.com", RuleID: "a"})
+	a.Add(&logger.AccessLog{Timestamp: time.Now(), Domain: "example.com", RuleID: "a"})
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// 超过保留天数的文件被清理
+	if _, err := os.Stat(dayFile(dir, dayOf(old))); !os.IsNotExist(err) {
+		t.Errorf("rollup older than retention kept: %v", err)
+	}
+	if _, err := os.Stat(dayFile(dir, dayOf(time.Now()))); err != nil {
+		t.Errorf("today's rollup: %v", err)
+	}
+
+	// 损坏的文件改名保留，当天重新开始统计
+	path := dayFile(dir, dayOf(base))
+	if err := os.WriteFile(path, []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	q := Query{From: base, To: base.Add(time.Hour)}
+	if _, err := a.Query(q, nil); err == nil {
+		t.Error("query over a corrupt rollup succeeded")
+	}
+	a.Add(&logger.AccessLog{Timestamp: base, Domain: "example.com", RuleID: "a"})
+	if _, err := os.Stat(path + ".corrupt"); err != nil {
+		t.Errorf("corrupt rollup not kept: %v", err)
+	}
+	if result := query(t, a, q, nil); result.Total != 1 {
+		t.Errorf("total after corruption = %d, want 1", result.Total)
+	}
+	if got, _ := filepath.Glob(filepath.Join(dir, "*.tmp*")); len(got) != 0 {
+		t.Errorf("temporary files left: %v", got)
+	}
+}
+
+func TestClassifyUserAgent(t *testing.T) {
+	for ua, want := range map[string]string{
+		"":                                    UAUnknown,
+		"Mozilla/5.0 (Windows NT 10.0)":       UADesktop,
+		"Mozilla/5.0 (iPhone; CPU iPhone OS)": UAMobile,
+		"Mozilla/5.0 (Linux; Android 14) Mobile Safari": UAMobile,
+		"Mozilla/5.0 (Linux; Android 14) Safari":        UATablet,
+		"Mozilla/5.0 (iPad; CPU OS 17_0)":               UATablet,
+		"Googlebot/2.1":                                 UABot,
+		"python-requests/2.31":                          UABot,
+		"Mozilla/5.0 HeadlessChrome/120":                UABot,
+	} {
+		if got := ClassifyUserAgent(ua); got != want {
+			t.Errorf("ClassifyUserAgent(%q) = %s, want %s", ua, got, want)
+		}
+	}
+}
+
+func TestQueryValidate(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)
+	q := Query{}
+	if errs := q.Validate(now); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	// 默认查询最近 24 小时，结束时间向上取整到小时
+	if !q.To.Equal(now.Add(30*time.Minute)) || !q.From.Equal(q.To.Add(-DefaultRange)) || q.Limit != DefaultLimit {
+		t.Errorf("defaults = %v - %v, limit %d", q.From, q.To, q.Limit)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		q     Query
+		field string
+	}{
+		{"reversed range", Query{From: now, To: now.Add(-time.Hour)}, "from"},
+		{"range too long", Query{From: now.Add(-MaxRange - time.Hour), To: now}, "to"},
+		{"interval", Query{Interval: "week"}, "interval"},
+		{"dimension", Query{GroupBy: []string{"path"}}, "group_by"},
+		{"duplicate dimension", Query{GroupBy: []string{DimRule, DimRule}}, "group_by"},
+		{"limit", Query{Limit: MaxLimit + 1}, "limit"},
+	} {
+		errs := tt.q.Validate(now)
+		if len(errs) != 1 || errs[0].Field != tt.field {
+			t.Errorf("%s: errors = %v, want one on %s", tt.name, errs, tt.field)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mini_jump/config"
+)
+
+// 时间粒度
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day" // 按服务器本地时区的日期
+)
+
+// 查询限制
+const (
+	DefaultRange = 24 * time.Hour       // 未指定时间范围时查询最近 24 小时
+	MaxRange     = 366 * 24 * time.Hour // 最大时间范围
+	DefaultLimit = 10
+	MaxLimit     = 1000
+)
+
+// Query 统计查询条件
+type Query struct {
+	From     time.Time // 起始时间（含），按小时向下取整
+	To       time.Time // 结束时间（不含），按小时向上取整
+	Interval string    // 时间粒度：hour、day，为空时汇总整个时间范围
+	GroupBy  []string  // 分组维度，为空时不分组
+	Domain   string    // 只统计该域名，支持 *.example.com 匹配子域名
+	RuleID   string    // 只统计该规则
+	Limit    int       // 最多返回的分组数（按访问量从高到低）
+}
+
+// Row 一个分组（及时间段）的访问量
+type Row struct {
+	Time       *time.Time        `json:"time,omitempty"`       // 时间段起始（按时间粒度查询时）
+	Dimensions map[string]string `json:"dimensions,omitempty"` // 分组维度的取值（status 为字符串）
+	Hits       int64             `json:"hits"`
+}
+
+// Result 统计查询结果
+type Result struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Interval string    `json:"interval,omitempty"`
+	GroupBy  []string  `json:"group_by"`
+	Total    int64     `json:"total"` // 时间范围内满足条件的总访问量（包括未返回的分组）
+	Rows     []Row     `json:"rows"`
+}
+
+// Validate 校验查询条件并补全默认值
+func (q *Query) Validate(now time.Time) config.ValidationErrors {
+	var errs config.ValidationErrors
+	if q.To.IsZero() {
+		q.To = now
+	}
+	if t := q.To.Truncate(time.Hour); t.Before(q.To) {
+		q.To = t.Add(time.Hour)
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-DefaultRange)
+	}
+	q.From = q.From.Truncate(time.Hour)
+	if !q.From.Before(q.To) {
+		errs = append(errs, config.FieldError{Field: "from", Message: "必须早于 to"})
+	} else if q.To.Sub(q.From) > MaxRange {
+		errs = append(errs, config.FieldError{Field: "to", Message: "时间范围不能超过 366 天"})
+	}
+
+	switch q.Interval {
+	case "", IntervalHour, IntervalDay:
+	default:
+		errs = append(errs, config.FieldError{Field: "interval", Message: "必须是 hour 或 day"})
+	}
+	seen := make(map[string]bool)
+	for _, dim := range q.GroupBy {
+		if !isDimension(dim) {
+			errs = append(errs, config.FieldError{Field: "group_by", Message: fmt.Sprintf("不支持的维度 %s（可选 %s）", dim, strings.Join(Dimensions, "、"))})
+		} else if seen[dim] {
+			errs = append(errs, config.FieldError{Field: "group_by", Message: "维度 " + dim + " 重复"})
+		}
+		seen[dim] = true
+	}
+
+	if q.Limit == 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxLimit {
+		errs = append(errs, config.FieldError{Field: "limit", Message: fmt.Sprintf("必须在 1 到 %d 之间", MaxLimit)})
+	}
+	return errs
+}
+
+// Query 按条件查询统计，visible 用于按域名过滤权限（为 nil 时不过滤）
+// 调用前需先调用 Validate
+// 不分组时按时间粒度返回每个时间段（包括访问量为 0 的时间段）；
+// 分组时返回访问量最高的 Limit 个分组，按时间粒度查询时返回这些分组在各时间段的访问量
+func (a *Aggregator) Query(q Query, visible func(domain string) bool) (*Result, error) {
+	result := &Result{From: q.From, To: q.To, Interval: q.Interval, GroupBy: q.GroupBy, Rows: []Row{}}
+	if result.GroupBy == nil {
+		result.GroupBy = []string{}
+	}
+
+	type cell struct {
+		bucket int64
+		group  string
+	}
+	groupTotals := make(map[string]int64)
+	groupValues := make(map[string]map[string]string)
+	cells := make(map[cell]int64)
+	err := a.scan(q.From, q.To, func(hour time.Time, rollup hourRollup) {
+		bucket := q.bucket(hour).Unix()
+		for key, hits := range rollup {
+			if q.Domain != "" && !matchDomain(q.Domain, key.Domain) || q.RuleID != "" && key.Rule != q.RuleID {
+				continue
+			}
+			if visible != nil && !visible(key.Domain) {
+				continue
+			}
+			parts := make([]string, len(q.GroupBy))
+			for i, dim := range q.GroupBy {
+				parts[i] = key.value(dim)
+			}
+			group := strings.Join(parts, "\x00")
+			if _, ok := groupValues[group]; !ok && len(q.GroupBy) > 0 {
+				values := make(map[string]string, len(q.GroupBy))
+				for i, dim := range q.GroupBy {
+					values[dim] = parts[i]
+				}
+				groupValues[group] = values
+			}
+			groupTotals[group] += hits
+			cells[cell{bucket, group}] += hits
+			result.Total += hits
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 按访问量选出前 Limit 个分组
+	groups := make([]string, 0, len(groupTotals))
+	for group := range groupTotals {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groupTotals[groups[i]] != groupTotals[groups[j]] {
+			return groupTotals[groups[i]] > groupTotals[groups[j]]
+		}
+		return groups[i] < groups[j]
+	})
+	if len(q.GroupBy) > 0 && len(groups) > q.Limit {
+		groups = groups[:q.Limit]
+	}
+
+	if q.Interval == "" {
+		for _, group := range groups {
+			result.Rows = append(result.Rows, Row{Dimensions: groupValues[group], Hits: groupTotals[group]})
+		}
+		return result, nil
+	}
+
+	// 按时间段展开，不分组时补齐访问量为 0 的时间段
+	var buckets []time.Time
+	for t := q.bucket(q.From); t.Before(q.To); t = q.nextBucket(t) {
+		buckets = append(buckets, t)
+	}
+	if len(q.GroupBy) == 0 {
+		groups = []string{""}
+	}
+	for _, bucket := range buckets {
+		for _, group := range groups {
+			hits := cells[cell{bucket.Unix(), group}]
+			if hits == 0 && len(q.GroupBy) > 0 {
+				continue
+			}
+			t := bucket
+			result.Rows = append(result.Rows, Row{Time: &t, Dimensions: groupValues[group], Hits: hits})
+		}
+	}
+	return result, nil
+}
+
+// bucket 返回时间所在时间段的起始时间
+func (q *Query) bucket(t time.Time) time.Time {
+	if q.Interval == IntervalDay {
+		y, m, d := t.In(time.Local).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+	return t.Truncate(time.Hour)
+}
+
+// nextBucket 返回下一个时间段的起始时间
+func (q *Query) nextBucket(t time.Time) time.Time {
+	if q.Interval == IntervalDay {
+		return t.AddDate(0, 0, 1)
+	}
+	return t.Add(time.Hour)
+}
+
+// isDimension 判断是否为支持的统计维度
+func isDimension(dim string) bool {
+	for _, d := range Dimensions {
+		if d == dim {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDomain 判断域名是否满足过滤条件（精确匹配，或 *.example.com 匹配其子域名）
+func matchDomain(filter, domain string) bool {
+	if suffix, ok := strings.CutPrefix(filter, "*."); ok {
+		return strings.HasSuffix(domain, "."+suffix)
+	}
+	return domain == filter
+}
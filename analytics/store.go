@@ -0,0 +1,105 @@
+package analytics
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mini_jump/atomicfile"
+)
+
+// dateLayout 汇总文件按 UTC 日期命名
+const dateLayout = "2006-01-02"
+
+// fileSuffix 汇总文件后缀：gzip 压缩的 gob 编码
+const fileSuffix = ".rollup.gz"
+
+// record 汇总文件中的一条记录
+type record struct {
+	Hour int64 // 小时起始的 Unix 时间
+	Key  Key
+	Hits int64
+}
+
+// ensureDir 创建统计目录
+func ensureDir(dir string) error {
+	return os.MkdirAll(dir, 0755)
+}
+
+// dayFile 返回某天汇总文件的路径
+func dayFile(dir, date string) string {
+	return filepath.Join(dir, date+fileSuffix)
+}
+
+// readDay 读取某天的汇总，文件不存在时返回 nil
+func readDay(dir, date string) (map[int64]hourRollup, error) {
+	file, err := os.Open(dayFile(dir, date))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	var records []record
+	if err := gob.NewDecoder(zr).Decode(&records); err != nil {
+		return nil, err
+	}
+
+	hours := make(map[int64]hourRollup)
+	for _, rec := range records {
+		rollup := hours[rec.Hour]
+		if rollup == nil {
+			rollup = make(hourRollup)
+			hours[rec.Hour] = rollup
+		}
+		rollup[rec.Key] += rec.Hits
+	}
+	return hours, nil
+}
+
+// writeDay 写入某天的汇总（先写临时文件再替换，避免写坏）
+func writeDay(dir, date string, hours map[int64]hourRollup) error {
+	var records []record
+	for hour, rollup := range hours {
+		for key, hits := range rollup {
+			records = append(records, record{Hour: hour, Key: key, Hits: hits})
+		}
+	}
+
+	return atomicfile.Write(dayFile(dir, date), func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		if err := gob.NewEncoder(zw).Encode(records); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+}
+
+// pruneDays 删除早于 oldest 的汇总文件
+func pruneDays(dir, oldest string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		date, ok := strings.CutSuffix(entry.Name(), fileSuffix)
+		if !ok || date >= oldest {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
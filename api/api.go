@@ -2,43 +2,143 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	"mini_jump/analytics"
+	"mini_jump/auth"
 	"mini_jump/config"
+	"mini_jump/handler"
+	"mini_jump/hits"
+	"mini_jump/logger"
+	"mini_jump/metrics"
+	"mini_jump/webhook"
 )
 
 // API API 管理接口
 type API struct {
-	config *config.Config
+	config   *config.Config
+	redirect *handler.Handler
+	auth     *auth.Authenticator
+	audit    *logger.Logger        // 审计日志（为 nil 时不记录）
+	hooks    *webhook.Dispatcher   // Webhook 投递器（为 nil 时不发送事件）
+	access   *logger.Logger        // 访问日志（为 nil 时不提供实时日志）
+	hits     *hits.Counter         // 规则命中计数（为 nil 时不返回命中统计）
+	stats    *analytics.Aggregator // 访问统计（为 nil 时不提供统计接口）
+	metrics  *metrics.Metrics      // 监控指标（为 nil 时不记录）
+	proxies  auth.TrustedProxies   // 可信反向代理（为空时不采用 X-Forwarded-Host）
 }
 
 // NewAPI 创建 API 处理器
-func NewAPI(cfg *config.Config) *API {
+func NewAPI(cfg *config.Config, redirect *handler.Handler) *API {
 	return &API{
-		config: cfg,
+		config:   cfg,
+		redirect: redirect,
 	}
 }
 
+// SetAuth 启用认证，未设置时 API 不做认证
+func (a *API) SetAuth(authenticator *auth.Authenticator) {
+	a.auth = authenticator
+}
+
+// SetTrustedProxies 设置可信反向代理，跨站检查只对来自这些地址的请求采用 X-Forwarded-Host
+func (a *API) SetTrustedProxies(proxies auth.TrustedProxies) {
+	a.proxies = proxies
+}
+
 // RegisterRoutes 注册 API 路由
 func (a *API) RegisterRoutes(r *mux.Router) {
+	sameOrigin := auth.SameOrigin(a.proxies)
+	// 登录相关接口无需认证，但同样拒绝跨站请求；接口文档无需认证
+	r.Handle("/api/login", a.counted(withRequestID(sameOrigin(a.audited("login", a.Login))))).Methods("POST")
+	r.Handle("/api/logout", a.counted(withRequestID(sameOrigin(a.audited("logout", a.Logout))))).Methods("POST")
+	r.Handle("/api/openapi.json", a.counted(http.HandlerFunc(a.OpenAPI))).Methods("GET")
+
 	apiRouter := r.PathPrefix("/api").Subrouter()
+	apiRouter.Use(a.counted)
+	apiRouter.Use(withRequestID)
+	apiRouter.Use(sameOrigin)
+	apiRouter.Use(func(next http.Handler) http.Handler {
+		return a.auth.Protect(next, http.HandlerFunc(auth.Unauthorized))
+	})
+	apiRouter.HandleFunc("/me", a.Me).Methods("GET")
 	apiRouter.HandleFunc("/rules", a.ListRules).Methods("GET")
-	apiRouter.HandleFunc("/rules", a.CreateRule).Methods("POST")
+	apiRouter.HandleFunc("/rules", a.audited("create", a.CreateRule)).Methods("POST")
+	apiRouter.HandleFunc("/rules/batch", a.audited("batch", a.BatchRules)).Methods("POST")
 	apiRouter.HandleFunc("/rules/{id}", a.GetRule).Methods("GET")
-	apiRouter.HandleFunc("/rules/{id}", a.UpdateRule).Methods("PUT")
-	apiRouter.HandleFunc("/rules/{id}", a.DeleteRule).Methods("DELETE")
-	apiRouter.HandleFunc("/reload", a.ReloadConfig).Methods("POST")
-	apiRouter.HandleFunc("/save", a.SaveConfig).Methods("POST")
+	apiRouter.HandleFunc("/rules/{id}/hits", a.RuleHits).Methods("GET")
+	apiRouter.HandleFunc("/rules/{id}", a.audited("update", a.UpdateRule)).Methods("PUT")
+	apiRouter.HandleFunc("/rules/{id}", a.audited("update", a.PatchRule)).Methods("PATCH")
+	apiRouter.HandleFunc("/rules/{id}", a.audited("delete", a.DeleteRule)).Methods("DELETE")
+	apiRouter.HandleFunc("/import", a.audited("import", a.ImportRules)).Methods("POST")
+	apiRouter.HandleFunc("/analysis/chains", a.AnalyzeChains).Methods("GET")
+	apiRouter.HandleFunc("/test", a.TestURL).Methods("POST")
+	apiRouter.HandleFunc("/reload", a.audited("reload", a.ReloadConfig)).Methods("POST")
+	apiRouter.HandleFunc("/save", a.audited("save", a.SaveConfig)).Methods("POST")
+	apiRouter.HandleFunc("/audit", a.AuditLogs).Methods("GET")
+	apiRouter.HandleFunc("/live", a.LiveTraffic).Methods("GET")
+	apiRouter.HandleFunc("/stats", a.Stats).Methods("GET")
+	a.registerWebhookRoutes(apiRouter)
 }
 
-// ListRules 列出所有规则
+// ListRules 分页查询规则，启用命中计数时每条规则附带命中概况
+// 查询参数：domain、type、q（搜索目标 URL 和描述）、state、sort、limit、cursor
 func (a *API) ListRules(w http.ResponseWriter, r *http.Request) {
-	rules := a.config.GetAllRules()
-	respondJSON(w, http.StatusOK, rules)
+	query, errs := parseRuleQuery(r.URL.Query())
+	if a.hits != nil {
+		query.Hits = a.hits
+	}
+	if errs = append(errs, query.Validate()...); len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+
+	page, err := a.config.QueryRules(query, canRead(r))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to query rules: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, a.withHits(page))
+}
+
+// parseRuleQuery 解析规则查询参数
+func parseRuleQuery(values url.Values) (config.RuleQuery, config.ValidationErrors) {
+	query := config.RuleQuery{
+		Domain: strings.ToLower(values.Get("domain")),
+		Search: values.Get("q"),
+		State:  values.Get("state"),
+		Sort:   values.Get("sort"),
+		Cursor: values.Get("cursor"),
+	}
+	var errs config.ValidationErrors
+	if v := values.Get("type"); v != "" {
+		if v == "js" {
+			v = strconv.Itoa(int(config.RedirectTypeJS))
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, config.FieldError{Field: "type", Message: "必须是 301、302、307、4 或 js"})
+		}
+		query.Type = config.RedirectType(n)
+	}
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			errs = append(errs, config.FieldError{Field: "limit", Message: "必须是正整数"})
+			n = 0
+		}
+		query.Limit = n
+	}
+	return query, errs
 }
 
 // CreateRule 创建规则
@@ -48,21 +148,51 @@ func (a *API) CreateRule(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	auditDetail(r, rule.ID, nil, &rule)
+
+	// 校验规则
+	if errs := config.ValidateRuleForSave(&rule); len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
 
-	// 验证必填字段
-	if rule.Domain == "" || rule.Target == "" {
-		respondError(w, http.StatusBadRequest, "域名和目标URL不能为空")
+	if !principal(r).CanWrite(rule.Domain) {
+		respondForbidden(w, rule.Domain)
 		return
 	}
 
 	// 生成 ID
 	if rule.ID == "" {
-		rule.ID = a.generateID(rule.Domain, rule.Path)
+		rule.ID = config.GenerateID(rule.Domain, rule.Path, a.ruleIDTaken)
 	}
+	auditDetail(r, rule.ID, nil, &rule)
 
-	// 检查冲突
-	conflicts, conflictMsg := a.config.CheckConflict(&rule, "")
-	if len(conflicts) > 0 {
+	rule.CreatedAt = time.Now()
+	rule.Revision = nextRevision(nil)
+
+	// 在持有写锁时检查 ID 是否已被使用和冲突，避免并发创建覆盖已有规则
+	var taken *config.RedirectRule
+	var conflicts []*config.RedirectRule
+	var conflictMsg string
+	saved, err := a.config.ApplyBatch([]*config.RedirectRule{&rule}, nil, func(staged *config.Config) bool {
+		if taken, _ = a.config.GetRuleByID(rule.ID); taken != nil {
+			return false
+		}
+		conflicts, conflictMsg = staged.CheckConflict(&rule, rule.ID, canRead(r))
+		return conflictMsg == ""
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save rule: "+err.Error())
+		return
+	}
+	if !saved {
+		if taken != nil {
+			// 不可查看的规则不返回内容
+			conflictMsg, conflicts = "规则 ID 已存在", []*config.RedirectRule{}
+			if principal(r).CanRead(taken.Domain) {
+				conflicts = append(conflicts, taken)
+			}
+		}
 		respondJSON(w, http.StatusConflict, map[string]interface{}{
 			"error":     conflictMsg,
 			"conflicts": conflicts,
@@ -70,10 +200,9 @@ func (a *API) CreateRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rule.CreatedAt = time.Now()
-	a.config.SetRule(&rule)
-	a.config.SaveToFile()
-
+	a.emit(r, webhook.EventRuleCreated, &rule, nil)
+	a.warnLongChains(w, rule.ID)
+	w.Header().Set("ETag", ruleETag(&rule))
 	respondJSON(w, http.StatusCreated, rule)
 }
 
@@ -84,7 +213,8 @@ func (a *API) GetRule(w http.ResponseWriter, r *http.Request) {
 
 	rules := a.config.GetAllRules()
 	for _, rule := range rules {
-		if rule.ID == id {
+		if rule.ID == id && principal(r).CanRead(rule.Domain) {
+			w.Header().Set("ETag", ruleETag(rule))
 			respondJSON(w, http.StatusOK, rule)
 			return
 		}
@@ -93,7 +223,7 @@ func (a *API) GetRule(w http.ResponseWriter, r *http.Request) {
 	respondError(w, http.StatusNotFound, "Rule not found")
 }
 
-// UpdateRule 更新规则
+// UpdateRule 更新规则，需通过 If-Match 携带规则当前的 ETag
 func (a *API) UpdateRule(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -104,82 +234,366 @@ func (a *API) UpdateRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 验证必填字段
-	if updatedRule.Domain == "" || updatedRule.Target == "" {
-		respondError(w, http.StatusBadRequest, "域名和目标URL不能为空")
+	// 校验规则（ID 以 URL 为准）
+	updatedRule.ID = id
+	auditDetail(r, id, nil, &updatedRule)
+	if errs := config.ValidateRuleForSave(&updatedRule); len(errs) > 0 {
+		respondValidationError(w, errs)
 		return
 	}
 
-	rules := a.config.GetAllRules()
-	for _, rule := range rules {
-		if rule.ID == id {
-			updatedRule.ID = id
-			if updatedRule.CreatedAt.IsZero() {
-				updatedRule.CreatedAt = rule.CreatedAt
-			}
+	rule, ok := a.config.GetRuleByID(id)
+	if !ok || !principal(r).CanRead(rule.Domain) {
+		respondError(w, http.StatusNotFound, "Rule not found")
+		return
+	}
+	a.replaceRule(w, r, rule, &updatedRule)
+}
 
-			// 检查冲突（排除当前规则）
-			conflicts, conflictMsg := a.config.CheckConflict(&updatedRule, id)
-			if len(conflicts) > 0 {
-				respondJSON(w, http.StatusConflict, map[string]interface{}{
-					"error":     conflictMsg,
-					"conflicts": conflicts,
-				})
-				return
-			}
+// replaceRule 用 updated 替换已有规则 rule（updated 需已通过校验）
+// 检查写权限和 If-Match，并在持有写锁时再次确认版本未变化、检查冲突
+func (a *API) replaceRule(w http.ResponseWriter, r *http.Request, rule, updated *config.RedirectRule) {
+	id := rule.ID
+	p := principal(r)
+	auditDetail(r, id, rule, updated)
+	if !p.CanWrite(rule.Domain) {
+		respondForbidden(w, rule.Domain)
+		return
+	}
+	if !p.CanWrite(updated.Domain) {
+		respondForbidden(w, updated.Domain)
+		return
+	}
+	if !checkIfMatch(w, r, rule) {
+		return
+	}
+	if updated.CreatedAt.IsZero() {
+		updated.CreatedAt = rule.CreatedAt
+	}
+	updated.Revision = nextRevision(rule)
 
-			a.config.SetRule(&updatedRule)
-			a.config.SaveToFile()
-			respondJSON(w, http.StatusOK, updatedRule)
-			return
+	var current *config.RedirectRule
+	var conflicts []*config.RedirectRule
+	var conflictMsg string
+	saved, err := a.config.ApplyBatch([]*config.RedirectRule{updated}, nil, func(staged *config.Config) bool {
+		current, _ = a.config.GetRuleByID(id)
+		if current == nil || !ifMatches(ifMatchHeader(r), current) {
+			return false
 		}
+		conflicts, conflictMsg = staged.CheckConflict(updated, id, canRead(r))
+		return conflictMsg == ""
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save rule: "+err.Error())
+		return
+	}
+	if !saved {
+		switch {
+		case current == nil:
+			respondError(w, http.StatusNotFound, "Rule not found")
+		case !ifMatches(ifMatchHeader(r), current):
+			respondStale(w, current)
+		default:
+			respondJSON(w, http.StatusConflict, map[string]interface{}{
+				"error":     conflictMsg,
+				"conflicts": conflicts,
+			})
+		}
+		return
 	}
 
-	respondError(w, http.StatusNotFound, "Rule not found")
+	a.emit(r, webhook.EventRuleUpdated, updated, rule)
+	a.warnLongChains(w, id)
+	w.Header().Set("ETag", ruleETag(updated))
+	respondJSON(w, http.StatusOK, updated)
 }
 
-// DeleteRule 删除规则
+// DeleteRule 删除规则，需通过 If-Match 携带规则当前的 ETag
 func (a *API) DeleteRule(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	rules := a.config.GetAllRules()
+	auditDetail(r, id, nil, nil)
+
+	p := principal(r)
+	rule, ok := a.config.GetRuleByID(id)
+	if !ok || !p.CanRead(rule.Domain) {
+		respondError(w, http.StatusNotFound, "Rule not found")
+		return
+	}
+	auditDetail(r, id, rule, nil)
+	if !p.CanWrite(rule.Domain) {
+		respondForbidden(w, rule.Domain)
+		return
+	}
+	if !checkIfMatch(w, r, rule) {
+		return
+	}
+
+	var current *config.RedirectRule
+	deleted, err := a.config.ApplyBatch(nil, []string{id}, func(*config.Config) bool {
+		current, _ = a.config.GetRuleByID(id)
+		return current != nil && ifMatches(ifMatchHeader(r), current)
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to delete rule: "+err.Error())
+		return
+	}
+	if !deleted {
+		if current == nil {
+			respondError(w, http.StatusNotFound, "Rule not found")
+		} else {
+			respondStale(w, current)
+		}
+		return
+	}
+
+	a.emit(r, webhook.EventRuleDeleted, rule, nil)
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Rule deleted"})
+}
+
+// ImportRules 批量导入规则
+// 所有规则校验通过且无冲突时才会写入，ID 相同的已有规则会被覆盖
+func (a *API) ImportRules(w http.ResponseWriter, r *http.Request) {
+	var rules []*config.RedirectRule
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(rules) == 0 {
+		respondError(w, http.StatusBadRequest, "导入的规则列表不能为空")
+		return
+	}
+	auditDetail(r, "", nil, rules)
+
+	// 逐条校验
+	p := principal(r)
+	var invalid []importItemError
+	seen := make(map[string]int)
+	for i, rule := range rules {
+		if rule == nil {
+			invalid = append(invalid, importItemError{Index: i, Error: "规则不能为 null"})
+			continue
+		}
+		if errs := config.ValidateRuleForSave(rule); len(errs) > 0 {
+			invalid = append(invalid, importItemError{Index: i, Error: "规则校验失败", Fields: errs})
+			continue
+		}
+		if rule.ID == "" {
+			// 与已有规则的域名和路径相同时沿用其 ID（覆盖该规则）
+			rule.ID = config.GenerateID(rule.Domain, rule.Path, func(id string) bool {
+				existing, ok := a.config.GetRuleByID(id)
+				_, dup := seen[id]
+				return dup || ok && (existing.Domain != rule.Domain || existing.Path != rule.Path)
+			})
+		}
+		if !p.CanWrite(rule.Domain) {
+			invalid = append(invalid, importItemError{Index: i, Error: "无权修改域名 " + rule.Domain + " 的规则"})
+			continue
+		}
+		if existing, ok := a.config.GetRuleByID(rule.ID); ok && !p.CanWrite(existing.Domain) {
+			invalid = append(invalid, importItemError{Index: i, Error: "无权覆盖规则 " + rule.ID})
+			continue
+		}
+		if first, dup := seen[rule.ID]; dup {
+			invalid = append(invalid, importItemError{Index: i, Error: "与第 " + strconv.Itoa(first) + " 条规则 ID 重复"})
+			continue
+		}
+		seen[rule.ID] = i
+	}
+	if len(invalid) > 0 {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "规则校验失败",
+			"items": invalid,
+		})
+		return
+	}
+
+	now := time.Now()
+	var replaced []*config.RedirectRule
+	previous := make(map[string]*config.RedirectRule)
 	for _, rule := range rules {
-		if rule.ID == id {
-			a.config.DeleteRule(rule.Domain, rule.Path)
-			a.config.SaveToFile()
-			respondJSON(w, http.StatusOK, map[string]string{"message": "Rule deleted"})
-			return
+		if rule.CreatedAt.IsZero() {
+			rule.CreatedAt = now
+		}
+		if existing, ok := a.config.GetRuleByID(rule.ID); ok {
+			replaced = append(replaced, existing)
+			previous[rule.ID] = existing
+		}
+		rule.Revision = nextRevision(previous[rule.ID])
+	}
+	if len(replaced) > 0 {
+		auditDetail(r, "", replaced, rules)
+	}
+
+	// 在导入后的规则集上检查冲突（与同 ID 的已有规则视为覆盖，导入的规则之间也不能冲突）
+	var conflicted []importItemError
+	imported, err := a.config.ApplyBatch(rules, nil, func(staged *config.Config) bool {
+		for i, rule := range rules {
+			conflicts, conflictMsg := staged.CheckConflict(rule, rule.ID, canRead(r))
+			if conflictMsg != "" {
+				conflicted = append(conflicted, importItemError{Index: i, Error: conflictMsg, Conflicts: conflicts})
+			}
+		}
+		return len(conflicted) == 0
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to import rules: "+err.Error())
+		return
+	}
+	if !imported {
+		respondJSON(w, http.StatusConflict, map[string]interface{}{
+			"error": "导入的规则与已有规则冲突",
+			"items": conflicted,
+		})
+		return
+	}
+	events := make([]*webhook.Event, 0, len(rules))
+	for _, rule := range rules {
+		if old, ok := previous[rule.ID]; ok {
+			events = append(events, newEvent(r, webhook.EventRuleUpdated, rule, old))
+		} else {
+			events = append(events, newEvent(r, webhook.EventRuleCreated, rule, nil))
 		}
 	}
+	a.hooks.Emit(events...)
 
-	respondError(w, http.StatusNotFound, "Rule not found")
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message":  "Rules imported",
+		"imported": len(rules),
+	})
+}
+
+// AnalyzeChains 列出所有多跳跳转链
+func (a *API) AnalyzeChains(w http.ResponseWriter, r *http.Request) {
+	// 只返回操作者可查看全部规则的链
+	p := principal(r)
+	chains := []config.RedirectChain{}
+	for _, chain := range a.config.AnalyzeChains() {
+		readable := true
+		for _, rule := range chain.Rules {
+			readable = readable && p.CanRead(rule.Domain)
+		}
+		if readable {
+			chains = append(chains, chain)
+		}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"max_chain_length": a.config.MaxChainLength,
+		"chains":           chains,
+	})
+}
+
+// testRequest 匹配测试请求
+type testRequest struct {
+	URL      string            `json:"url"`       // 完整请求 URL
+	Method   string            `json:"method"`    // 请求方法（默认 GET）
+	Headers  map[string]string `json:"headers"`   // 请求头（可覆盖 Host）
+	ClientIP string            `json:"client_ip"` // 客户端 IP
+}
+
+// TestURL 模拟请求，返回命中的规则、最终目标和所有候选规则（不记录访问日志）
+func (a *API) TestURL(w http.ResponseWriter, r *http.Request) {
+	var req testRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || u.Host == "" {
+		respondValidationError(w, config.ValidationErrors{{Field: "url", Message: "必须是包含主机名的完整 URL"}})
+		return
+	}
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+
+	testReq, err := http.NewRequest(req.Method, u.String(), nil)
+	if err != nil {
+		respondValidationError(w, config.ValidationErrors{{Field: "method", Message: err.Error()}})
+		return
+	}
+	for name, value := range req.Headers {
+		testReq.Header.Set(name, value)
+	}
+	if host := testReq.Header.Get("Host"); host != "" {
+		testReq.Host = host
+	}
+	if req.ClientIP != "" {
+		testReq.RemoteAddr = net.JoinHostPort(req.ClientIP, "0")
+	}
+
+	if !principal(r).CanRead(testReq.Host) {
+		respondError(w, http.StatusForbidden, "无权查看域名 "+testReq.Host+" 的规则")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, a.redirect.Evaluate(testReq, true))
 }
 
 // ReloadConfig 重新加载配置
 func (a *API) ReloadConfig(w http.ResponseWriter, r *http.Request) {
-	if err := a.config.LoadFromFile(); err != nil {
+	if !principal(r).IsAdmin() {
+		respondError(w, http.StatusForbidden, "仅管理员可以重新加载配置")
+		return
+	}
+	if err := a.config.Load(); err != nil {
+		var partial *config.LoadError
+		if errors.As(err, &partial) {
+			a.metrics.Reload(metrics.ReloadPartial)
+			a.emit(r, webhook.EventConfigReloaded, nil, nil)
+			respondJSON(w, http.StatusOK, map[string]interface{}{
+				"message": "Config reloaded",
+				"invalid": partial.Items,
+			})
+			return
+		}
+		a.metrics.Reload(metrics.ReloadFailure)
 		respondError(w, http.StatusInternalServerError, "Failed to reload config: "+err.Error())
 		return
 	}
+	a.metrics.Reload(metrics.ReloadSuccess)
+	a.emit(r, webhook.EventConfigReloaded, nil, nil)
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Config reloaded"})
 }
 
 // SaveConfig 保存配置
 func (a *API) SaveConfig(w http.ResponseWriter, r *http.Request) {
-	if err := a.config.SaveToFile(); err != nil {
+	if !principal(r).IsAdmin() {
+		respondError(w, http.StatusForbidden, "仅管理员可以保存配置")
+		return
+	}
+	if err := a.config.Save(); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to save config: "+err.Error())
 		return
 	}
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Config saved"})
 }
 
-// generateID 生成规则 ID
-func (a *API) generateID(domain, path string) string {
-	if path == "" {
-		return strings.ReplaceAll(domain, ".", "_")
+// warnLongChains 规则所在跳转链超过最大长度时，通过 Warning 响应头告警
+func (a *API) warnLongChains(w http.ResponseWriter, ruleIDs ...string) {
+	for _, chain := range a.config.ChainsThrough(ruleIDs...) {
+		if chain.Exceeds {
+			w.Header().Add("Warning", fmt.Sprintf(`199 minijump "redirect chain of %d hops exceeds limit of %d"`,
+				chain.Length, a.config.MaxChainLength))
+			return
+		}
 	}
-	return strings.ReplaceAll(domain+"_"+path, ".", "_")
+}
+
+// ruleIDTaken 判断规则 ID 是否已被使用
+func (a *API) ruleIDTaken(id string) bool {
+	_, ok := a.config.GetRuleByID(id)
+	return ok
+}
+
+// importItemError 导入时单条规则的错误
+type importItemError struct {
+	Index     int                     `json:"index"`
+	Error     string                  `json:"error"`
+	Fields    config.ValidationErrors `json:"fields,omitempty"`
+	Conflicts []*config.RedirectRule  `json:"conflicts,omitempty"`
 }
 
 // respondJSON 返回 JSON 响应
@@ -193,3 +607,11 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 func respondError(w http.ResponseWriter, status int, message string) {
 	respondJSON(w, status, map[string]string{"error": message})
 }
+
+// respondValidationError 返回字段校验错误响应
+func respondValidationError(w http.ResponseWriter, errs config.ValidationErrors) {
+	respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"error":  "规则校验失败",
+		"fields": errs,
+	})
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"mini_jump/auth"
+	"mini_jump/config"
+	"mini_jump/handler"
+	"mini_jump/logger"
+)
+
+// 接口测试使用的凭据
+const (
+	testAdminToken  = "test-admin-token"
+	testEditorToken = "test-editor-token" // 只能修改 example.com 的规则
+)
+
+// newTestAPI 在临时目录中创建启用认证的 API（规则集为空），返回注册好路由的 router
+func newTestAPI(t *testing.T) (*API, *mux.Router) {
+	t.Helper()
+	// 认证失败等日志属于预期，不输出
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	dir := t.TempDir()
+	cfg := config.GetDefaultConfig()
+	store, err := config.OpenStore(config.StoreJSON, filepath.Join(dir, "rules.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetStore(store)
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cfg.Close() })
+
+	creds, _ := json.Marshal(auth.Credentials{
+		Tokens: []auth.TokenCredential{
+			{Name: "admin", Hash: auth.HashToken(testAdminToken), Role: auth.RoleAdmin},
+			{Name: "editor", Hash: auth.HashToken(testEditorToken), Role: auth.RoleEditor, Domains: []string{"example.com"}},
+		},
+	})
+	credFile := filepath.Join(dir, "credentials.json")
+	if err := os.WriteFile(credFile, creds, 0600); err != nil {
+		t.Fatal(err)
+	}
+	authenticator, err := auth.NewAuthenticator(credFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := NewAPI(cfg, handler.NewHandler(cfg, logger.New()))
+	api.SetAuth(authenticator)
+	router := mux.NewRouter()
+	api.RegisterRoutes(router)
+	return api, router
+}
+
+// send 以 token 身份发送请求，headers 为成对的请求头名称和值
+func send(router *mux.Router, method, path, token, body string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+	return rec
+}
+
+// mustSend 发送请求并检查状态码，返回解码后的响应体
+func mustSend(t *testing.T, router *mux.Router, status int, method, path, token, body string, headers ...string) map[string]interface{} {
+	t.Helper()
+	rec := send(router, method, path, token, body, headers...)
+	if rec.Code != status {
+		t.Fatalf("%s %s: status %d, want %d (%s)", method, path, rec.Code, status, strings.TrimSpace(rec.Body.String()))
+	}
+	var resp map[string]interface{}
+	if rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+func TestCycleConflictHidesUnreadableRules(t *testing.T) {
+	_, router := newTestAPI(t)
+	// 其他团队的规则跳转到 example.com/a 和 example.com/c
+	mustSend(t, router, 201, "POST", "/api/rules", testAdminToken,
+		`{"id":"team-x","domain":"other.com","path":"/x","target":"https://example.com/a","type":302}`)
+	mustSend(t, router, 201, "POST", "/api/rules", testAdminToken,
+		`{"id":"team-y","domain":"other.com","path":"/y","target":"https://example.com/c","type":302}`)
+	mustSend(t, router, 201, "POST", "/api/rules", testEditorToken,
+		`{"id":"a","domain":"example.com","path":"/a","target":"https://example.com/done","type":302}`)
+
+	// checkHidden 检查响应中的冲突不包含其他团队的规则
+	checkHidden := func(what, msg string, conflicts interface{}) {
+		t.Helper()
+		if !strings.Contains(msg, "循环") || !strings.Contains(msg, "…") || strings.Contains(msg, "other.com") {
+			t.Errorf("%s: error = %q, want the cycle with other.com hidden", what, msg)
+		}
+		if list, _ := conflicts.([]interface{}); len(list) != 0 {
+			t.Errorf("%s: conflicts = %v, want none readable", what, conflicts)
+		}
+	}
+	cycle := `{"domain":"example.com","path":"/c","target":"https://other.com/y","type":302}`
+
+	resp := mustSend(t, router, 409, "POST", "/api/rules", testEditorToken, cycle)
+	checkHidden("create", resp["error"].(string), resp["conflicts"])
+
+	resp = mustSend(t, router, 409, "PUT", "/api/rules/a", testEditorToken,
+		`{"domain":"example.com","path":"/a","target":"https://other.com/x","type":302}`, "If-Match", "*")
+	checkHidden("update", resp["error"].(string), resp["conflicts"])
+
+	resp = mustSend(t, router, 409, "POST", "/api/rules/batch", testEditorToken,
+		`{"operations":[{"op":"create","rule":`+cycle+`}]}`)
+	result := resp["results"].([]interface{})[0].(map[string]interface{})
+	checkHidden("batch", result["error"].(string), result["conflicts"])
+
+	resp = mustSend(t, router, 409, "POST", "/api/import", testEditorToken, `[`+cycle+`]`)
+	item := resp["items"].([]interface{})[0].(map[string]interface{})
+	checkHidden("import", item["error"].(string), item["conflicts"])
+
+	// 管理员可以看到完整的循环
+	resp = mustSend(t, router, 409, "POST", "/api/rules", testAdminToken, cycle)
+	if msg := resp["error"].(string); !strings.Contains(msg, "example.com/c → other.com/y → example.com/c") {
+		t.Errorf("admin: error = %q, want the full cycle", msg)
+	}
+	if list := resp["conflicts"].([]interface{}); len(list) != 1 {
+		t.Errorf("admin: conflicts = %v, want team-y", list)
+	}
+}
+
+func TestRuleRevisions(t *testing.T) {
+	_, router := newTestAPI(t)
+	mustSend(t, router, 201, "POST", "/api/rules", testEditorToken,
+		`{"id":"a","domain":"example.com","path":"/a","target":"https://example.net/a","type":302,"description":"spring","expires_at":"2099-01-01T00:00:00Z"}`)
+	rec := send(router, "GET", "/api/rules/a", testEditorToken, "")
+	if etag := rec.Header().Get("ETag"); etag != `"1"` {
+		t.Fatalf("ETag = %s, want \"1\"", etag)
+	}
+	update := `{"domain":"example.com","path":"/a","target":"https://example.net/b","type":302,"description":"spring","expires_at":"2099-01-01T00:00:00Z"}`
+
+	// 缺少 If-Match
+	mustSend(t, router, 428, "PUT", "/api/rules/a", testEditorToken, update)
+	mustSend(t, router, 428, "PATCH", "/api/rules/a", testEditorToken, `{"target":"https://example.net/b"}`)
+	mustSend(t, router, 428, "DELETE", "/api/rules/a", testEditorToken, "")
+
+	// 过期的 If-Match 返回当前版本
+	rec = send(router, "PUT", "/api/rules/a", testEditorToken, update, "If-Match", `"0"`)
+	if rec.Code != 412 || rec.Header().Get("ETag") != `"1"` || !strings.Contains(rec.Body.String(), `"revision":1`) {
+		t.Fatalf("stale PUT: status %d, ETag %s, body %s", rec.Code, rec.Header().Get("ETag"), rec.Body.String())
+	}
+
+	// 匹配的 If-Match 更新成功并返回新的 ETag
+	rec = send(router, "PUT", "/api/rules/a", testEditorToken, update, "If-Match", `"1"`)
+	if rec.Code != 200 || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PUT: status %d, ETag %s, body %s", rec.Code, rec.Header().Get("ETag"), rec.Body.String())
+	}
+	mustSend(t, router, 412, "PUT", "/api/rules/a", testEditorToken, update, "If-Match", `"1"`)
+
+	// 合并补丁中的 null 清除字段，未出现的字段保持不变
+	rec = send(router, "PATCH", "/api/rules/a", testEditorToken, `{"expires_at":null,"description":null}`,
+		"If-Match", `"2"`, "Content-Type", MergePatchContentType)
+	if rec.Code != 200 || rec.Header().Get("ETag") != `"3"` {
+		t.Fatalf("PATCH: status %d, ETag %s, body %s", rec.Code, rec.Header().Get("ETag"), rec.Body.String())
+	}
+	var patched config.RedirectRule
+	if err := json.Unmarshal(rec.Body.Bytes(), &patched); err != nil {
+		t.Fatal(err)
+	}
+	if patched.ExpiresAt != nil || patched.Description != "" || patched.Target != "https://example.net/b" || patched.CreatedAt.IsZero() {
+		t.Errorf("patched rule = %+v", patched)
+	}
+	mustSend(t, router, 412, "PATCH", "/api/rules/a", testEditorToken, `{"description":"x"}`, "If-Match", `"2"`)
+	mustSend(t, router, 415, "PATCH", "/api/rules/a", testEditorToken, `{"description":"x"}`, "If-Match", `"3"`, "Content-Type", "text/plain")
+
+	mustSend(t, router, 412, "DELETE", "/api/rules/a", testEditorToken, "", "If-Match", `"2"`)
+	mustSend(t, router, 200, "DELETE", "/api/rules/a", testEditorToken, "", "If-Match", `"3"`)
+	mustSend(t, router, 404, "GET", "/api/rules/a", testEditorToken, "")
+}
+
+func TestInvalidRulesRejected(t *testing.T) {
+	_, router := newTestAPI(t)
+	// fieldsOf 返回校验错误涉及的字段
+	fieldsOf := func(v interface{}) []string {
+		var out []string
+		list, _ := v.([]interface{})
+		for _, fe := range list {
+			out = append(out, fe.(map[string]interface{})["field"].(string))
+		}
+		return out
+	}
+
+	for _, tt := range []struct {
+		body   string
+		fields []string
+	}{
+		{`{"domain":"https://example.com","target":"https://example.net/","type":302}`, []string{"domain"}},
+		{`{"domain":"example.com","path":"a","target":"example.net","type":302}`, []string{"path", "target"}},
+		{`{"domain":"example.com","target":"https://example.net/","type":303}`, []string{"type"}},
+		{`{"domain":"example.com","target":"https://example.net/","type":302,"expires_at":"2000-01-01T00:00:00Z"}`, []string{"expires_at"}},
+	} {
+		resp := mustSend(t, router, 400, "POST", "/api/rules", testAdminToken, tt.body)
+		if got := fieldsOf(resp["fields"]); strings.Join(got, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("%s: fields %v, want %v", tt.body, got, tt.fields)
+		}
+	}
+
+	// 导入时逐条报告无效规则，全部不生效
+	resp := mustSend(t, router, 400, "POST", "/api/import", testAdminToken,
+		`[{"domain":"example.com","path":"/a","target":"https://example.net/a","type":302},{"domain":"","target":"https://example.net/","type":302}]`)
+	items := resp["items"].([]interface{})
+	if len(items) != 1 || items[0].(map[string]interface{})["index"].(float64) != 1 {
+		t.Errorf("import items = %v, want index 1", items)
+	}
+	mustSend(t, router, 404, "GET", "/api/rules/example_com_a", testAdminToken, "")
+}
+
+func TestLongChainWarning(t *testing.T) {
+	api, router := newTestAPI(t)
+	api.config.MaxChainLength = 2
+	t.Cleanup(func() { api.config.MaxChainLength = config.GetDefaultConfig().MaxChainLength })
+
+	mustSend(t, router, 201, "POST", "/api/rules", testAdminToken,
+		`{"id":"b","domain":"example.com","path":"/b","target":"https://example.com/c","type":302}`)
+	mustSend(t, router, 201, "POST", "/api/rules", testAdminToken,
+		`{"id":"c","domain":"example.com","path":"/c","target":"https://example.net/","type":302}`)
+	unrelated := send(router, "POST", "/api/rules", testAdminToken,
+		`{"id":"x","domain":"other.com","path":"/x","target":"https://example.net/","type":302}`)
+	if unrelated.Code != 201 || unrelated.Header().Get("Warning") != "" {
+		t.Fatalf("unrelated rule: status %d, Warning %q", unrelated.Code, unrelated.Header().Get("Warning"))
+	}
+
+	// 新规则位于链头，整条链超过最大长度
+	rec := send(router, "POST", "/api/rules", testAdminToken,
+		`{"id":"a","domain":"example.com","path":"/a","target":"https://example.com/b","type":302}`)
+	if want := `199 minijump "redirect chain of 3 hops exceeds limit of 2"`; rec.Code != 201 || rec.Header().Get("Warning") != want {
+		t.Errorf("status %d, Warning %q, want %q", rec.Code, rec.Header().Get("Warning"), want)
+	}
+	// 修改链尾也会告警
+	rec = send(router, "PUT", "/api/rules/c", testAdminToken,
+		`{"domain":"example.com","path":"/c","target":"https://example.net/new","type":302}`, "If-Match", "*")
+	if rec.Code != 200 || rec.Header().Get("Warning") == "" {
+		t.Errorf("update tail: status %d, Warning %q", rec.Code, rec.Header().Get("Warning"))
+	}
+}
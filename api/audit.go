@@ -0,0 +1,251 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini_jump/logger"
+)
+
+// RequestIDHeader 请求 ID 请求头/响应头
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern 接受客户端传入的请求 ID 格式
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// maxAuditErrorBody 失败时为提取错误信息最多记录的响应体大小
+const maxAuditErrorBody = 4096
+
+type requestIDKey struct{}
+
+type auditKey struct{}
+
+// auditRecord 处理器填写的审计详情
+type auditRecord struct {
+	actor  string // 登录等未认证接口的操作者
+	ruleID string
+	before interface{}
+	after  interface{}
+}
+
+// SetAuditLogger 启用审计日志，未设置时不记录
+func (a *API) SetAuditLogger(l *logger.Logger) {
+	a.audit = l
+}
+
+// withRequestID 为请求分配请求 ID（优先使用客户端传入的合法 ID），并写入响应头
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			buf := make([]byte, 8)
+			rand.Read(buf)
+			id = hex.EncodeToString(buf)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestID 获取请求 ID
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// audited 包装管理操作，处理完成后按响应状态记录审计日志
+// 审计日志立即写入磁盘，写入失败时记录到服务日志
+func (a *API) audited(action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.audit == nil {
+			next(w, r)
+			return
+		}
+
+		record := &auditRecord{}
+		rec := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r.WithContext(context.WithValue(r.Context(), auditKey{}, record)))
+
+		entry := &logger.AuditLog{
+			Timestamp:    time.Now(),
+			RequestID:    requestID(r),
+			Actor:        record.actor,
+			IP:           remoteIP(r),
+			ForwardedFor: strings.Join(r.Header.Values("X-Forwarded-For"), ", "),
+			Action:       action,
+			RuleID:       record.ruleID,
+			Before:       marshalAudit(record.before),
+			After:        marshalAudit(record.after),
+			StatusCode:   rec.status,
+			Outcome:      logger.OutcomeSuccess,
+		}
+		if p := principal(r); p != nil {
+			entry.Actor = p.Name
+			entry.AuthMethod = p.Method
+		}
+		if record.before != nil && record.after != nil {
+			entry.Changes = diffFields(entry.Before, entry.After)
+		}
+		if rec.status >= http.StatusBadRequest {
+			entry.Outcome = logger.OutcomeFailure
+			var body struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(rec.body.Bytes(), &body) == nil && body.Error != "" {
+				entry.Error = body.Error
+			} else {
+				entry.Error = http.StatusText(rec.status)
+			}
+		}
+
+		if err := a.audit.LogSync(entry); err != nil {
+			log.Printf("Failed to write audit log: %v (request_id=%s action=%s actor=%q)\n",
+				err, entry.RequestID, action, entry.Actor)
+		}
+	}
+}
+
+// auditDetail 记录操作的规则和修改前后的内容（未启用审计时忽略）
+func auditDetail(r *http.Request, ruleID string, before, after interface{}) {
+	if record, ok := r.Context().Value(auditKey{}).(*auditRecord); ok {
+		record.ruleID = ruleID
+		record.before = before
+		record.after = after
+	}
+}
+
+// auditActor 记录未认证接口（如登录）的操作者
+func auditActor(r *http.Request, actor string) {
+	if record, ok := r.Context().Value(auditKey{}).(*auditRecord); ok {
+		record.actor = actor
+	}
+}
+
+// AuditLogs 查询审计日志（仅管理员）
+// 查询参数：since、until（RFC 3339）、actor、action、rule_id、limit（默认 100）
+func (a *API) AuditLogs(w http.ResponseWriter, r *http.Request) {
+	if !principal(r).IsAdmin() {
+		respondError(w, http.StatusForbidden, "仅管理员可以查看审计日志")
+		return
+	}
+	if a.audit == nil {
+		respondError(w, http.StatusNotFound, "Audit log is not enabled")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := logger.AuditFilter{
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		RuleID: query.Get("rule_id"),
+		Limit:  100,
+	}
+	var errs []string
+	for name, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				errs = append(errs, name+" 必须是 RFC 3339 时间")
+				continue
+			}
+			*t = parsed
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			errs = append(errs, "limit 必须是正整数")
+		} else {
+			filter.Limit = n
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		respondError(w, http.StatusBadRequest, strings.Join(errs, "; "))
+		return
+	}
+
+	entries, err := a.audit.QueryAudit(filter)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to read audit log: "+err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []*logger.AuditLog{}
+	}
+	respondJSON(w, http.StatusOK, entries)
+}
+
+// auditResponseWriter 记录响应状态码，失败时保留响应体用于提取错误信息
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader 记录状态码
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write 失败响应时保留部分响应体
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.status >= http.StatusBadRequest && w.body.Len() < maxAuditErrorBody {
+		w.body.Write(data[:min(len(data), maxAuditErrorBody-w.body.Len())])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// marshalAudit 序列化审计内容
+func marshalAudit(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// diffFields 比较修改前后的 JSON 对象，返回发生变化的字段名
+func diffFields(before, after json.RawMessage) []string {
+	var b, a map[string]json.RawMessage
+	if json.Unmarshal(before, &b) != nil || json.Unmarshal(after, &a) != nil {
+		return nil
+	}
+	var changes []string
+	for key, value := range a {
+		if old, ok := b[key]; !ok || !bytes.Equal(old, value) {
+			changes = append(changes, key)
+		}
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			changes = append(changes, key)
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// remoteIP 获取连接的来源 IP
+// 不使用 X-Forwarded-For：未经可信代理时客户端可以任意伪造，审计记录另行保存该请求头
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
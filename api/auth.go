@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"mini_jump/auth"
+	"mini_jump/config"
+)
+
+// loginRequest 登录请求
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Redirect string `json:"redirect"` // 登录成功后跳转的本地路径（表单提交时使用）
+}
+
+// Login 用户名密码登录，成功后设置会话 Cookie
+// 支持 JSON 和表单提交，表单提交成功后跳转回 redirect 指定的页面
+func (a *API) Login(w http.ResponseWriter, r *http.Request) {
+	if a.auth == nil {
+		respondError(w, http.StatusNotFound, "Authentication is not enabled")
+		return
+	}
+
+	var req loginRequest
+	isForm := strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+	if isForm {
+		req.Username = r.PostFormValue("username")
+		req.Password = r.PostFormValue("password")
+		req.Redirect = r.PostFormValue("redirect")
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	auditActor(r, req.Username)
+
+	sessionID, ok := a.auth.Login(r, req.Username, req.Password)
+	if !ok {
+		if isForm && isLocalPath(req.Redirect) {
+			http.Redirect(w, r, req.Redirect+"?login_failed=1", http.StatusSeeOther)
+			return
+		}
+		auth.Unauthorized(w, r)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookieName,
+		Value:    sessionID,
+		Path:     "/",
+		MaxAge:   int(auth.SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	if isForm && isLocalPath(req.Redirect) {
+		http.Redirect(w, r, req.Redirect, http.StatusSeeOther)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Logged in", "username": req.Username})
+}
+
+// Logout 退出登录，销毁会话
+func (a *API) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(auth.SessionCookieName); err == nil && a.auth != nil {
+		if username, ok := a.auth.SessionUser(cookie.Value); ok {
+			auditActor(r, username)
+		}
+		a.auth.Logout(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Logged out"})
+}
+
+// Me 返回当前操作者
+func (a *API) Me(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]interface{}{"auth_enabled": a.auth != nil}
+	if p, ok := auth.FromContext(r.Context()); ok {
+		resp["name"] = p.Name
+		resp["method"] = p.Method
+		resp["role"] = p.Role
+		resp["domains"] = p.Domains
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// principal 获取当前操作者（未启用认证时为 nil，拥有全部权限）
+func principal(r *http.Request) *auth.Principal {
+	p, _ := auth.FromContext(r.Context())
+	return p
+}
+
+// canRead 返回判断当前操作者能否查看规则的函数，用于过滤返回的规则
+func canRead(r *http.Request) func(rule *config.RedirectRule) bool {
+	p := principal(r)
+	return func(rule *config.RedirectRule) bool {
+		return p.CanRead(rule.Domain)
+	}
+}
+
+// respondForbidden 返回无权操作指定域名的错误
+func respondForbidden(w http.ResponseWriter, domain string) {
+	respondError(w, http.StatusForbidden, "无权修改域名 "+domain+" 的规则")
+}
+
+// isLocalPath 判断是否为本站路径（防止开放跳转）
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") && !strings.HasPrefix(path, "/\\")
+}
@@ -0,0 +1,325 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"mini_jump/config"
+	"mini_jump/webhook"
+)
+
+// maxBatchSize 单次批量操作的最大条数
+const maxBatchSize = 10000
+
+// 批量操作类型
+const (
+	batchCreate = "create"
+	batchUpdate = "update"
+	batchDelete = "delete"
+)
+
+// 单项操作结果状态
+const (
+	batchApplied    = "applied"     // 已生效
+	batchFailed     = "failed"      // 本项校验失败或存在冲突
+	batchNotApplied = "not_applied" // 本项无误，但因其他项失败未生效
+)
+
+// batchOperation 批量操作中的单项操作
+type batchOperation struct {
+	Op       string               `json:"op"`                 // create、update 或 delete
+	ID       string               `json:"id"`                 // update/delete 时必填，create 时可选
+	Rule     *config.RedirectRule `json:"rule"`               // create/update 时必填
+	Revision *int64               `json:"revision,omitempty"` // update/delete 时可选，规则当前的修订号
+	IfMatch  string               `json:"if_match,omitempty"` // update/delete 时可选，规则当前的 ETag，同 If-Match 请求头
+}
+
+// hasPrecondition 判断本项操作是否指定了版本前置条件
+func (op *batchOperation) hasPrecondition() bool {
+	return op.Revision != nil || op.IfMatch != ""
+}
+
+// matches 判断规则当前版本是否满足本项操作的前置条件，未指定时总是满足
+func (op *batchOperation) matches(rule *config.RedirectRule) bool {
+	if op.Revision != nil && *op.Revision != rule.Revision {
+		return false
+	}
+	return op.IfMatch == "" || ifMatches(op.IfMatch, rule)
+}
+
+// batchResult 单项操作的结果
+type batchResult struct {
+	Index     int                     `json:"index"`
+	Op        string                  `json:"op"`
+	ID        string                  `json:"id,omitempty"`
+	Status    string                  `json:"status"`
+	Error     string                  `json:"error,omitempty"`
+	Fields    config.ValidationErrors `json:"fields,omitempty"`
+	Conflicts []*config.RedirectRule  `json:"conflicts,omitempty"`
+	Rule      *config.RedirectRule    `json:"rule,omitempty"`    // 生效后的规则
+	Current   *config.RedirectRule    `json:"current,omitempty"` // 版本不一致时规则的当前版本
+}
+
+// BatchRules 批量创建、更新和删除规则
+// 所有操作先按变更后的规则集整体校验和检查冲突，全部通过后一次性持久化；任一项失败时不做任何修改
+// update/delete 可通过 revision 或 if_match 指定规则的当前版本，任一项版本不一致时整批返回 412
+func (a *API) BatchRules(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Operations []batchOperation `json:"operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Operations) == 0 {
+		respondError(w, http.StatusBadRequest, "operations 不能为空")
+		return
+	}
+	if len(req.Operations) > maxBatchSize {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("单次最多 %d 项操作", maxBatchSize))
+		return
+	}
+
+	p := principal(r)
+	existing := make(map[string]*config.RedirectRule)
+	for _, rule := range a.config.GetAllRules() {
+		existing[rule.ID] = rule
+	}
+
+	now := time.Now()
+	results := make([]batchResult, len(req.Operations))
+	seen := make(map[string]int)
+	var puts []*config.RedirectRule
+	var deleteIDs []string
+	putIndex := make(map[string]int) // 规则 ID -> 操作序号
+	previous := make(map[string]*config.RedirectRule)
+	failed := false
+	stale := false // 存在版本不一致的项
+
+	for i, op := range req.Operations {
+		res := &results[i]
+		res.Index = i
+		res.Op = op.Op
+		fail := func(msg string) {
+			res.Status = batchFailed
+			res.Error = msg
+			failed = true
+		}
+
+		// 确定规则 ID 并校验
+		id := op.ID
+		switch op.Op {
+		case batchCreate, batchUpdate:
+			if op.Op == batchCreate && op.hasPrecondition() {
+				fail("create 不能指定 revision 或 if_match")
+				continue
+			}
+			if op.Rule == nil {
+				fail("缺少 rule")
+				continue
+			}
+			if id == "" {
+				id = op.Rule.ID
+			}
+			op.Rule.ID = id
+			if errs := config.ValidateRuleForSave(op.Rule); len(errs) > 0 {
+				res.Fields = errs
+				fail("规则校验失败")
+				continue
+			}
+			if id == "" && op.Op == batchCreate {
+				id = config.GenerateID(op.Rule.Domain, op.Rule.Path, func(id string) bool {
+					_, found := existing[id]
+					_, dup := seen[id]
+					return found || dup
+				})
+				op.Rule.ID = id
+			}
+		case batchDelete:
+		default:
+			fail("op 必须是 create、update 或 delete")
+			continue
+		}
+		res.ID = id
+		if id == "" {
+			fail("缺少规则 ID")
+			continue
+		}
+		if j, ok := seen[id]; ok {
+			fail(fmt.Sprintf("与第 %d 项操作的规则 ID 重复", j))
+			continue
+		}
+		seen[id] = i
+
+		// 检查规则是否存在及权限
+		// 不可查看的规则按不存在处理，但新建时不能复用其 ID
+		old, found := existing[id]
+		visible := found && p.CanRead(old.Domain)
+		switch op.Op {
+		case batchCreate:
+			if found {
+				fail("规则 ID 已存在")
+				continue
+			}
+			if !p.CanWrite(op.Rule.Domain) {
+				fail("无权修改域名 " + op.Rule.Domain + " 的规则")
+				continue
+			}
+			if op.Rule.CreatedAt.IsZero() {
+				op.Rule.CreatedAt = now
+			}
+		case batchUpdate:
+			if !visible {
+				fail("Rule not found")
+				continue
+			}
+			for _, domain := range []string{old.Domain, op.Rule.Domain} {
+				if !p.CanWrite(domain) {
+					fail("无权修改域名 " + domain + " 的规则")
+					break
+				}
+			}
+			if res.Status == batchFailed {
+				continue
+			}
+			if op.Rule.CreatedAt.IsZero() {
+				op.Rule.CreatedAt = old.CreatedAt
+			}
+		case batchDelete:
+			if !visible {
+				fail("Rule not found")
+				continue
+			}
+			if !p.CanWrite(old.Domain) {
+				fail("无权修改域名 " + old.Domain + " 的规则")
+				continue
+			}
+		}
+
+		if op.Op != batchCreate && !op.matches(old) {
+			res.Current = old
+			fail("规则已被其他人修改")
+			stale = true
+			continue
+		}
+
+		if found {
+			previous[id] = old
+		}
+		if op.Op == batchDelete {
+			deleteIDs = append(deleteIDs, id)
+		} else {
+			op.Rule.Revision = nextRevision(previous[id])
+			putIndex[id] = i
+			puts = append(puts, op.Rule)
+		}
+	}
+
+	var before []*config.RedirectRule
+	for _, rule := range previous {
+		before = append(before, rule)
+	}
+	auditDetail(r, "", before, req.Operations)
+
+	if stale {
+		respondBatchFailure(w, http.StatusPreconditionFailed, "批量操作中的规则已被其他人修改", results)
+		return
+	}
+	if failed {
+		respondBatchFailure(w, http.StatusBadRequest, "批量操作校验失败", results)
+		return
+	}
+
+	// 在持有写锁时再次确认版本未变化，并在变更后的规则集上检查冲突，通过后一次性持久化
+	applied, err := a.config.ApplyBatch(puts, deleteIDs, func(staged *config.Config) bool {
+		for i, op := range req.Operations {
+			if op.Op == batchCreate || !op.hasPrecondition() {
+				continue
+			}
+			if current, _ := a.config.GetRuleByID(results[i].ID); current == nil || !op.matches(current) {
+				results[i].Status = batchFailed
+				results[i].Error = "规则已被其他人修改"
+				if current != nil && p.CanRead(current.Domain) {
+					results[i].Current = current
+				}
+				stale = true
+			}
+		}
+		if stale {
+			return false
+		}
+		for _, rule := range puts {
+			res := &results[putIndex[rule.ID]]
+			if _, found := existing[rule.ID]; !found && a.ruleIDTaken(rule.ID) {
+				// 校验之后被并发请求创建
+				res.Status = batchFailed
+				res.Error = "规则 ID 已存在"
+				failed = true
+				continue
+			}
+			conflicts, conflictMsg := staged.CheckConflict(rule, rule.ID, canRead(r))
+			if conflictMsg != "" {
+				res.Status = batchFailed
+				res.Error = conflictMsg
+				res.Conflicts = conflicts
+				failed = true
+			}
+		}
+		return !failed
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to apply batch: "+err.Error())
+		return
+	}
+	if !applied {
+		if stale {
+			respondBatchFailure(w, http.StatusPreconditionFailed, "批量操作中的规则已被其他人修改", results)
+		} else {
+			respondBatchFailure(w, http.StatusConflict, "批量操作中的规则存在冲突", results)
+		}
+		return
+	}
+
+	events := make([]*webhook.Event, 0, len(req.Operations))
+	for i, op := range req.Operations {
+		res := &results[i]
+		res.Status = batchApplied
+		switch op.Op {
+		case batchCreate:
+			res.Rule = op.Rule
+			events = append(events, newEvent(r, webhook.EventRuleCreated, op.Rule, nil))
+		case batchUpdate:
+			res.Rule = op.Rule
+			events = append(events, newEvent(r, webhook.EventRuleUpdated, op.Rule, previous[res.ID]))
+		case batchDelete:
+			events = append(events, newEvent(r, webhook.EventRuleDeleted, previous[res.ID], nil))
+		}
+	}
+	a.hooks.Emit(events...)
+
+	ids := make([]string, 0, len(puts))
+	for _, rule := range puts {
+		ids = append(ids, rule.ID)
+	}
+	a.warnLongChains(w, ids...)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Batch applied",
+		"applied": len(results),
+		"results": results,
+	})
+}
+
+// respondBatchFailure 返回批量操作失败的响应，未出错的项标记为未生效
+func respondBatchFailure(w http.ResponseWriter, status int, message string, results []batchResult) {
+	for i := range results {
+		if results[i].Status == "" {
+			results[i].Status = batchNotApplied
+		}
+	}
+	respondJSON(w, status, map[string]interface{}{
+		"error":   message,
+		"results": results,
+	})
+}
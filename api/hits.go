@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"mini_jump/config"
+	"mini_jump/hits"
+)
+
+// ruleWithHits 规则列表中的规则，附带命中概况
+type ruleWithHits struct {
+	*config.RedirectRule
+	Hits *hits.Summary `json:"hits,omitempty"`
+}
+
+// rulePageWithHits 附带命中概况的规则分页结果
+type rulePageWithHits struct {
+	Rules      []ruleWithHits `json:"rules"`
+	Total      int            `json:"total"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// SetHits 启用规则命中计数，未设置时不返回命中统计
+func (a *API) SetHits(c *hits.Counter) {
+	a.hits = c
+}
+
+// withHits 为分页结果中的规则附带命中概况（未启用命中计数时原样返回）
+func (a *API) withHits(page *config.RulePage) interface{} {
+	if a.hits == nil {
+		return page
+	}
+	result := &rulePageWithHits{
+		Rules:      make([]ruleWithHits, 0, len(page.Rules)),
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+	}
+	for _, rule := range page.Rules {
+		summary := a.hits.Summary(rule.ID)
+		result.Rules = append(result.Rules, ruleWithHits{RedirectRule: rule, Hits: &summary})
+	}
+	return result
+}
+
+// RuleHits 获取规则的命中统计：累计次数、最后命中时间和最近 30 天每天的命中次数
+func (a *API) RuleHits(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if a.hits == nil {
+		respondError(w, http.StatusNotFound, "Hit counters are not enabled")
+		return
+	}
+	rule, ok := a.config.GetRuleByID(id)
+	if !ok || !principal(r).CanRead(rule.Domain) {
+		respondError(w, http.StatusNotFound, "Rule not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, a.hits.Stats(id, time.Now()))
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini_jump/auth"
+	"mini_jump/config"
+	"mini_jump/logger"
+)
+
+// liveHeartbeat 实时日志流的心跳间隔，防止代理因空闲断开连接
+const liveHeartbeat = 15 * time.Second
+
+// SetAccessLogger 设置访问日志，用于推送实时访问日志（未设置时不提供）
+func (a *API) SetAccessLogger(l *logger.Logger) {
+	a.access = l
+}
+
+// LiveTraffic 以 Server-Sent Events 推送实时访问日志，只推送调用者可查看的域名
+// 查询参数：domain（支持 *.example.com）、rule_id、status
+// 每条访问日志为一个 access 事件；处理不及时丢弃日志时发送 dropped 事件，data 为累计丢弃条数
+func (a *API) LiveTraffic(w http.ResponseWriter, r *http.Request) {
+	if a.access == nil {
+		respondError(w, http.StatusNotFound, "Live traffic is not enabled")
+		return
+	}
+
+	query := r.URL.Query()
+	domain := strings.ToLower(query.Get("domain"))
+	ruleID := query.Get("rule_id")
+	status := 0
+	if v := query.Get("status"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 100 || n > 599 {
+			respondValidationError(w, config.ValidationErrors{{Field: "status", Message: "必须是 HTTP 状态码"}})
+			return
+		}
+		status = n
+	}
+
+	p := principal(r)
+	sub := a.access.Subscribe(func(log *logger.AccessLog) bool {
+		if domain != "" && !auth.MatchDomain(domain, strings.ToLower(log.Domain)) {
+			return false
+		}
+		if ruleID != "" && log.RuleID != ruleID {
+			return false
+		}
+		if status != 0 && log.StatusCode != status {
+			return false
+		}
+		return p.CanRead(log.Domain)
+	})
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // 禁止 nginx 缓冲
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(liveHeartbeat)
+	defer heartbeat.Stop()
+	var reported int64
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case entry, ok := <-sub.C:
+			if !ok {
+				return // 日志管理器已关闭
+			}
+			data, _ := json.Marshal(entry)
+			fmt.Fprintf(w, "event: access\ndata: %s\n\n", data)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		if dropped := sub.Dropped(); dropped != reported {
+			reported = dropped
+			fmt.Fprintf(w, "event: dropped\ndata: %d\n\n", dropped)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"mini_jump/metrics"
+)
+
+// SetMetrics 启用监控指标，记录 API 请求数和重新加载结果
+func (a *API) SetMetrics(m *metrics.Metrics) {
+	a.metrics = m
+}
+
+// counted 按路由模板记录 API 请求数（路由模板而不是实际路径，避免标签随规则 ID 增长）
+func (a *API) counted(next http.Handler) http.Handler {
+	if a.metrics == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		a.metrics.APIRequest(r.Method, route, rec.status)
+	})
+}
+
+// statusRecorder 记录响应状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader 记录第一次写入的状态码
+func (w *statusRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap 返回原始 ResponseWriter，使实时日志流可以通过 http.ResponseController 刷新
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPIDocument 管理 API 的 OpenAPI 3 文档
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// OpenAPI 返回 OpenAPI 文档（无需认证）
+func (a *API) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
@@ -0,0 +1,658 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"mini_jump/analytics"
+	"mini_jump/auth"
+	"mini_jump/config"
+	"mini_jump/handler"
+	"mini_jump/hits"
+	"mini_jump/logger"
+	"mini_jump/metrics"
+	"mini_jump/webhook"
+)
+
+// 契约检查使用的凭据
+const (
+	contractAdminToken  = "contract-admin-token"
+	contractEditorToken = "contract-editor-token" // 只能修改 example.com 的规则
+	contractPassword    = "contract-password"
+)
+
+// contractRequest 契约检查中的一次请求
+type contractRequest struct {
+	method  string
+	route   string            // 文档中的路径模板
+	path    string            // 实际请求路径（为空时同 route）
+	token   string            // Bearer Token（为空时不认证）
+	headers map[string]string // 额外请求头
+	body    string
+	status  int // 期望的状态码
+}
+
+// contractChecker 用真实的路由和处理器发送请求，核对响应是否与 OpenAPI 文档一致
+type contractChecker struct {
+	t       *testing.T
+	api     *API
+	spec    *openAPISpec
+	router  *mux.Router
+	covered map[string]bool // 已检查的操作
+	session string          // 最近一次登录得到的会话 ID
+}
+
+// TestOpenAPIContract 检查 API 与 OpenAPI 文档是否一致
+// 在临时目录中启动一套完整的 API（认证、审计日志、Webhook），依次请求每个接口，检查：
+//   - RegisterRoutes 注册的每个路由都在文档中声明
+//   - 文档中的每个操作都被请求过
+//   - 每个响应的状态码在文档中声明，响应体符合对应的 schema（包括未声明的字段）
+func TestOpenAPIContract(t *testing.T) {
+	// 检查过程中的认证失败等日志属于预期，不输出
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	spec, err := loadOpenAPISpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	api, cleanup, err := newContractAPI(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	c := &contractChecker{
+		t:       t,
+		api:     api,
+		spec:    spec,
+		router:  mux.NewRouter(),
+		covered: make(map[string]bool),
+	}
+	api.RegisterRoutes(c.router)
+
+	c.checkRoutes()
+	c.runScenario()
+	for _, op := range spec.operations() {
+		if !c.covered[op] {
+			c.fail(op, "文档中的操作未被检查")
+		}
+	}
+}
+
+// newContractAPI 在 dir 中创建带认证、审计日志和 Webhook 的 API
+func newContractAPI(dir string) (*API, func(), error) {
+	cfg := config.GetDefaultConfig()
+	store, err := config.OpenStore(config.StoreJSON, filepath.Join(dir, "rules.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.SetStore(store)
+	if err := cfg.Load(); err != nil {
+		return nil, nil, err
+	}
+
+	passwordHash, err := auth.HashPassword(contractPassword)
+	if err != nil {
+		return nil, nil, err
+	}
+	creds, _ := json.Marshal(auth.Credentials{
+		Tokens: []auth.TokenCredential{
+			{Name: "admin", Hash: auth.HashToken(contractAdminToken), Role: auth.RoleAdmin},
+			{Name: "editor", Hash: auth.HashToken(contractEditorToken), Role: auth.RoleEditor, Domains: []string{"example.com"}},
+		},
+		Users: []auth.UserCredential{
+			{Username: "admin", Password: passwordHash, Role: auth.RoleAdmin},
+		},
+	})
+	credFile := filepath.Join(dir, "credentials.json")
+	if err := os.WriteFile(credFile, creds, 0600); err != nil {
+		return nil, nil, err
+	}
+	authenticator, err := auth.NewAuthenticator(credFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accessLogger, err := logger.NewLogger(filepath.Join(dir, "access.log"), 100, 60)
+	if err != nil {
+		return nil, nil, err
+	}
+	auditLogger, err := logger.NewLogger(filepath.Join(dir, "audit.log"), 1, 60)
+	if err != nil {
+		accessLogger.Close()
+		return nil, nil, err
+	}
+	hitCounter, err := hits.Open(filepath.Join(dir, "hits.json"), 0)
+	if err != nil {
+		accessLogger.Close()
+		auditLogger.Close()
+		return nil, nil, err
+	}
+	stats, err := analytics.Open(filepath.Join(dir, "analytics"), 0, 0)
+	if err != nil {
+		accessLogger.Close()
+		auditLogger.Close()
+		hitCounter.Close()
+		return nil, nil, err
+	}
+	hooks, err := webhook.NewDispatcher(filepath.Join(dir, "webhooks.db"))
+	if err != nil {
+		accessLogger.Close()
+		auditLogger.Close()
+		hitCounter.Close()
+		stats.Close()
+		return nil, nil, err
+	}
+
+	// 启用监控指标，使检查覆盖记录状态码的中间件
+	m := metrics.New()
+	redirect := handler.NewHandler(cfg, accessLogger)
+	redirect.SetHits(hitCounter)
+	redirect.SetAnalytics(stats)
+	redirect.SetMetrics(m)
+	api := NewAPI(cfg, redirect)
+	api.SetAuth(authenticator)
+	api.SetAuditLogger(auditLogger)
+	api.SetAccessLogger(accessLogger)
+	api.SetHits(hitCounter)
+	api.SetAnalytics(stats)
+	api.SetWebhooks(hooks)
+	api.SetMetrics(m)
+	cleanup := func() {
+		hooks.Close()
+		hitCounter.Close()
+		stats.Close()
+		auditLogger.Close()
+		accessLogger.Close()
+		cfg.Close()
+	}
+	return api, cleanup, nil
+}
+
+// checkRoutes 检查所有已注册的 API 路由都在文档中声明
+func (c *contractChecker) checkRoutes() {
+	c.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(path, "/api") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // 子路由前缀
+		}
+		for _, method := range methods {
+			if _, ok := c.spec.operation(method, path); !ok {
+				c.fail(method+" "+path, "路由未在文档中声明")
+			}
+		}
+		return nil
+	})
+}
+
+// do 发送请求并核对响应，返回解码后的响应体
+func (c *contractChecker) do(req contractRequest) interface{} {
+	path := req.path
+	if path == "" {
+		path = req.route
+	}
+	op := req.method + " " + req.route
+	label := fmt.Sprintf("%s %s -> %d", req.method, path, req.status)
+	c.covered[op] = true
+
+	r := httptest.NewRequest(req.method, path, strings.NewReader(req.body))
+	if req.body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if req.token != "" {
+		r.Header.Set("Authorization", "Bearer "+req.token)
+	}
+	for name, value := range req.headers {
+		r.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	c.router.ServeHTTP(rec, r)
+
+	var problems []string
+	if rec.Code != req.status {
+		problems = append(problems, fmt.Sprintf("状态码为 %d（响应: %s）", rec.Code, strings.TrimSpace(rec.Body.String())))
+	}
+	schema, declared := c.spec.responseSchema(req.method, req.route, rec.Code)
+	var body interface{}
+	switch {
+	case !declared:
+		problems = append(problems, fmt.Sprintf("文档未声明状态码 %d", rec.Code))
+	case schema != nil:
+		if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+			problems = append(problems, "Content-Type 不是 application/json")
+		}
+		if err := decodeJSON(rec.Body.Bytes(), &body); err != nil {
+			problems = append(problems, "响应体不是有效的 JSON: "+err.Error())
+		} else {
+			problems = append(problems, c.spec.validate(schema, body, "body")...)
+		}
+	}
+
+	if len(problems) > 0 {
+		c.fail(label, problems...)
+	} else {
+		c.t.Logf("ok    %s", label)
+	}
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == auth.SessionCookieName && cookie.Value != "" {
+			c.session = cookie.Value
+		}
+	}
+	return body
+}
+
+// stream 订阅事件流，触发一次跳转后核对收到的 access 事件
+// 事件流不会结束，因此通过真实的 HTTP 服务读取，收到事件后断开连接
+func (c *contractChecker) stream(req contractRequest, host, redirectPath string) {
+	label := fmt.Sprintf("%s %s -> %d", req.method, req.path, req.status)
+	c.covered[req.method+" "+req.route] = true
+
+	srv := httptest.NewServer(c.router)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r, _ := http.NewRequestWithContext(ctx, req.method, srv.URL+req.path, nil)
+	r.Header.Set("Authorization", "Bearer "+req.token)
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		c.fail(label, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	var problems []string
+	if resp.StatusCode != req.status {
+		problems = append(problems, fmt.Sprintf("状态码为 %d", resp.StatusCode))
+	}
+	if _, declared := c.spec.responseSchema(req.method, req.route, resp.StatusCode); !declared {
+		problems = append(problems, fmt.Sprintf("文档未声明状态码 %d", resp.StatusCode))
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		problems = append(problems, "Content-Type 不是 text/event-stream")
+	}
+	if len(problems) > 0 {
+		c.fail(label, problems...)
+		return
+	}
+
+	// 响应头返回时订阅已经建立，此时触发的跳转一定会推送
+	redirect := httptest.NewRequest("GET", "http://"+host+redirectPath, nil)
+	redirect.Header.Set("Referer", "https://referrer.example/")
+	c.api.redirect.HandleRedirect(httptest.NewRecorder(), redirect)
+
+	scanner := bufio.NewScanner(resp.Body)
+	event := ""
+	received := false
+	for !received && scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+		} else if data, ok := strings.CutPrefix(line, "data: "); ok && event == "access" {
+			var body interface{}
+			if err := decodeJSON([]byte(data), &body); err != nil {
+				problems = append(problems, "事件不是有效的 JSON: "+err.Error())
+			} else {
+				schema := map[string]interface{}{"$ref": "#/components/schemas/AccessLog"}
+				problems = append(problems, c.spec.validate(schema, body, "event")...)
+			}
+			received = true
+		}
+	}
+	if !received {
+		problems = append(problems, "未收到 access 事件")
+	}
+
+	if len(problems) > 0 {
+		c.fail(label, problems...)
+	} else {
+		c.t.Logf("ok    %s", label)
+	}
+}
+
+// fail 记录一条检查失败
+func (c *contractChecker) fail(label string, problems ...string) {
+	c.t.Errorf("%s\n      %s", label, strings.Join(problems, "\n      "))
+}
+
+// field 读取响应对象的字符串字段（不存在时为空）
+func field(body interface{}, name string) string {
+	obj, _ := body.(map[string]interface{})
+	s, _ := obj[name].(string)
+	return s
+}
+
+// runScenario 依次请求所有接口，覆盖成功和主要的错误响应
+func (c *contractChecker) runScenario() {
+	const (
+		admin  = contractAdminToken
+		editor = contractEditorToken
+	)
+	ruleA := `{"id":"a","domain":"example.com","path":"/a","target":"https://example.com/b","type":301,"description":"a"}`
+	ruleB := `{"id":"b","domain":"example.com","path":"/b","target":"https://example.org/","type":302}`
+	anyVersion := map[string]string{"If-Match": "*"}
+
+	// 文档与认证
+	c.do(contractRequest{method: "GET", route: "/api/openapi.json", status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/me", status: 401})
+	c.do(contractRequest{method: "GET", route: "/api/me", token: admin, status: 200})
+	c.do(contractRequest{method: "POST", route: "/api/login", body: `{`, status: 400})
+	c.do(contractRequest{method: "POST", route: "/api/login", body: `{"username":"admin","password":"wrong"}`, status: 401})
+	c.do(contractRequest{method: "POST", route: "/api/login",
+		body: `{"username":"admin","password":"` + contractPassword + `"}`, status: 200})
+	cookie := map[string]string{"Cookie": auth.SessionCookieName + "=" + c.session}
+	c.do(contractRequest{method: "GET", route: "/api/me", headers: cookie, status: 200})
+	c.do(contractRequest{method: "POST", route: "/api/logout", headers: cookie, status: 200})
+
+	// 创建与查询
+	c.do(contractRequest{method: "POST", route: "/api/rules", token: admin, body: ruleA, status: 201})
+	c.do(contractRequest{method: "POST", route: "/api/rules", token: admin, body: ruleB, status: 201})
+	c.do(contractRequest{method: "POST", route: "/api/rules", token: admin, body: `{"domain":"example.com","target":"ftp://x","type":999}`, status: 400})
+	c.do(contractRequest{method: "POST", route: "/api/rules", token: admin, body: `{"domain":"example.com","path":"/a","target":"https://example.com/","type":301}`, status: 409})
+	c.do(contractRequest{method: "POST", route: "/api/rules", token: editor, body: `{"domain":"other.com","target":"https://example.com/","type":301}`, status: 403})
+	c.do(contractRequest{method: "GET", route: "/api/rules", token: admin, status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/rules", path: "/api/rules?limit=1&sort=-created_at", token: admin, status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/rules", path: "/api/rules?sort=bogus", token: admin, status: 400})
+	c.do(contractRequest{method: "GET", route: "/api/rules/{id}", path: "/api/rules/a", token: admin, status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/rules/{id}", path: "/api/rules/missing", token: admin, status: 404})
+
+	// 替换
+	c.do(contractRequest{method: "PUT", route: "/api/rules/{id}", path: "/api/rules/a", token: admin, body: ruleA, status: 428})
+	c.do(contractRequest{method: "PUT", route: "/api/rules/{id}", path: "/api/rules/a", token: admin, body: ruleA,
+		headers: map[string]string{"If-Match": `"1"`}, status: 200})
+	c.do(contractRequest{method: "PUT", route: "/api/rules/{id}", path: "/api/rules/a", token: admin, body: ruleA,
+		headers: map[string]string{"If-Match": `"1"`}, status: 412})
+	c.do(contractRequest{method: "PUT", route: "/api/rules/{id}", path: "/api/rules/a", token: admin, body: ruleB, headers: anyVersion, status: 409})
+	c.do(contractRequest{method: "PUT", route: "/api/rules/{id}", path: "/api/rules/a", token: admin, body: `{"type":1}`, headers: anyVersion, status: 400})
+	c.do(contractRequest{method: "PUT", route: "/api/rules/{id}", path: "/api/rules/missing", token: admin, body: ruleA, headers: anyVersion, status: 404})
+	c.do(contractRequest{method: "PUT", route: "/api/rules/{id}", path: "/api/rules/a", token: editor, headers: anyVersion,
+		body: `{"domain":"other.com","target":"https://example.com/","type":301}`, status: 403})
+
+	// 部分更新
+	c.do(contractRequest{method: "PATCH", route: "/api/rules/{id}", path: "/api/rules/a", token: admin, body: `{"description":null}`,
+		headers: map[string]string{"If-Match": `"2"`, "Content-Type": MergePatchContentType}, status: 200})
+	c.do(contractRequest{method: "PATCH", route: "/api/rules/{id}", path: "/api/rules/a", token: admin, body: `{}`, status: 428})
+	c.do(contractRequest{method: "PATCH", route: "/api/rules/{id}", path: "/api/rules/a", token: admin, body: `{}`,
+		headers: map[string]string{"If-Match": `"2"`}, status: 412})
+	c.do(contractRequest{method: "PATCH", route: "/api/rules/{id}", path: "/api/rules/a", token: admin, body: `[]`, headers: anyVersion, status: 400})
+	c.do(contractRequest{method: "PATCH", route: "/api/rules/{id}", path: "/api/rules/a", token: admin, body: `{}`,
+		headers: map[string]string{"If-Match": "*", "Content-Type": "text/plain"}, status: 415})
+	c.do(contractRequest{method: "PATCH", route: "/api/rules/{id}", path: "/api/rules/a", token: admin, body: `{"path":"/b"}`, headers: anyVersion, status: 409})
+	c.do(contractRequest{method: "PATCH", route: "/api/rules/{id}", path: "/api/rules/a", token: editor, body: `{"domain":"other.com"}`, headers: anyVersion, status: 403})
+	c.do(contractRequest{method: "PATCH", route: "/api/rules/{id}", path: "/api/rules/missing", token: admin, body: `{}`, headers: anyVersion, status: 404})
+
+	// 批量操作与导入
+	c.do(contractRequest{method: "POST", route: "/api/rules/batch", token: admin, status: 200, body: `{"operations":[
+		{"op":"create","rule":{"id":"c","domain":"example.com","path":"/c","target":"https://example.com/a","type":302}},
+		{"op":"update","id":"b","rule":{"domain":"example.com","path":"/b","target":"https://example.net/","type":301}}]}`})
+	c.do(contractRequest{method: "POST", route: "/api/rules/batch", token: admin, status: 400, body: `{"operations":[
+		{"op":"create","rule":{"domain":"example.com","path":"/d","target":"https://example.com/","type":301}},
+		{"op":"delete","id":"missing"}]}`})
+	c.do(contractRequest{method: "POST", route: "/api/rules/batch", token: admin, status: 409, body: `{"operations":[
+		{"op":"create","rule":{"id":"d","domain":"example.com","path":"/a","target":"https://example.com/","type":301}}]}`})
+	c.do(contractRequest{method: "POST", route: "/api/rules/batch", token: admin, status: 412, body: `{"operations":[
+		{"op":"update","id":"b","if_match":"\"2\"","rule":{"domain":"example.com","path":"/b","target":"https://example.net/","type":301}},
+		{"op":"delete","id":"c","revision":2}]}`})
+	c.do(contractRequest{method: "POST", route: "/api/import", token: admin, status: 200,
+		body: `[{"id":"e","domain":"example.com","path":"/e","target":"https://example.com/","type":307}]`})
+	c.do(contractRequest{method: "POST", route: "/api/import", token: admin, status: 400, body: `[{"domain":"example.com","type":1}]`})
+	c.do(contractRequest{method: "POST", route: "/api/import", token: admin, status: 409,
+		body: `[{"id":"f","domain":"example.com","path":"/e","target":"https://example.com/","type":301}]`})
+
+	// 分析与测试
+	c.do(contractRequest{method: "GET", route: "/api/analysis/chains", token: admin, status: 200})
+	c.do(contractRequest{method: "POST", route: "/api/test", token: admin, body: `{"url":"https://example.com/c","client_ip":"192.0.2.1"}`, status: 200})
+	c.do(contractRequest{method: "POST", route: "/api/test", token: admin, body: `{"url":"https://nothing.example.net/"}`, status: 200})
+	c.do(contractRequest{method: "POST", route: "/api/test", token: admin, body: `{"url":"/no-host"}`, status: 400})
+	c.do(contractRequest{method: "POST", route: "/api/test", token: editor, body: `{"url":"https://other.com/"}`, status: 403})
+
+	// 实时访问日志
+	c.stream(contractRequest{method: "GET", route: "/api/live", path: "/api/live?domain=example.com&status=301", token: admin, status: 200},
+		"example.com", "/a")
+	c.do(contractRequest{method: "GET", route: "/api/live", path: "/api/live?status=abc", token: admin, status: 400})
+	c.do(contractRequest{method: "GET", route: "/api/live", status: 401})
+
+	// 命中统计（上面的跳转命中了规则 a）
+	c.do(contractRequest{method: "GET", route: "/api/rules", path: "/api/rules?sort=-hits", token: admin, status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/rules/{id}/hits", path: "/api/rules/a/hits", token: admin, status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/rules/{id}/hits", path: "/api/rules/missing/hits", token: admin, status: 404})
+
+	// 访问统计（跳转是异步汇总的，这里直接汇总一条以保证结果非空）
+	c.api.stats.Add(&logger.AccessLog{Timestamp: time.Now(), Domain: "example.com", Path: "/c", RuleID: "c",
+		UserAgent: "Mozilla/5.0 (iPhone)", Referer: "https://referrer.example/x", Country: "de", StatusCode: 302})
+	c.do(contractRequest{method: "GET", route: "/api/stats", token: admin, status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/stats", path: "/api/stats?interval=hour&group_by=rule,status,ua,referer,country,domain", token: editor, status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/stats", path: "/api/stats?interval=day&domain=*.example.com", token: admin, status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/stats", path: "/api/stats?group_by=city&interval=week", token: admin, status: 400})
+
+	// 删除
+	c.do(contractRequest{method: "DELETE", route: "/api/rules/{id}", path: "/api/rules/e", token: admin, status: 428})
+	c.do(contractRequest{method: "DELETE", route: "/api/rules/{id}", path: "/api/rules/e", token: admin,
+		headers: map[string]string{"If-Match": `"9"`}, status: 412})
+	c.do(contractRequest{method: "DELETE", route: "/api/rules/{id}", path: "/api/rules/e", token: admin,
+		headers: map[string]string{"If-Match": `"1"`}, status: 200})
+	c.do(contractRequest{method: "DELETE", route: "/api/rules/{id}", path: "/api/rules/e", token: admin, headers: anyVersion, status: 404})
+
+	// 配置与审计日志
+	c.do(contractRequest{method: "POST", route: "/api/save", token: admin, status: 200})
+	c.do(contractRequest{method: "POST", route: "/api/save", token: editor, status: 403})
+	c.do(contractRequest{method: "POST", route: "/api/reload", token: admin, status: 200})
+	c.do(contractRequest{method: "POST", route: "/api/reload", token: editor, status: 403})
+	c.do(contractRequest{method: "GET", route: "/api/audit", token: admin, status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/audit", path: "/api/audit?limit=x", token: admin, status: 400})
+	c.do(contractRequest{method: "GET", route: "/api/audit", token: editor, status: 403})
+
+	// Webhook（端点地址不可达，投递会保持待重试状态）
+	endpoint := `{"url":"http://127.0.0.1:9/hook","events":["rule.updated"],"description":"contract"}`
+	c.do(contractRequest{method: "GET", route: "/api/webhooks", token: editor, status: 403})
+	id := field(c.do(contractRequest{method: "POST", route: "/api/webhooks", token: admin, body: endpoint, status: 201}), "id")
+	c.do(contractRequest{method: "POST", route: "/api/webhooks", token: admin, body: `{"url":"ftp://x"}`, status: 400})
+	c.do(contractRequest{method: "GET", route: "/api/webhooks", token: admin, status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/webhooks/{id}", path: "/api/webhooks/" + id, token: admin, status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/webhooks/{id}", path: "/api/webhooks/missing", token: admin, status: 404})
+	c.do(contractRequest{method: "PUT", route: "/api/webhooks/{id}", path: "/api/webhooks/" + id, token: admin, body: endpoint, status: 200})
+	c.do(contractRequest{method: "PUT", route: "/api/webhooks/{id}", path: "/api/webhooks/" + id, token: admin, body: `{"url":""}`, status: 400})
+	delivery := field(c.do(contractRequest{method: "POST", route: "/api/webhooks/{id}/ping", path: "/api/webhooks/" + id + "/ping", token: admin, status: 202}), "id")
+	c.do(contractRequest{method: "POST", route: "/api/webhooks/{id}/ping", path: "/api/webhooks/missing/ping", token: admin, status: 404})
+	c.do(contractRequest{method: "GET", route: "/api/webhooks/deliveries", token: admin, status: 200})
+	c.do(contractRequest{method: "GET", route: "/api/webhooks/deliveries", path: "/api/webhooks/deliveries?limit=0", token: admin, status: 400})
+	c.do(contractRequest{method: "POST", route: "/api/webhooks/deliveries/{id}/redeliver", path: "/api/webhooks/deliveries/" + delivery + "/redeliver", token: admin, status: 202})
+	c.do(contractRequest{method: "POST", route: "/api/webhooks/deliveries/{id}/redeliver", path: "/api/webhooks/deliveries/missing/redeliver", token: admin, status: 404})
+	c.do(contractRequest{method: "DELETE", route: "/api/webhooks/{id}", path: "/api/webhooks/" + id, token: admin, status: 200})
+	c.do(contractRequest{method: "DELETE", route: "/api/webhooks/{id}", path: "/api/webhooks/" + id, token: admin, status: 404})
+}
+
+// openAPISpec 解析后的 OpenAPI 文档，用于核对路由和响应是否与文档一致
+type openAPISpec struct {
+	doc map[string]interface{}
+}
+
+// loadOpenAPISpec 解析内置的 OpenAPI 文档（数字保留为 json.Number）
+func loadOpenAPISpec() (*openAPISpec, error) {
+	var doc map[string]interface{}
+	if err := decodeJSON(openAPIDocument, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	return &openAPISpec{doc: doc}, nil
+}
+
+// operations 返回文档中的全部操作，格式为 "METHOD /path"
+func (s *openAPISpec) operations() []string {
+	var ops []string
+	paths, _ := s.doc["paths"].(map[string]interface{})
+	for path, item := range paths {
+		methods, _ := item.(map[string]interface{})
+		for method := range methods {
+			if method != "parameters" {
+				ops = append(ops, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// operation 返回指定路径模板和方法的操作定义
+func (s *openAPISpec) operation(method, path string) (map[string]interface{}, bool) {
+	paths, _ := s.doc["paths"].(map[string]interface{})
+	item, _ := paths[path].(map[string]interface{})
+	op, ok := item[strings.ToLower(method)].(map[string]interface{})
+	return op, ok
+}
+
+// responseSchema 返回操作在指定状态码下的 JSON 响应体 schema
+// declared 为 false 表示文档未声明该状态码；schema 为 nil 表示该响应没有响应体
+func (s *openAPISpec) responseSchema(method, path string, status int) (schema map[string]interface{}, declared bool) {
+	op, ok := s.operation(method, path)
+	if !ok {
+		return nil, false
+	}
+	responses, _ := op["responses"].(map[string]interface{})
+	resp, ok := responses[strconv.Itoa(status)].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	resp = s.deref(resp)
+	content, _ := resp["content"].(map[string]interface{})
+	media, _ := content["application/json"].(map[string]interface{})
+	schema, _ = media["schema"].(map[string]interface{})
+	return schema, true
+}
+
+// deref 解析 $ref 引用（仅支持文档内引用）
+func (s *openAPISpec) deref(node map[string]interface{}) map[string]interface{} {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		var target interface{} = s.doc
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, _ := target.(map[string]interface{})
+			target = m[part]
+		}
+		next, ok := target.(map[string]interface{})
+		if !ok {
+			return map[string]interface{}{}
+		}
+		node = next
+	}
+}
+
+// validate 校验 JSON 值是否符合 schema，返回所有不符合之处（at 为值所在位置）
+// 支持 OpenAPI 3.0 的 $ref、allOf、nullable、type、enum、format（date-time）、
+// required、properties、additionalProperties 和 items
+func (s *openAPISpec) validate(schema map[string]interface{}, value interface{}, at string) []string {
+	schema = s.deref(schema)
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable || (schema["type"] == nil && schema["allOf"] == nil) {
+			return nil
+		}
+		return []string{at + ": 不能为 null"}
+	}
+
+	var errs []string
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if m, ok := sub.(map[string]interface{}); ok {
+				errs = append(errs, s.validate(m, value, at)...)
+			}
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || fmt.Sprint(e) == fmt.Sprint(value)
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: %v 不在允许的取值 %v 中", at, value, enum))
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return append(errs, at+": 应为对象")
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: 缺少必需字段 %s", at, name))
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if prop, ok := props[key].(map[string]interface{}); ok {
+				errs = append(errs, s.validate(prop, obj[key], at+"."+key)...)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					errs = append(errs, fmt.Sprintf("%s: 文档中未定义字段 %s", at, key))
+				}
+			case map[string]interface{}:
+				errs = append(errs, s.validate(extra, obj[key], at+"."+key)...)
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return append(errs, at+": 应为数组")
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range arr {
+				errs = append(errs, s.validate(items, item, fmt.Sprintf("%s[%d]", at, i))...)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return append(errs, at+": 应为字符串")
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				errs = append(errs, at+": 应为 RFC 3339 时间")
+			}
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if _, err := n.Int64(); !ok || err != nil {
+			errs = append(errs, at+": 应为整数")
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			errs = append(errs, at+": 应为数字")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs = append(errs, at+": 应为布尔值")
+		}
+	}
+	return errs
+}
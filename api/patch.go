@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"mini_jump/config"
+)
+
+// MergePatchContentType JSON Merge Patch 的媒体类型
+const MergePatchContentType = "application/merge-patch+json"
+
+// PatchRule 按 JSON Merge Patch（RFC 7396）部分更新规则，需通过 If-Match 携带规则当前的 ETag
+// 补丁中未出现的字段保持不变，值为 null 的字段被清除（如 expires_at 置空表示永不过期）
+// 合并后的规则按完整规则校验并检查冲突
+func (a *API) PatchRule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, _ := mime.ParseMediaType(ct)
+		if mediaType != MergePatchContentType && mediaType != "application/json" {
+			respondError(w, http.StatusUnsupportedMediaType, "Content-Type 必须是 "+MergePatchContentType)
+			return
+		}
+	}
+	var patch interface{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&patch); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if _, ok := patch.(map[string]interface{}); !ok {
+		respondError(w, http.StatusBadRequest, "补丁必须是 JSON 对象")
+		return
+	}
+
+	rule, ok := a.config.GetRuleByID(id)
+	if !ok || !principal(r).CanRead(rule.Domain) {
+		respondError(w, http.StatusNotFound, "Rule not found")
+		return
+	}
+
+	updated, err := applyMergePatch(rule, patch)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "补丁无法应用到规则: "+err.Error())
+		return
+	}
+	// ID 以 URL 为准，修订号由服务端维护
+	updated.ID = id
+	auditDetail(r, id, rule, updated)
+	if errs := config.ValidateRuleForSave(updated); len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+	a.replaceRule(w, r, rule, updated)
+}
+
+// applyMergePatch 将补丁合并到规则的 JSON 表示上，返回合并后的新规则（不修改原规则）
+func applyMergePatch(rule *config.RedirectRule, patch interface{}) (*config.RedirectRule, error) {
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := decodeJSON(data, &doc); err != nil {
+		return nil, err
+	}
+
+	merged, err := json.Marshal(mergePatch(doc, patch))
+	if err != nil {
+		return nil, err
+	}
+	var updated config.RedirectRule
+	if err := json.Unmarshal(merged, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// mergePatch 按 RFC 7396 合并：对象逐字段递归合并，null 删除字段，其他值直接替换
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// decodeJSON 解码 JSON，数字保留为 json.Number
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mini_jump/config"
+)
+
+// ruleETag 返回规则当前修订号对应的 ETag
+func ruleETag(rule *config.RedirectRule) string {
+	return `"` + strconv.FormatInt(rule.Revision, 10) + `"`
+}
+
+// nextRevision 返回写入规则时的新修订号（previous 为 nil 表示新建）
+func nextRevision(previous *config.RedirectRule) int64 {
+	if previous == nil {
+		return 1
+	}
+	return previous.Revision + 1
+}
+
+// ifMatches 判断 If-Match 请求头是否与规则当前的 ETag 匹配（强比较，* 匹配任意版本）
+func ifMatches(header string, rule *config.RedirectRule) bool {
+	etag := ruleETag(rule)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchHeader 返回合并后的 If-Match 请求头
+func ifMatchHeader(r *http.Request) string {
+	return strings.Join(r.Header.Values("If-Match"), ",")
+}
+
+// checkIfMatch 校验修改规则的前置条件，缺少 If-Match 时返回 428，版本不一致时返回 412
+func checkIfMatch(w http.ResponseWriter, r *http.Request, rule *config.RedirectRule) bool {
+	header := ifMatchHeader(r)
+	if header == "" {
+		respondError(w, http.StatusPreconditionRequired, "缺少 If-Match 请求头，请先获取规则的 ETag")
+		return false
+	}
+	if !ifMatches(header, rule) {
+		respondStale(w, rule)
+		return false
+	}
+	return true
+}
+
+// respondStale 返回 412，并附带规则的当前版本供客户端对比
+func respondStale(w http.ResponseWriter, current *config.RedirectRule) {
+	w.Header().Set("ETag", ruleETag(current))
+	respondJSON(w, http.StatusPreconditionFailed, map[string]interface{}{
+		"error":   "规则已被其他人修改",
+		"current": current,
+	})
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mini_jump/analytics"
+	"mini_jump/config"
+)
+
+// SetAnalytics 启用访问统计，未设置时统计接口返回 404
+func (a *API) SetAnalytics(s *analytics.Aggregator) {
+	a.stats = s
+}
+
+// Stats 查询访问统计，只统计调用者可查看的域名
+// 查询参数：from、to（RFC 3339，默认最近 24 小时）、interval（hour/day）、
+// group_by（逗号分隔的维度：domain、rule、referer、ua、country、status）、domain、rule_id、limit（默认 10）
+func (a *API) Stats(w http.ResponseWriter, r *http.Request) {
+	if a.stats == nil {
+		respondError(w, http.StatusNotFound, "Analytics is not enabled")
+		return
+	}
+
+	query, errs := parseStatsQuery(r)
+	if errs = append(errs, query.Validate(time.Now())...); len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+
+	p := principal(r)
+	result, err := a.stats.Query(query, func(domain string) bool {
+		return p.CanRead(domain)
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to query analytics: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// parseStatsQuery 解析统计查询参数
+func parseStatsQuery(r *http.Request) (analytics.Query, config.ValidationErrors) {
+	values := r.URL.Query()
+	query := analytics.Query{
+		Interval: values.Get("interval"),
+		Domain:   strings.ToLower(values.Get("domain")),
+		RuleID:   values.Get("rule_id"),
+	}
+	var errs config.ValidationErrors
+	for name, t := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if v := values.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				errs = append(errs, config.FieldError{Field: name, Message: "必须是 RFC 3339 时间"})
+				continue
+			}
+			*t = parsed
+		}
+	}
+	if v := values.Get("group_by"); v != "" {
+		for _, dim := range strings.Split(v, ",") {
+			query.GroupBy = append(query.GroupBy, strings.TrimSpace(dim))
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			errs = append(errs, config.FieldError{Field: "limit", Message: "必须是正整数"})
+			n = 0
+		}
+		query.Limit = n
+	}
+	return query, errs
+}
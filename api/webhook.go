@@ -0,0 +1,244 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"mini_jump/config"
+	"mini_jump/webhook"
+)
+
+// SetWebhooks 启用 Webhook，未设置时不发送事件
+func (a *API) SetWebhooks(d *webhook.Dispatcher) {
+	a.hooks = d
+}
+
+// registerWebhookRoutes 注册 Webhook 管理接口（仅管理员）
+func (a *API) registerWebhookRoutes(r *mux.Router) {
+	r.HandleFunc("/webhooks", a.ListWebhooks).Methods("GET")
+	r.HandleFunc("/webhooks", a.audited("webhook_create", a.CreateWebhook)).Methods("POST")
+	// 投递记录路由需先于 /webhooks/{id} 注册
+	r.HandleFunc("/webhooks/deliveries", a.ListDeliveries).Methods("GET")
+	r.HandleFunc("/webhooks/deliveries/{id}/redeliver", a.Redeliver).Methods("POST")
+	r.HandleFunc("/webhooks/{id}", a.GetWebhook).Methods("GET")
+	r.HandleFunc("/webhooks/{id}", a.audited("webhook_update", a.UpdateWebhook)).Methods("PUT")
+	r.HandleFunc("/webhooks/{id}", a.audited("webhook_delete", a.DeleteWebhook)).Methods("DELETE")
+	r.HandleFunc("/webhooks/{id}/ping", a.PingWebhook).Methods("POST")
+}
+
+// emit 发送规则变更事件
+func (a *API) emit(r *http.Request, eventType string, rule, previous *config.RedirectRule) {
+	a.hooks.Emit(newEvent(r, eventType, rule, previous))
+}
+
+// newEvent 创建规则变更事件，操作者为当前请求的身份
+func newEvent(r *http.Request, eventType string, rule, previous *config.RedirectRule) *webhook.Event {
+	event := &webhook.Event{Type: eventType, Rule: rule, Previous: previous}
+	if rule != nil {
+		event.RuleID = rule.ID
+	}
+	if p := principal(r); p != nil {
+		event.Actor = p.Name
+	}
+	return event
+}
+
+// requireWebhooks 检查管理员权限和 Webhook 是否启用
+func (a *API) requireWebhooks(w http.ResponseWriter, r *http.Request) bool {
+	if !principal(r).IsAdmin() {
+		respondError(w, http.StatusForbidden, "仅管理员可以管理 Webhook")
+		return false
+	}
+	if a.hooks == nil {
+		respondError(w, http.StatusNotFound, "Webhooks are not enabled")
+		return false
+	}
+	return true
+}
+
+// ListWebhooks 列出所有 Webhook 端点（不返回密钥）
+func (a *API) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !a.requireWebhooks(w, r) {
+		return
+	}
+	endpoints, err := a.hooks.Endpoints()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to load webhooks: "+err.Error())
+		return
+	}
+	result := make([]webhook.Endpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		result = append(result, ep.Redacted())
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// CreateWebhook 创建 Webhook 端点，响应中返回签名密钥（仅此一次）
+func (a *API) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if !a.requireWebhooks(w, r) {
+		return
+	}
+	ep := webhook.Endpoint{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&ep); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	ep.ID = ""
+	ep.CreatedAt = time.Time{}
+	if errs := ep.Validate(); len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+	if err := a.hooks.SaveEndpoint(&ep); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save webhook: "+err.Error())
+		return
+	}
+	redacted := ep.Redacted()
+	auditDetail(r, ep.ID, nil, &redacted)
+	respondJSON(w, http.StatusCreated, ep)
+}
+
+// GetWebhook 获取 Webhook 端点（不返回密钥）
+func (a *API) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	if !a.requireWebhooks(w, r) {
+		return
+	}
+	ep, ok, err := a.hooks.Endpoint(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to load webhook: "+err.Error())
+		return
+	}
+	if !ok {
+		respondError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, ep.Redacted())
+}
+
+// UpdateWebhook 更新 Webhook 端点，secret 为空时保留原密钥
+func (a *API) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	if !a.requireWebhooks(w, r) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	existing, ok, err := a.hooks.Endpoint(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to load webhook: "+err.Error())
+		return
+	}
+	if !ok {
+		respondError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	var ep webhook.Endpoint
+	if err := json.NewDecoder(r.Body).Decode(&ep); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	ep.ID = id
+	ep.CreatedAt = existing.CreatedAt
+	if ep.Secret == "" {
+		ep.Secret = existing.Secret
+	}
+	before, after := existing.Redacted(), ep.Redacted()
+	auditDetail(r, id, &before, &after)
+	if errs := ep.Validate(); len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+	if err := a.hooks.SaveEndpoint(&ep); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save webhook: "+err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, ep.Redacted())
+}
+
+// DeleteWebhook 删除 Webhook 端点
+func (a *API) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if !a.requireWebhooks(w, r) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	auditDetail(r, id, nil, nil)
+	found, err := a.hooks.DeleteEndpoint(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to delete webhook: "+err.Error())
+		return
+	}
+	if !found {
+		respondError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Webhook deleted"})
+}
+
+// PingWebhook 向端点发送测试事件
+func (a *API) PingWebhook(w http.ResponseWriter, r *http.Request) {
+	if !a.requireWebhooks(w, r) {
+		return
+	}
+	delivery, err := a.hooks.Ping(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to queue ping: "+err.Error())
+		return
+	}
+	if delivery == nil {
+		respondError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	respondJSON(w, http.StatusAccepted, delivery)
+}
+
+// ListDeliveries 查询投递记录，按时间倒序
+// 查询参数：endpoint（端点 ID）、status（pending/delivered/failed）、limit（默认 100）
+func (a *API) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !a.requireWebhooks(w, r) {
+		return
+	}
+	query := r.URL.Query()
+	filter := webhook.DeliveryFilter{
+		EndpointID: query.Get("endpoint"),
+		Status:     query.Get("status"),
+		Limit:      100,
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			respondError(w, http.StatusBadRequest, "limit 必须是正整数")
+			return
+		}
+		filter.Limit = n
+	}
+
+	deliveries, err := a.hooks.Deliveries(filter)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to load deliveries: "+err.Error())
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*webhook.Delivery{}
+	}
+	respondJSON(w, http.StatusOK, deliveries)
+}
+
+// Redeliver 重新投递指定记录
+func (a *API) Redeliver(w http.ResponseWriter, r *http.Request) {
+	if !a.requireWebhooks(w, r) {
+		return
+	}
+	delivery, ok, err := a.hooks.Redeliver(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to redeliver: "+err.Error())
+		return
+	}
+	if !ok {
+		respondError(w, http.StatusNotFound, "Delivery not found")
+		return
+	}
+	respondJSON(w, http.StatusAccepted, delivery)
+}
@@ -0,0 +1,48 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	if err := WriteData(path, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteData(path, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "second" {
+		t.Fatalf("file = %q, %v", data, err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	// 写入失败时原文件不变，也不留下临时文件
+	failed := errors.New("encode failed")
+	err = Write(path, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Write error = %v, want %v", err, failed)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "second" {
+		t.Errorf("file = %q after a failed write", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d files left in the directory, want 1", len(entries))
+	}
+
+	// 目录不存在时返回错误
+	if err := WriteData(filepath.Join(dir, "missing", "data.json"), nil); err == nil {
+		t.Error("WriteData into a missing directory succeeded")
+	}
+}
@@ -0,0 +1,304 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 认证方式
+const (
+	MethodToken   = "token"   // API Token（Authorization: Bearer）
+	MethodBasic   = "basic"   // HTTP Basic（bcrypt 密码）
+	MethodSession = "session" // 管理页面登录会话
+)
+
+// tokenHashPrefix API Token 哈希前缀
+const tokenHashPrefix = "sha256:"
+
+// TokenCredential API Token 凭据（只保存哈希）
+type TokenCredential struct {
+	Name    string   `json:"name"`    // Token 名称（作为操作者标识）
+	Hash    string   `json:"hash"`    // Token 哈希，格式 sha256:<hex>
+	Role    Role     `json:"role"`    // 角色（为空时为 admin）
+	Domains []string `json:"domains"` // 可访问的域名或域名模式（viewer、editor 必填，* 表示全部）
+}
+
+// UserCredential 用户凭据
+type UserCredential struct {
+	Username string   `json:"username"` // 用户名
+	Password string   `json:"password"` // bcrypt 密码哈希
+	Role     Role     `json:"role"`     // 角色（为空时为 admin）
+	Domains  []string `json:"domains"`  // 可访问的域名或域名模式（viewer、editor 必填，* 表示全部）
+}
+
+// Credentials 凭据文件内容
+type Credentials struct {
+	Tokens []TokenCredential `json:"tokens"`
+	Users  []UserCredential  `json:"users"`
+}
+
+// Principal 已认证的操作者
+type Principal struct {
+	Name    string   `json:"name"`              // 用户名或 Token 名称
+	Method  string   `json:"method"`            // 认证方式
+	Role    Role     `json:"role"`              // 角色
+	Domains []string `json:"domains,omitempty"` // 可访问的域名范围（管理员不受限制）
+
+	CSRFToken string `json:"-"` // 会话的 CSRF Token（仅会话认证时有值）
+}
+
+type contextKey struct{}
+
+// FromContext 获取请求上下文中的操作者
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok
+}
+
+// WithPrincipal 将操作者写入上下文
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// Authenticator 认证器
+// 为 nil 时表示未启用认证，所有请求直接放行
+type Authenticator struct {
+	credFile string
+	creds    *Credentials
+	sessions *sessionStore
+	mu       sync.RWMutex
+}
+
+// NewAuthenticator 从凭据文件创建认证器
+func NewAuthenticator(credFile string) (*Authenticator, error) {
+	a := &Authenticator{
+		credFile: credFile,
+		sessions: newSessionStore(),
+	}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload 重新加载凭据文件
+// 未知字段视为错误，避免拼写错误的 role、domains 被忽略后授予全部权限
+func (a *Authenticator) Reload() error {
+	data, err := os.ReadFile(a.credFile)
+	if err != nil {
+		return err
+	}
+
+	var creds Credentials
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&creds); err != nil {
+		return fmt.Errorf("%s: %v", a.credFile, err)
+	}
+	for i := range creds.Tokens {
+		t := &creds.Tokens[i]
+		if t.Name == "" || !strings.HasPrefix(t.Hash, tokenHashPrefix) {
+			return fmt.Errorf("%s: Token %q 的哈希必须以 %s 开头", a.credFile, t.Name, tokenHashPrefix)
+		}
+		defaulted, err := normalizeScope(&t.Role, t.Domains)
+		if err != nil {
+			return fmt.Errorf("%s: Token %q: %v", a.credFile, t.Name, err)
+		}
+		if defaulted {
+			log.Printf("Warning: %s: token %q has no role, defaulting to admin\n", a.credFile, t.Name)
+		}
+	}
+	for i := range creds.Users {
+		u := &creds.Users[i]
+		if _, err := bcrypt.Cost([]byte(u.Password)); u.Username == "" || err != nil {
+			return fmt.Errorf("%s: 用户 %q 的密码必须是 bcrypt 哈希", a.credFile, u.Username)
+		}
+		defaulted, err := normalizeScope(&u.Role, u.Domains)
+		if err != nil {
+			return fmt.Errorf("%s: 用户 %q: %v", a.credFile, u.Username, err)
+		}
+		if defaulted {
+			log.Printf("Warning: %s: user %q has no role, defaulting to admin\n", a.credFile, u.Username)
+		}
+	}
+
+	a.mu.Lock()
+	a.creds = &creds
+	a.mu.Unlock()
+	return nil
+}
+
+// Protect 要求请求已认证，未认证时交给 onFail 处理
+func (a *Authenticator) Protect(next http.Handler, onFail http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, attempted := a.authenticate(r)
+		if p == nil {
+			if attempted {
+				log.Printf("Auth failed: ip=%s method=%s path=%s\n", clientIP(r), r.Method, r.URL.Path)
+			}
+			onFail.ServeHTTP(w, r)
+			return
+		}
+		// 会话认证依赖浏览器自动携带的 Cookie，修改类请求必须回传 CSRF Token
+		if p.Method == MethodSession && !isSafeMethod(r.Method) {
+			token := r.Header.Get(CSRFHeader)
+			if subtle.ConstantTimeCompare([]byte(token), []byte(p.CSRFToken)) != 1 {
+				log.Printf("CSRF check failed: ip=%s method=%s path=%s user=%q\n", clientIP(r), r.Method, r.URL.Path, p.Name)
+				Forbidden(w, "CSRF Token 无效")
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+// Unauthorized 返回 401 JSON 响应（用于 API）
+func Unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="MiniJump"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+}
+
+// Forbidden 返回 403 JSON 响应
+func Forbidden(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// Login 校验用户名密码并创建会话，返回会话 ID
+func (a *Authenticator) Login(r *http.Request, username, password string) (string, bool) {
+	if a.checkPassword(username, password) == nil {
+		log.Printf("Auth failed: ip=%s method=login user=%q\n", clientIP(r), username)
+		return "", false
+	}
+	return a.sessions.create(username), true
+}
+
+// SessionUser 返回会话对应的用户名
+func (a *Authenticator) SessionUser(sessionID string) (string, bool) {
+	sess, ok := a.sessions.get(sessionID)
+	return sess.username, ok
+}
+
+// Logout 销毁会话
+func (a *Authenticator) Logout(sessionID string) {
+	a.sessions.delete(sessionID)
+}
+
+// authenticate 依次尝试 Token、Basic、会话认证
+// 返回认证成功的操作者，以及请求是否携带了凭据
+func (a *Authenticator) authenticate(r *http.Request) (*Principal, bool) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return a.checkToken(strings.TrimSpace(token)), true
+		}
+		if username, password, ok := r.BasicAuth(); ok {
+			return a.checkPassword(username, password), true
+		}
+		return nil, true
+	}
+
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		if sess, ok := a.sessions.get(cookie.Value); ok {
+			// 每次请求按最新凭据确定角色，凭据变更后立即生效
+			if p := a.lookupUser(sess.username); p != nil {
+				p.Method = MethodSession
+				p.CSRFToken = sess.csrfToken
+				return p, false
+			}
+		}
+		return nil, true
+	}
+
+	return nil, false
+}
+
+// checkToken 校验 API Token
+func (a *Authenticator) checkToken(token string) *Principal {
+	sum := sha256.Sum256([]byte(token))
+	hash := tokenHashPrefix + hex.EncodeToString(sum[:])
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, t := range a.creds.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) == 1 {
+			return &Principal{Name: t.Name, Method: MethodToken, Role: t.Role, Domains: t.Domains}
+		}
+	}
+	return nil
+}
+
+// lookupUser 按用户名查找用户（不校验密码）
+func (a *Authenticator) lookupUser(username string) *Principal {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, u := range a.creds.Users {
+		if u.Username == username {
+			return &Principal{Name: u.Username, Role: u.Role, Domains: u.Domains}
+		}
+	}
+	return nil
+}
+
+// dummyPasswordHash 用户不存在时用于比较的 bcrypt 哈希，使耗时与用户存在时一致，避免通过响应时间枚举用户名
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("minijump-dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// checkPassword 校验用户名和 bcrypt 密码
+func (a *Authenticator) checkPassword(username, password string) *Principal {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, u := range a.creds.Users {
+		if u.Username == username {
+			if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil {
+				return &Principal{Name: username, Method: MethodBasic, Role: u.Role, Domains: u.Domains}
+			}
+			return nil
+		}
+	}
+	bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+	return nil
+}
+
+// HashToken 计算 API Token 的哈希（用于写入凭据文件）
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return tokenHashPrefix + hex.EncodeToString(sum[:])
+}
+
+// HashPassword 计算密码的 bcrypt 哈希（用于写入凭据文件）
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// clientIP 获取请求来源 IP（用于失败日志）
+func clientIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if idx := strings.LastIndex(ip, ":"); idx != -1 {
+		ip = ip[:idx]
+	}
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return ip + " (X-Forwarded-For: " + forwarded + ")"
+	}
+	return ip
+}
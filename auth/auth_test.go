@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPassword 测试用户的密码
+const testPassword = "test-password"
+
+// writeCredentials 将凭据文件内容写入临时目录，返回文件路径
+func writeCredentials(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// passwordHash 返回 testPassword 的 bcrypt 哈希
+func passwordHash(t *testing.T) string {
+	t.Helper()
+	hash, err := HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// captureLog 在 fn 执行期间捕获服务日志
+func captureLog(fn func()) string {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	fn()
+	return buf.String()
+}
+
+func TestReloadRejectsInvalidCredentials(t *testing.T) {
+	hash := passwordHash(t)
+	for _, tt := range []struct {
+		name    string
+		content string
+		want    string // 错误信息应包含的内容
+	}{
+		{"unknown field", `{"users":[{"username":"a","password":"` + hash + `","role":"editor","domain":["a.com"]}]}`, `unknown field "domain"`},
+		{"unknown role", `{"tokens":[{"name":"ci","hash":"sha256:00","role":"owner","domains":["*"]}]}`, `未知的角色 "owner"`},
+		{"editor without domains", `{"tokens":[{"name":"ci","hash":"sha256:00","role":"editor"}]}`, "必须配置 domains"},
+		{"viewer without domains", `{"users":[{"username":"a","password":"` + hash + `","role":"viewer","domains":[]}]}`, "必须配置 domains"},
+		{"invalid domain pattern", `{"tokens":[{"name":"ci","hash":"sha256:00","role":"editor","domains":["a.*.com"]}]}`, "无效的域名模式"},
+		{"plain token", `{"tokens":[{"name":"ci","hash":"secret","role":"admin"}]}`, "sha256:"},
+		{"plain password", `{"users":[{"username":"a","password":"secret","role":"admin"}]}`, "bcrypt"},
+	} {
+		_, err := NewAuthenticator(writeCredentials(t, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestReloadDefaultRoleWarns(t *testing.T) {
+	var a *Authenticator
+	var err error
+	out := captureLog(func() {
+		a, err = NewAuthenticator(writeCredentials(t, `{"tokens":[{"name":"legacy","hash":"`+HashToken("legacy-token")+`"}]}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `token "legacy" has no role, defaulting to admin`) {
+		t.Errorf("log = %q, want a warning about the default role", out)
+	}
+	if p := a.checkToken("legacy-token"); p == nil || !p.IsAdmin() {
+		t.Errorf("principal = %+v, want admin", p)
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	a, err := NewAuthenticator(writeCredentials(t,
+		`{"users":[{"username":"team-a","password":"`+passwordHash(t)+`","role":"editor","domains":["a.com"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := a.checkPassword("team-a", testPassword)
+	if p == nil || p.Name != "team-a" || p.Method != MethodBasic || p.Role != RoleEditor {
+		t.Fatalf("principal = %+v", p)
+	}
+	if a.checkPassword("team-a", "wrong") != nil {
+		t.Error("wrong password accepted")
+	}
+	if a.checkPassword("nobody", testPassword) != nil {
+		t.Error("unknown user accepted")
+	}
+}
+
+func TestPrincipalScope(t *testing.T) {
+	editor := &Principal{Role: RoleEditor, Domains: []string{"a.com", "*.b.com"}}
+	viewer := &Principal{Role: RoleViewer, Domains: []string{"*"}}
+	admin := &Principal{Role: RoleAdmin}
+	for _, tt := range []struct {
+		p           *Principal
+		domain      string
+		read, write bool
+		desc        string
+	}{
+		{editor, "a.com", true, true, "editor in scope"},
+		{editor, "A.com:8080", true, true, "editor ignores case and port"},
+		{editor, "x.b.com", true, true, "editor subdomain"},
+		{editor, "b.com", false, false, "subdomain pattern excludes the parent"},
+		{editor, "c.com", false, false, "editor out of scope"},
+		{viewer, "c.com", true, false, "viewer reads everything with *"},
+		{admin, "c.com", true, true, "admin is not scoped"},
+		{nil, "c.com", true, true, "auth disabled"},
+		{&Principal{Role: RoleEditor}, "c.com", false, false, "empty domains grant nothing"},
+	} {
+		if got := tt.p.CanRead(tt.domain); got != tt.read {
+			t.Errorf("%s: CanRead(%q) = %v", tt.desc, tt.domain, got)
+		}
+		if got := tt.p.CanWrite(tt.domain); got != tt.write {
+			t.Errorf("%s: CanWrite(%q) = %v", tt.desc, tt.domain, got)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// okHandler 请求通过检查时返回 200
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestSameOrigin(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := SameOrigin(proxies)(okHandler)
+	for _, tt := range []struct {
+		name    string
+		method  string
+		remote  string
+		headers map[string]string
+		status  int
+	}{
+		{"non-browser client", "POST", "192.0.2.1:1234", nil, 200},
+		{"same origin", "POST", "192.0.2.1:1234", map[string]string{"Origin": "http://admin.example.com"}, 200},
+		{"same origin, case and fetch site", "DELETE", "192.0.2.1:1234", map[string]string{"Origin": "http://ADMIN.example.com", "Sec-Fetch-Site": "same-origin"}, 200},
+		{"cross-origin", "POST", "192.0.2.1:1234", map[string]string{"Origin": "https://evil.example"}, 403},
+		{"null origin", "PUT", "192.0.2.1:1234", map[string]string{"Origin": "null"}, 403},
+		{"cross-site fetch", "POST", "192.0.2.1:1234", map[string]string{"Sec-Fetch-Site": "cross-site"}, 403},
+		{"same-site fetch", "POST", "192.0.2.1:1234", map[string]string{"Sec-Fetch-Site": "same-site"}, 403},
+		{"safe method", "GET", "192.0.2.1:1234", map[string]string{"Origin": "https://evil.example", "Sec-Fetch-Site": "cross-site"}, 200},
+		// 客户端伪造 X-Forwarded-Host 使其与 Origin 一致
+		{"forged forwarded host", "POST", "192.0.2.1:1234", map[string]string{"Origin": "https://evil.example", "X-Forwarded-Host": "evil.example"}, 403},
+		{"forwarded host from trusted proxy", "POST", "10.1.2.3:1234", map[string]string{"Origin": "https://public.example.com", "X-Forwarded-Host": "public.example.com, 10.1.2.3"}, 200},
+		{"forwarded host from trusted IPv6 proxy", "POST", "[::1]:1234", map[string]string{"Origin": "https://public.example.com", "X-Forwarded-Host": "public.example.com"}, 200},
+		{"trusted proxy, cross-origin", "POST", "10.1.2.3:1234", map[string]string{"Origin": "http://admin.example.com", "X-Forwarded-Host": "public.example.com"}, 403},
+	} {
+		r := httptest.NewRequest(tt.method, "http://admin.example.com/api/rules", nil)
+		r.RemoteAddr = tt.remote
+		for name, value := range tt.headers {
+			r.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"192.0.2.1", " 10.0.0.0/8 ", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, want := range map[string]bool{
+		"192.0.2.1:80":         true,
+		"192.0.2.2:80":         false,
+		"10.255.0.1:80":        true,
+		"[::ffff:10.0.0.1]:80": true,
+		"11.0.0.1:80":          false,
+		"not-an-ip":            false,
+	} {
+		if got := proxies.Contains(addr); got != want {
+			t.Errorf("Contains(%s) = %v, want %v", addr, got, want)
+		}
+	}
+	if (TrustedProxies)(nil).Contains("127.0.0.1:80") {
+		t.Error("empty list trusts loopback")
+	}
+	for _, bad := range []string{"10.0.0.0/33", "proxy.local"} {
+		if _, err := ParseTrustedProxies([]string{bad}); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestSessionRequiresCSRFToken(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	a, err := NewAuthenticator(writeCredentials(t,
+		`{"users":[{"username":"admin","password":"`+passwordHash(t)+`","role":"admin"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID, ok := a.Login(httptest.NewRequest("POST", "/api/login", nil), "admin", testPassword)
+	if !ok {
+		t.Fatal("login failed")
+	}
+	sess, _ := a.sessions.get(sessionID)
+	handler := a.Protect(okHandler, http.HandlerFunc(Unauthorized))
+
+	for _, tt := range []struct {
+		name   string
+		method string
+		token  string
+		status int
+	}{
+		{"read without token", "GET", "", 200},
+		{"write without token", "POST", "", 403},
+		{"write with wrong token", "DELETE", "wrong", 403},
+		{"write with token", "POST", sess.csrfToken, 200},
+	} {
+		r := httptest.NewRequest(tt.method, "/api/rules", nil)
+		r.AddCookie(&http.Cookie{Name: SessionCookieName, Value: sessionID})
+		if tt.token != "" {
+			r.Header.Set(CSRFHeader, tt.token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+
+	// Basic 认证不依赖 Cookie，无需 CSRF Token
+	r := httptest.NewRequest("POST", "/api/rules", nil)
+	r.SetBasicAuth("admin", testPassword)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != 200 {
+		t.Errorf("basic auth write: status %d, want 200", rec.Code)
+	}
+}
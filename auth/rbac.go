@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Role 角色
+type Role string
+
+const (
+	RoleViewer Role = "viewer" // 只读
+	RoleEditor Role = "editor" // 可修改授权域名范围内的规则
+	RoleAdmin  Role = "admin"  // 全部权限（含重新加载、保存配置）
+)
+
+// rank 角色权限等级
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// normalizeScope 校验角色和域名范围
+// 角色为空时默认为 admin（兼容未配置角色的凭据文件），返回 defaulted 为 true 以便提示；
+// viewer 和 editor 必须配置域名范围，全部域名使用 "*"，避免拼写错误导致不受限制
+func normalizeScope(role *Role, domains []string) (defaulted bool, err error) {
+	if *role == "" {
+		*role = RoleAdmin
+		defaulted = true
+	}
+	if role.rank() == 0 {
+		return false, fmt.Errorf("未知的角色 %q（可选 viewer/editor/admin）", *role)
+	}
+	if *role != RoleAdmin && len(domains) == 0 {
+		return false, fmt.Errorf("角色 %s 必须配置 domains（全部域名使用 [\"*\"]）", *role)
+	}
+	for _, pattern := range domains {
+		if pattern == "*" {
+			continue
+		}
+		if rest := strings.TrimPrefix(pattern, "*."); rest == "" || strings.Contains(rest, "*") {
+			return false, fmt.Errorf("无效的域名模式 %q（支持 example.com、*.example.com 或 *）", pattern)
+		}
+	}
+	return defaulted, nil
+}
+
+// IsAdmin 是否为管理员（未启用认证时 p 为 nil，视为管理员）
+func (p *Principal) IsAdmin() bool {
+	return p == nil || p.Role == RoleAdmin
+}
+
+// CanRead 是否可查看指定域名的规则
+func (p *Principal) CanRead(domain string) bool {
+	if p == nil {
+		return true
+	}
+	return p.Role.rank() >= RoleViewer.rank() && p.inScope(domain)
+}
+
+// CanWrite 是否可修改指定域名的规则
+func (p *Principal) CanWrite(domain string) bool {
+	if p == nil {
+		return true
+	}
+	return p.Role.rank() >= RoleEditor.rank() && p.inScope(domain)
+}
+
+// inScope 域名是否在授权范围内（管理员不受域名范围限制）
+func (p *Principal) inScope(domain string) bool {
+	if p.Role == RoleAdmin {
+		return true
+	}
+	domain = strings.ToLower(domain)
+	for _, pattern := range p.Domains {
+		if MatchDomain(strings.ToLower(pattern), domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchDomain 域名模式匹配
+// "*" 匹配所有域名，"*.example.com" 匹配 example.com 的所有子域名（不含自身），其余为精确匹配
+// 域名带端口时按主机名部分匹配
+func MatchDomain(pattern, domain string) bool {
+	if idx := strings.LastIndex(domain, ":"); idx != -1 && !strings.Contains(pattern, ":") {
+		domain = domain[:idx]
+	}
+	if pattern == "*" {
+		return true
+	}
+	if parent, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(domain, "."+parent)
+	}
+	return pattern == domain
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// SessionCookieName 会话 Cookie 名称
+const SessionCookieName = "minijump_session"
+
+// SessionTTL 会话有效期
+const SessionTTL = 12 * time.Hour
+
+// session 登录会话
+type session struct {
+	username  string
+	csrfToken string // CSRF Token，修改类请求需通过 X-CSRF-Token 请求头回传
+	expiresAt time.Time
+}
+
+// sessionStore 内存会话存储（重启后需重新登录）
+type sessionStore struct {
+	sessions map[string]*session
+	mu       sync.Mutex
+}
+
+// newSessionStore 创建会话存储
+func newSessionStore() *sessionStore {
+	return &sessionStore{
+		sessions: make(map[string]*session),
+	}
+}
+
+// create 创建会话，返回会话 ID
+func (s *sessionStore) create(username string) string {
+	id := randomHex(32)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleanupUnlocked()
+	s.sessions[id] = &session{
+		username:  username,
+		csrfToken: randomHex(32),
+		expiresAt: time.Now().Add(SessionTTL),
+	}
+	return id
+}
+
+// get 获取未过期的会话
+func (s *sessionStore) get(id string) (session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return session{}, false
+	}
+	if time.Now().After(sess.expiresAt) {
+		delete(s.sessions, id)
+		return session{}, false
+	}
+	return *sess, true
+}
+
+// delete 删除会话
+func (s *sessionStore) delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// cleanupUnlocked 清理过期会话（需要在锁内调用）
+func (s *sessionStore) cleanupUnlocked() {
+	now := time.Now()
+	for id, sess := range s.sessions {
+		if now.After(sess.expiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// randomHex 生成指定字节数的随机十六进制串
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// GenerateToken 生成随机 API Token
+func GenerateToken() string {
+	return randomHex(32)
+}
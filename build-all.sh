@@ -13,11 +13,19 @@ echo ""
 BUILD_DIR="build"
 mkdir -p ${BUILD_DIR}
 
+# 构建信息（通过 -ldflags 注入，可通过 /version 和 minijump version 查看）
+VERSION=${VERSION:-$(git describe --tags --always --dirty 2>/dev/null || date +"%Y%m%d-%H%M%S")}
+COMMIT=$(git rev-parse HEAD 2>/dev/null || true)
+BUILD_TIME=$(date -u +"%Y-%m-%dT%H:%M:%SZ")
+LDFLAGS="-s -w -X mini_jump/health.Version=${VERSION} -X mini_jump/health.Commit=${COMMIT} -X mini_jump/health.BuildTime=${BUILD_TIME}"
+echo "版本: ${VERSION}"
+echo ""
+
 # Linux x86_64
 echo "[1/3] 构建 Linux x86_64..."
 export GOOS=linux
 export GOARCH=amd64
-go build -ldflags "-s -w" -o ${BUILD_DIR}/minijump-linux-x86_64 main.go
+go build -ldflags "${LDFLAGS}" -o ${BUILD_DIR}/minijump-linux-x86_64 main.go
 echo "✓ Linux x86_64 构建完成"
 echo ""
 
@@ -25,7 +33,7 @@ echo ""
 echo "[2/3] 构建 Linux x86 (32位)..."
 export GOOS=linux
 export GOARCH=386
-go build -ldflags "-s -w" -o ${BUILD_DIR}/minijump-linux-x86 main.go
+go build -ldflags "${LDFLAGS}" -o ${BUILD_DIR}/minijump-linux-x86 main.go
 echo "✓ Linux x86 (32位) 构建完成"
 echo ""
 
@@ -33,7 +41,7 @@ echo ""
 echo "[3/3] 构建 Windows x86_64..."
 export GOOS=windows
 export GOARCH=amd64
-go build -ldflags "-s -w" -o ${BUILD_DIR}/minijump-windows-x86_64.exe main.go
+go build -ldflags "${LDFLAGS}" -o ${BUILD_DIR}/minijump-windows-x86_64.exe main.go
 echo "✓ Windows x86_64 构建完成"
 echo ""
 
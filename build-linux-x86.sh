@@ -13,7 +13,10 @@ export GOARCH=amd64
 
 # 构建参数
 APP_NAME="minijump"
-VERSION=$(date +"%Y%m%d-%H%M%S")
+VERSION=${VERSION:-$(git describe --tags --always --dirty 2>/dev/null || date +"%Y%m%d-%H%M%S")}
+COMMIT=$(git rev-parse HEAD 2>/dev/null || true)
+BUILD_TIME=$(date -u +"%Y-%m-%dT%H:%M:%SZ")
+LDFLAGS="-s -w -X mini_jump/health.Version=${VERSION} -X mini_jump/health.Commit=${COMMIT} -X mini_jump/health.BuildTime=${BUILD_TIME}"
 BUILD_DIR="build"
 OUTPUT_FILE="${BUILD_DIR}/minijump-linux-x86_64"
 
@@ -22,12 +25,13 @@ mkdir -p ${BUILD_DIR}
 
 # 构建
 echo "正在编译..."
-go build -ldflags "-s -w" -o ${OUTPUT_FILE} main.go
+go build -ldflags "${LDFLAGS}" -o ${OUTPUT_FILE} main.go
 
 # 检查构建结果
 if [ -f ${OUTPUT_FILE} ]; then
     echo "✓ 构建成功！"
     echo "  输出文件: ${OUTPUT_FILE}"
+    echo "  版本: ${VERSION}"
     
     # 显示文件信息
     file ${OUTPUT_FILE}
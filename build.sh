@@ -1 +1,7 @@
- go build -ldflags "-s -w" -o minijump main.go
\ No newline at end of file
+#!/bin/bash
+# 构建信息（通过 -ldflags 注入，可通过 /version 和 minijump version 查看）
+VERSION=${VERSION:-$(git describe --tags --always --dirty 2>/dev/null || date +"%Y%m%d-%H%M%S")}
+COMMIT=$(git rev-parse HEAD 2>/dev/null || true)
+BUILD_TIME=$(date -u +"%Y-%m-%dT%H:%M:%SZ")
+LDFLAGS="-s -w -X mini_jump/health.Version=${VERSION} -X mini_jump/health.Commit=${COMMIT} -X mini_jump/health.BuildTime=${BUILD_TIME}"
+go build -ldflags "${LDFLAGS}" -o minijump main.go
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// maxChainSteps 解析跳转链的最大步数，防止异常数据导致无限循环
+const maxChainSteps = 100
+
+// RedirectChain 跳转链（规则目标命中本地其他规则形成的多跳跳转）
+type RedirectChain struct {
+	Rules       []*RedirectRule `json:"rules"`        // 依次经过的规则
+	Length      int             `json:"length"`       // 跳数
+	Cyclic      bool            `json:"cyclic"`       // 是否形成循环
+	Exceeds     bool            `json:"exceeds"`      // 是否超过最大链长
+	FinalTarget string          `json:"final_target"` // 最终跳转目标（循环时为空）
+}
+
+// hiddenHop 描述中代替不可查看的规则
+const hiddenHop = "…"
+
+// Describe 返回链路描述，如 a.com/x → b.com/y → https://c.com
+func (ch *RedirectChain) Describe() string {
+	return ch.DescribeVisible(nil)
+}
+
+// DescribeVisible 返回链路描述，visible 返回 false 的规则以 … 代替（连续多条合并为一个，为 nil 时不隐藏）
+func (ch *RedirectChain) DescribeVisible(visible func(rule *RedirectRule) bool) string {
+	parts := make([]string, 0, len(ch.Rules)+1)
+	add := func(rule *RedirectRule) {
+		switch {
+		case visible == nil || visible(rule):
+			parts = append(parts, rule.Domain+rule.Path)
+		case len(parts) == 0 || parts[len(parts)-1] != hiddenHop:
+			parts = append(parts, hiddenHop)
+		}
+	}
+	for _, rule := range ch.Rules {
+		add(rule)
+	}
+	if ch.Cyclic && len(ch.Rules) > 0 {
+		// 循环时补上回到的规则
+		last := ch.Rules[len(ch.Rules)-1]
+		if domain, path, ok := targetLocation(last.Target); ok {
+			add(&RedirectRule{Domain: domain, Path: path})
+		}
+	} else if ch.FinalTarget != "" {
+		parts = append(parts, ch.FinalTarget)
+	}
+	return strings.Join(parts, " → ")
+}
+
+// ruleLookup 按请求域名和路径查找规则
+type ruleLookup func(domain, path string) (*RedirectRule, bool)
+
+// TraceChain 从规则出发解析跳转链
+// rule 视为已保存（替换 ID 为 excludeID 的规则），用于保存前检查
+func (c *Config) TraceChain(rule *RedirectRule, excludeID string) RedirectChain {
+	ruleKey := c.generateKey(rule.Domain, rule.Path)
+	lookup := func(domain, path string) (*RedirectRule, bool) {
+		for _, key := range c.matchKeys(domain, path) {
+			if key == ruleKey {
+				return rule, true
+			}
+			if r, ok := c.GetRule(key); ok && r.ID != excludeID && r.ID != rule.ID {
+				return r, true
+			}
+		}
+		return nil, false
+	}
+	return c.traceChain(rule, lookup)
+}
+
+// AnalyzeChains 分析当前所有规则，返回所有多跳跳转链（含循环）
+func (c *Config) AnalyzeChains() []RedirectChain {
+	rules := c.GetAllRules()
+	sortRules(rules)
+
+	// 计算每条规则的下一跳
+	next := make(map[string]*RedirectRule, len(rules))
+	targeted := make(map[string]bool)
+	for _, rule := range rules {
+		domain, path, ok := targetLocation(rule.Target)
+		if !ok {
+			continue
+		}
+		if n, found := c.FindRule(domain, path); found {
+			next[rule.ID] = n
+			targeted[n.ID] = true
+		}
+	}
+
+	var chains []RedirectChain
+	covered := make(map[string]bool)
+	collect := func(head *RedirectRule) {
+		chain := c.traceChain(head, c.FindRule)
+		for _, r := range chain.Rules {
+			covered[r.ID] = true
+		}
+		chains = append(chains, chain)
+	}
+
+	// 从链头（不被其他规则指向的规则）开始解析
+	for _, rule := range rules {
+		if next[rule.ID] != nil && !targeted[rule.ID] {
+			collect(rule)
+		}
+	}
+	// 剩余未覆盖的规则只可能处于没有链头的纯循环中
+	for _, rule := range rules {
+		if next[rule.ID] != nil && !covered[rule.ID] {
+			collect(rule)
+		}
+	}
+
+	sort.SliceStable(chains, func(i, j int) bool {
+		return chains[i].Length > chains[j].Length
+	})
+	return chains
+}
+
+// ChainsThrough 返回经过指定规则的多跳跳转链（含循环），用于变更规则后的检查
+// 只解析与这些规则相连的规则，不分析全部跳转链
+func (c *Config) ChainsThrough(ids ...string) []RedirectChain {
+	rules := c.GetAllRules()
+	sortRules(rules)
+
+	// 按目标主机索引规则，查找上一跳时只需检查指向同一主机的规则
+	byHost := make(map[string][]*RedirectRule)
+	for _, rule := range rules {
+		if domain, _, ok := targetLocation(rule.Target); ok {
+			byHost[domain] = append(byHost[domain], rule)
+		}
+	}
+	previous := func(rule *RedirectRule) []*RedirectRule {
+		var prev []*RedirectRule
+		for _, r := range byHost[rule.Domain] {
+			domain, path, _ := targetLocation(r.Target)
+			if n, ok := c.FindRule(domain, path); ok && n.ID == rule.ID && r.ID != rule.ID {
+				prev = append(prev, r)
+			}
+		}
+		return prev
+	}
+
+	var chains []RedirectChain
+	covered := make(map[string]bool)
+	for _, id := range ids {
+		rule, ok := c.GetRuleByID(id)
+		if !ok {
+			continue
+		}
+		// 向上游查找链头（不被其他规则指向的规则），纯循环没有链头时从规则本身开始
+		var heads []*RedirectRule
+		visited := map[string]bool{rule.ID: true}
+		queue := []*RedirectRule{rule}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			prev := previous(current)
+			if len(prev) == 0 {
+				heads = append(heads, current)
+			}
+			for _, p := range prev {
+				if !visited[p.ID] {
+					visited[p.ID] = true
+					queue = append(queue, p)
+				}
+			}
+		}
+		if len(heads) == 0 {
+			heads = append(heads, rule)
+		}
+
+		for _, head := range heads {
+			if covered[head.ID] {
+				continue
+			}
+			chain := c.traceChain(head, c.FindRule)
+			for _, r := range chain.Rules {
+				covered[r.ID] = true
+			}
+			if chain.Length > 1 || chain.Cyclic {
+				chains = append(chains, chain)
+			}
+		}
+	}
+
+	sort.SliceStable(chains, func(i, j int) bool {
+		return chains[i].Length > chains[j].Length
+	})
+	return chains
+}
+
+// traceChain 沿规则目标逐跳查找本地规则
+func (c *Config) traceChain(start *RedirectRule, lookup ruleLookup) RedirectChain {
+	chain := RedirectChain{Rules: []*RedirectRule{start}}
+	visited := map[string]bool{start.ID: true}
+
+	current := start
+	for step := 0; step < maxChainSteps; step++ {
+		domain, path, ok := targetLocation(current.Target)
+		if !ok {
+			break
+		}
+		n, found := lookup(domain, path)
+		if !found {
+			break
+		}
+		if visited[n.ID] {
+			chain.Cyclic = true
+			break
+		}
+		visited[n.ID] = true
+		chain.Rules = append(chain.Rules, n)
+		current = n
+	}
+
+	chain.Length = len(chain.Rules)
+	if !chain.Cyclic {
+		chain.FinalTarget = current.Target
+	}
+	chain.Exceeds = c.MaxChainLength > 0 && chain.Length > c.MaxChainLength
+	return chain
+}
+
+// matchKeys 返回请求匹配时依次尝试的规则键（先精确路径，再域名）
+func (c *Config) matchKeys(domain, path string) []string {
+	if path == "" {
+		return []string{c.generateKey(domain, "")}
+	}
+	return []string{c.generateKey(domain, path), c.generateKey(domain, "")}
+}
+
+// targetLocation 解析目标URL对应的请求域名和路径
+func targetLocation(target string) (string, string, bool) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return "", "", false
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	return strings.ToLower(u.Host), path, true
+}
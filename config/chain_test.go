@@ -0,0 +1,131 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// hop 创建从 domain+path 跳转到 target 的规则
+func hop(id, domain, path, target string) *RedirectRule {
+	return &RedirectRule{ID: id, Domain: domain, Path: path, Target: target, Type: RedirectType302}
+}
+
+// chainIDs 返回跳转链依次经过的规则 ID
+func chainIDs(chains []RedirectChain) [][]string {
+	var out [][]string
+	for _, chain := range chains {
+		out = append(out, ids(chain.Rules))
+	}
+	return out
+}
+
+// chainRules 构造跳转链测试用的规则集：
+//
+//	a → b → c → https://final.net/（a.com 的域名规则 d 也指向 b）
+//	x → y → x（循环）
+//	z 指向外部地址，不形成多跳
+func chainRules() []*RedirectRule {
+	return []*RedirectRule{
+		hop("a", "a.com", "/a", "https://b.com/b"),
+		hop("b", "b.com", "/b", "https://c.com/c"),
+		hop("c", "c.com", "/c", "https://final.net/"),
+		hop("d", "a.com", "", "https://b.com/b"),
+		hop("x", "x.com", "/x", "https://y.com/"),
+		hop("y", "y.com", "", "https://x.com/x"),
+		hop("z", "z.com", "/z", "https://final.net/z"),
+	}
+}
+
+func TestTraceChain(t *testing.T) {
+	c := newTestConfig(chainRules()...)
+	c.MaxChainLength = 2
+
+	chain := c.TraceChain(mustRule(t, c, "a"), "")
+	if got := ids(chain.Rules); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Fatalf("chain = %v", got)
+	}
+	if chain.Length != 3 || chain.Cyclic || !chain.Exceeds || chain.FinalTarget != "https://final.net/" {
+		t.Errorf("chain = %+v", chain)
+	}
+	if got := chain.Describe(); got != "a.com/a → b.com/b → c.com/c → https://final.net/" {
+		t.Errorf("Describe = %q", got)
+	}
+
+	// 域名规则匹配任意路径
+	if got := ids(c.TraceChain(hop("new", "n.com", "/n", "https://a.com/other"), "").Rules); !reflect.DeepEqual(got, []string{"new", "d", "b", "c"}) {
+		t.Errorf("chain through domain rule = %v", got)
+	}
+
+	cycle := c.TraceChain(mustRule(t, c, "x"), "")
+	if !cycle.Cyclic || cycle.Length != 2 || cycle.FinalTarget != "" {
+		t.Errorf("cycle = %+v", cycle)
+	}
+	if got := cycle.Describe(); got != "x.com/x → y.com → x.com/x" {
+		t.Errorf("Describe = %q", got)
+	}
+
+	// 待保存的规则替换 c 后回到 a，形成循环
+	closing := hop("c", "c.com", "/c", "https://a.com/a")
+	if chain := c.TraceChain(closing, "c"); !chain.Cyclic || chain.Length != 3 {
+		t.Errorf("closing the loop: %v cyclic=%v", ids(chain.Rules), chain.Cyclic)
+	}
+
+	// excludeID 的规则视为已被替换，不再作为下一跳
+	if chain := c.TraceChain(hop("new", "n.com", "/n", "https://b.com/b"), "b"); chain.Length != 1 {
+		t.Errorf("excluded rule b still followed: %v", ids(chain.Rules))
+	}
+	// 修改 ID 后原位置由新规则占据
+	renamed := hop("b2", "b.com", "/b", "https://final.net/b")
+	if chain := c.TraceChain(renamed, "b"); chain.Length != 1 || chain.FinalTarget != "https://final.net/b" {
+		t.Errorf("chain from renamed b = %v", ids(chain.Rules))
+	}
+}
+
+func TestAnalyzeChains(t *testing.T) {
+	c := newTestConfig(chainRules()...)
+	c.MaxChainLength = 2
+	chains := c.AnalyzeChains()
+	want := [][]string{{"a", "b", "c"}, {"d", "b", "c"}, {"x", "y"}}
+	if got := chainIDs(chains); !reflect.DeepEqual(got, want) {
+		t.Fatalf("chains = %v, want %v", got, want)
+	}
+	if !chains[0].Exceeds || !chains[1].Exceeds || chains[2].Exceeds || !chains[2].Cyclic {
+		t.Errorf("chains = %+v", chains)
+	}
+}
+
+func TestChainsThrough(t *testing.T) {
+	c := newTestConfig(chainRules()...)
+	for _, tt := range []struct {
+		ids  []string
+		want [][]string
+	}{
+		{[]string{"c"}, [][]string{{"a", "b", "c"}, {"d", "b", "c"}}},
+		{[]string{"b"}, [][]string{{"a", "b", "c"}, {"d", "b", "c"}}},
+		{[]string{"a"}, [][]string{{"a", "b", "c"}}},
+		{[]string{"y"}, [][]string{{"y", "x"}}},
+		{[]string{"z"}, nil},
+		{[]string{"missing"}, nil},
+		{[]string{"a", "d", "x"}, [][]string{{"a", "b", "c"}, {"d", "b", "c"}, {"x", "y"}}},
+	} {
+		if got := chainIDs(c.ChainsThrough(tt.ids...)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ChainsThrough(%v) = %v, want %v", tt.ids, got, tt.want)
+		}
+	}
+
+	// 上游分支在中间汇合时也能找到所有链头
+	c.SetRule(hop("e", "e.com", "/e", "https://a.com/a"))
+	if got := chainIDs(c.ChainsThrough("a", "c")); !reflect.DeepEqual(got, [][]string{{"e", "a", "b", "c"}, {"d", "b", "c"}}) {
+		t.Errorf("ChainsThrough(a, c) = %v", got)
+	}
+}
+
+// mustRule 按 ID 获取规则，不存在时终止测试
+func mustRule(t *testing.T, c *Config, id string) *RedirectRule {
+	t.Helper()
+	rule, ok := c.GetRuleByID(id)
+	if !ok {
+		t.Fatalf("rule %s not found", id)
+	}
+	return rule
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
-	"encoding/json"
-	"os"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -27,6 +29,21 @@ type RedirectRule struct {
 	ExpiresAt   *time.Time   `json:"expires_at"`   // 过期时间（nil表示永不过期）
 	CreatedAt   time.Time    `json:"created_at"`   // 创建时间
 	Description string       `json:"description"`  // 描述
+	Revision    int64        `json:"revision"`     // 修订号（每次修改递增，用作 ETag）
+}
+
+// StatusCode 返回跳转类型对应的 HTTP 状态码
+func (t RedirectType) StatusCode() int {
+	switch t {
+	case RedirectType301:
+		return http.StatusMovedPermanently
+	case RedirectType307:
+		return http.StatusTemporaryRedirect
+	case RedirectTypeJS:
+		return http.StatusOK
+	default:
+		return http.StatusFound
+	}
 }
 
 // IsExpired 检查规则是否已过期
@@ -44,8 +61,41 @@ type Config struct {
 	ConfigFile     string `json:"config_file"`      // 配置文件路径
 	LogBufferSize  int    `json:"log_buffer_size"`  // 日志缓冲大小
 	LogFlushInterval int  `json:"log_flush_interval"` // 日志刷新间隔（秒）
-	rules          *sync.Map
+	LogOverflow    string `json:"log_overflow"`     // 日志队列已满时的处理策略（block/drop-newest/drop-oldest）
+	LogFsync       string `json:"log_fsync"`        // 日志同步到磁盘的策略（none/flush/interval）
+	LogFsyncInterval int  `json:"log_fsync_interval"` // 按间隔同步时的同步间隔（秒）
+	LogSpillFile   string `json:"log_spill_file"`   // 日志文件写入失败时的溢出文件路径（为空时超出内存上限的日志丢弃）
+	LogSinks       []string `json:"log_sinks"`    // 访问日志的其他输出目标（见 logger.OpenSink）
+	LogMaxSize     int    `json:"log_max_size"`     // 访问日志和审计日志超过该大小（MB）时轮转（0 表示不按大小轮转）
+	LogRotateDaily bool   `json:"log_rotate_daily"` // 访问日志和审计日志每天轮转
+	LogMaxBackups  int    `json:"log_max_backups"`  // 最多保留的轮转文件数（0 表示不限制）
+	LogMaxAge      int    `json:"log_max_age"`      // 轮转文件保留天数（0 表示不限制）
+	LogCompress    bool   `json:"log_compress"`     // 轮转后 gzip 压缩
+	StoreType      string `json:"store_type"`       // 规则存储类型（json/bolt）
+	MaxChainLength int    `json:"max_chain_length"` // 跳转链最大长度（超过时告警，0 表示不限制）
+	AuditLogFile   string `json:"audit_log_file"`   // 审计日志文件路径（为空时不记录）
+	WebhookDB      string `json:"webhook_db"`       // Webhook 存储文件路径（为空时不启用）
+	HitsFile       string `json:"hits_file"`        // 规则命中统计文件路径（为空时不统计）
+	HitsFlushInterval int `json:"hits_flush_interval"` // 命中统计保存间隔（秒）
+	AnalyticsDir   string `json:"analytics_dir"`    // 访问统计目录（为空时不统计）
+	AnalyticsFlushInterval int `json:"analytics_flush_interval"` // 访问统计保存间隔（秒）
+	AnalyticsRetention int `json:"analytics_retention"` // 访问统计保留天数（0 表示永久保留）
+	CountryHeader  string `json:"country_header"`   // 携带客户端国家/地区代码的请求头（为空时不记录）
+	TrustedProxies []string `json:"trusted_proxies"` // 可信反向代理地址（IP 或 CIDR，为空时不采用 X-Forwarded-Host）
+	rules          *sync.Map // 规则键 → 规则
+	byID           *sync.Map // 规则 ID → 规则，与 rules 同步维护
+	store          RuleStore
+	onExpire       func(rule *RedirectRule)
 	mu             sync.RWMutex
+	statusMu       sync.Mutex
+	status         LoadStatus
+}
+
+// LoadStatus 规则加载状态，用于就绪检查
+type LoadStatus struct {
+	Loading  bool       // 正在加载
+	LoadedAt *time.Time // 最近一次成功加载的时间（从未成功加载时为 nil）
+	Err      error      // 最近一次加载失败的错误（加载成功或只跳过了无效规则时为 nil）
 }
 
 var defaultConfig = &Config{
@@ -54,7 +104,20 @@ var defaultConfig = &Config{
 	ConfigFile:      "rules.json",
 	LogBufferSize:   1000,
 	LogFlushInterval: 180,
+	LogOverflow:     "block",
+	LogFsync:        "none",
+	LogFsyncInterval: 1,
+	LogCompress:     true,
+	StoreType:       StoreJSON,
+	MaxChainLength:  3,
+	AuditLogFile:    "audit.log",
+	HitsFile:        "hits.json",
+	HitsFlushInterval: 60,
+	AnalyticsDir:    "analytics",
+	AnalyticsFlushInterval: 60,
+	AnalyticsRetention: 90,
 	rules:           &sync.Map{},
+	byID:            &sync.Map{},
 }
 
 // GetDefaultConfig 获取默认配置
@@ -70,22 +133,71 @@ func (c *Config) GetRule(key string) (*RedirectRule, bool) {
 	}
 	rule := value.(*RedirectRule)
 	if rule.IsExpired() {
-		c.rules.Delete(key)
+		c.expire(key, rule)
 		return nil, false
 	}
 	return rule, true
 }
 
+// OnExpire 设置规则过期回调，每条过期规则从内存移除时调用一次
+func (c *Config) OnExpire(fn func(rule *RedirectRule)) {
+	c.onExpire = fn
+}
+
+// SweepExpired 移除内存中所有已过期的规则（触发过期回调），返回移除数量
+func (c *Config) SweepExpired() int {
+	count := 0
+	c.rules.Range(func(key, value interface{}) bool {
+		rule := value.(*RedirectRule)
+		if rule.IsExpired() && c.expire(key.(string), rule) {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// expire 移除过期规则，并发调用时只有一个调用方会触发回调
+func (c *Config) expire(key string, rule *RedirectRule) bool {
+	// 只删除仍是该规则的键，避免误删期间被替换的新规则
+	if !c.rules.CompareAndDelete(key, rule) {
+		return false
+	}
+	c.byID.CompareAndDelete(rule.ID, rule)
+	if c.onExpire != nil {
+		c.onExpire(rule)
+	}
+	return true
+}
+
 // SetRule 设置跳转规则
 func (c *Config) SetRule(rule *RedirectRule) {
 	key := c.generateKey(rule.Domain, rule.Path)
-	c.rules.Store(key, rule)
+	if prev, loaded := c.rules.Swap(key, rule); loaded {
+		c.byID.CompareAndDelete(prev.(*RedirectRule).ID, prev)
+	}
+	c.byID.Store(rule.ID, rule)
 }
 
 // DeleteRule 删除跳转规则
 func (c *Config) DeleteRule(domain, path string) {
 	key := c.generateKey(domain, path)
-	c.rules.Delete(key)
+	if prev, loaded := c.rules.LoadAndDelete(key); loaded {
+		c.byID.CompareAndDelete(prev.(*RedirectRule).ID, prev)
+	}
+}
+
+// GetRuleByID 按 ID 获取规则
+func (c *Config) GetRuleByID(id string) (*RedirectRule, bool) {
+	value, ok := c.byID.Load(id)
+	if !ok {
+		return nil, false
+	}
+	rule := value.(*RedirectRule)
+	if rule.IsExpired() {
+		return nil, false
+	}
+	return rule, true
 }
 
 // GetAllRules 获取所有规则
@@ -101,6 +213,19 @@ func (c *Config) GetAllRules() []*RedirectRule {
 	return rules
 }
 
+// GenerateID 根据域名和路径生成规则 ID
+// 不同的域名和路径可能生成相同的 ID（如 a.b 和 a_b），taken 返回 true 时依次添加 -2、-3 等后缀
+func GenerateID(domain, path string, taken func(id string) bool) string {
+	base := idReplacer.Replace(strings.Trim(domain+path, "/"))
+	id := base
+	for n := 2; taken != nil && taken(id); n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+var idReplacer = strings.NewReplacer(".", "_", "/", "_", ":", "_")
+
 // generateKey 生成规则键
 func (c *Config) generateKey(domain, path string) string {
 	if path == "" {
@@ -109,71 +234,180 @@ func (c *Config) generateKey(domain, path string) string {
 	return domain + "|" + path
 }
 
-// LoadFromFile 从文件加载配置
-func (c *Config) LoadFromFile() error {
+// SetStore 设置规则存储后端
+func (c *Config) SetStore(store RuleStore) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.store = store
+}
 
-	data, err := os.ReadFile(c.ConfigFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // 文件不存在，使用空配置
-		}
-		return err
+// ruleStore 获取规则存储后端，未设置时使用配置文件对应的 JSON 存储
+func (c *Config) ruleStore() RuleStore {
+	if c.store == nil {
+		c.store = NewJSONFileStore(c.ConfigFile)
 	}
+	return c.store
+}
 
-	var rules []*RedirectRule
-	if err := json.Unmarshal(data, &rules); err != nil {
+// Load 从存储加载规则
+// 存在无效规则时返回 *LoadError，其余有效规则仍会加载
+func (c *Config) Load() (err error) {
+	c.setLoading()
+	defer func() { c.setLoaded(err) }()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rules, err := c.ruleStore().Load()
+	var partial *LoadError
+	if err != nil && !errors.As(err, &partial) {
 		return err
 	}
 
-	c.rules = &sync.Map{}
+	c.rules, c.byID = &sync.Map{}, &sync.Map{}
 	for _, rule := range rules {
 		if !rule.IsExpired() {
-			key := c.generateKey(rule.Domain, rule.Path)
-			c.rules.Store(key, rule)
+			c.SetRule(rule)
 		}
 	}
 
-	return nil
+	return err
 }
 
-// SaveToFile 保存配置到文件
-func (c *Config) SaveToFile() error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// LoadStatus 返回规则加载状态（加载期间也不会阻塞）
+func (c *Config) LoadStatus() LoadStatus {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.status
+}
+
+// setLoading 标记开始加载
+func (c *Config) setLoading() {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.status.Loading = true
+}
+
+// setLoaded 记录加载结果，只跳过了无效规则时视为成功
+func (c *Config) setLoaded(err error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.status.Loading = false
+	var partial *LoadError
+	if err != nil && !errors.As(err, &partial) {
+		c.status.Err = err
+		return
+	}
+	now := time.Now()
+	c.status.LoadedAt = &now
+	c.status.Err = nil
+}
 
-	rules := c.GetAllRules()
-	data, err := json.MarshalIndent(rules, "", "  ")
+// Save 将当前所有规则全量保存到存储
+// 内存中不保留已过期的规则，保存时从存储中读取并一并写回，过期规则仍可查询
+func (c *Config) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expired, err := c.expiredRules()
 	if err != nil {
 		return err
 	}
+	return c.ruleStore().Replace(append(c.GetAllRules(), expired...))
+}
+
+// ApplyBatch 批量写入和删除规则，先一次性持久化，成功后再更新内存，全部生效或全部不生效
+// check 在持有写锁时对变更后的规则集副本进行检查，返回 false 时放弃变更
+func (c *Config) ApplyBatch(puts []*RedirectRule, deleteIDs []string, check func(staged *Config) bool) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if check != nil && !check(c.Staged(puts, deleteIDs)) {
+		return false, nil
+	}
+	if err := c.ruleStore().Apply(puts, deleteIDs); err != nil {
+		return false, err
+	}
 
-	return os.WriteFile(c.ConfigFile, data, 0644)
+	for _, id := range deleteIDs {
+		if old, ok := c.GetRuleByID(id); ok {
+			c.DeleteRule(old.Domain, old.Path)
+		}
+	}
+	for _, rule := range puts {
+		if old, ok := c.GetRuleByID(rule.ID); ok && (old.Domain != rule.Domain || old.Path != rule.Path) {
+			c.DeleteRule(old.Domain, old.Path)
+		}
+		c.SetRule(rule)
+	}
+	return true, nil
 }
 
-// FindRule 查找匹配的规则（优先精确路径匹配，再域名匹配）
-func (c *Config) FindRule(domain, path string) (*RedirectRule, bool) {
-	// 先尝试精确匹配（域名+路径）
-	if path != "" {
-		key := c.generateKey(domain, path)
-		if rule, ok := c.GetRule(key); ok {
-			return rule, true
+// Staged 返回应用变更后的规则集副本（不关联存储），用于变更前的冲突和跳转链检查
+// 写入的规则与其他规则的域名和路径相同时保留先出现的规则，使后者的冲突检查能发现重复
+func (c *Config) Staged(puts []*RedirectRule, deleteIDs []string) *Config {
+	removed := make(map[string]bool, len(deleteIDs)+len(puts))
+	for _, id := range deleteIDs {
+		removed[id] = true
+	}
+	for _, rule := range puts {
+		removed[rule.ID] = true
+	}
+
+	staged := &Config{
+		MaxChainLength: c.MaxChainLength,
+		rules:          &sync.Map{},
+		byID:           &sync.Map{},
+	}
+	for _, rule := range c.GetAllRules() {
+		if !removed[rule.ID] {
+			staged.SetRule(rule)
+		}
+	}
+	for _, rule := range puts {
+		if _, loaded := staged.rules.LoadOrStore(staged.generateKey(rule.Domain, rule.Path), rule); !loaded {
+			staged.byID.Store(rule.ID, rule)
 		}
 	}
+	return staged
+}
 
-	// 再尝试域名匹配
-	key := c.generateKey(domain, "")
-	if rule, ok := c.GetRule(key); ok {
-		return rule, true
+// Close 关闭规则存储
+func (c *Config) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.store == nil {
+		return nil
 	}
+	return c.store.Close()
+}
 
-	return nil, false
+// FindRule 查找匹配的规则（优先精确路径匹配，再域名匹配）
+func (c *Config) FindRule(domain, path string) (*RedirectRule, bool) {
+	rule, _ := c.Match(domain, path, false)
+	return rule, rule != nil
 }
 
 // CheckConflict 检查规则冲突
-// 返回冲突的规则列表和冲突描述
-func (c *Config) CheckConflict(rule *RedirectRule, excludeID string) ([]*RedirectRule, string) {
+// 返回冲突的规则列表和冲突描述；visible 用于按权限过滤返回的规则和描述中的跳转链（为 nil 时不过滤）
+// 冲突规则不可查看时仍视为冲突，只是不返回其内容
+func (c *Config) CheckConflict(rule *RedirectRule, excludeID string, visible func(rule *RedirectRule) bool) ([]*RedirectRule, string) {
+	conflicts, conflictMsg := c.checkConflict(rule, excludeID, visible)
+	if conflictMsg == "" || visible == nil {
+		return conflicts, conflictMsg
+	}
+	readable := []*RedirectRule{}
+	for _, r := range conflicts {
+		if visible(r) {
+			readable = append(readable, r)
+		}
+	}
+	return readable, conflictMsg
+}
+
+// checkConflict 检查规则冲突，返回全部冲突的规则
+func (c *Config) checkConflict(rule *RedirectRule, excludeID string, visible func(rule *RedirectRule) bool) ([]*RedirectRule, string) {
 	var conflicts []*RedirectRule
 	var conflictMsg string
 
@@ -212,5 +446,12 @@ func (c *Config) CheckConflict(rule *RedirectRule, excludeID string) ([]*Redirec
 		}
 	}
 
+	// 检查目标是否经本地规则跳转回自身（循环跳转）
+	if chain := c.TraceChain(rule, excludeID); chain.Cyclic {
+		conflicts = append(conflicts, chain.Rules[1:]...)
+		conflictMsg = "跳转目标会形成循环: " + chain.DescribeVisible(visible)
+		return conflicts, conflictMsg
+	}
+
 	return nil, ""
 }
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetRuleByIDIndex(t *testing.T) {
+	a := &RedirectRule{ID: "a", Domain: "example.com", Path: "/a", Target: "https://example.net/a", Type: RedirectType302}
+	c := newTestConfig(a)
+	if got, ok := c.GetRuleByID("a"); !ok || got != a {
+		t.Fatalf("GetRuleByID(a) = %v, %v", got, ok)
+	}
+
+	// 同一域名和路径被其他规则替换后，旧 ID 不再可查
+	b := &RedirectRule{ID: "b", Domain: "example.com", Path: "/a", Target: "https://example.net/b", Type: RedirectType302}
+	c.SetRule(b)
+	if _, ok := c.GetRuleByID("a"); ok {
+		t.Error("replaced rule a still indexed")
+	}
+	if got, ok := c.GetRuleByID("b"); !ok || got != b {
+		t.Errorf("GetRuleByID(b) = %v, %v", got, ok)
+	}
+
+	// 批量变更移动规则的路径
+	moved := &RedirectRule{ID: "b", Domain: "example.com", Path: "/moved", Target: "https://example.net/b", Type: RedirectType302}
+	c.store = NewJSONFileStore(filepath.Join(t.TempDir(), "rules.json"))
+	if ok, err := c.ApplyBatch([]*RedirectRule{moved}, nil, nil); !ok || err != nil {
+		t.Fatalf("ApplyBatch: %v, %v", ok, err)
+	}
+	if got, ok := c.GetRuleByID("b"); !ok || got != moved {
+		t.Errorf("GetRuleByID(b) after move = %v, %v", got, ok)
+	}
+	if _, ok := c.FindRule("example.com", "/a"); ok {
+		t.Error("old path still matches after move")
+	}
+
+	c.DeleteRule("example.com", "/moved")
+	if _, ok := c.GetRuleByID("b"); ok {
+		t.Error("deleted rule still indexed")
+	}
+
+	// 过期规则不再可查，清理后从索引中移除
+	past := time.Now().Add(-time.Minute)
+	expired := &RedirectRule{ID: "c", Domain: "example.com", Path: "/c", Target: "https://example.net/c", Type: RedirectType302, ExpiresAt: &past}
+	c.SetRule(expired)
+	if _, ok := c.GetRuleByID("c"); ok {
+		t.Error("expired rule returned")
+	}
+	if n := c.SweepExpired(); n != 1 {
+		t.Errorf("swept %d rules, want 1", n)
+	}
+	if _, ok := c.byID.Load("c"); ok {
+		t.Error("swept rule still indexed")
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+// MatchCandidate 匹配过程中考察过的候选规则
+type MatchCandidate struct {
+	Key     string        `json:"key"`            // 规则键（域名 或 域名|路径）
+	Level   string        `json:"level"`          // 匹配级别（path/domain）
+	Rule    *RedirectRule `json:"rule,omitempty"` // 该键对应的规则（不存在时为空）
+	Matched bool          `json:"matched"`        // 是否为最终命中的规则
+	Reason  string        `json:"reason"`         // 命中或未命中的原因
+}
+
+// Match 按匹配优先级查找规则（先精确路径，再域名）
+// trace 为 true 时不修改规则表，并返回所有候选规则及未命中原因，用于调试
+func (c *Config) Match(domain, path string, trace bool) (*RedirectRule, []MatchCandidate) {
+	var matched *RedirectRule
+	var candidates []MatchCandidate
+
+	for _, key := range c.matchKeys(domain, path) {
+		if !trace {
+			if rule, ok := c.GetRule(key); ok {
+				return rule, nil
+			}
+			continue
+		}
+
+		candidate := MatchCandidate{Key: key, Level: "domain"}
+		if key != c.generateKey(domain, "") {
+			candidate.Level = "path"
+		}
+
+		value, ok := c.rules.Load(key)
+		switch {
+		case !ok:
+			candidate.Reason = "不存在该键的规则"
+		case value.(*RedirectRule).IsExpired():
+			candidate.Rule = value.(*RedirectRule)
+			candidate.Reason = "规则已过期"
+		case matched != nil:
+			candidate.Rule = value.(*RedirectRule)
+			candidate.Reason = "已被优先级更高的规则命中"
+		default:
+			matched = value.(*RedirectRule)
+			candidate.Rule = matched
+			candidate.Matched = true
+			if candidate.Level == "path" {
+				candidate.Reason = "域名和路径精确匹配"
+			} else {
+				candidate.Reason = "域名匹配"
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return matched, candidates
+}
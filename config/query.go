@@ -0,0 +1,267 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 规则状态
+const (
+	StateActive    = "active"    // 未过期（包括永久和定时过期）
+	StateScheduled = "scheduled" // 设置了有效期且尚未过期
+	StatePermanent = "permanent" // 永不过期
+	StateExpired   = "expired"   // 已过期（从存储中读取）
+)
+
+// 排序字段
+var sortFields = map[string]func(rule *RedirectRule) string{
+	"id":         func(rule *RedirectRule) string { return rule.ID },
+	"domain":     func(rule *RedirectRule) string { return rule.Domain + "|" + rule.Path },
+	"target":     func(rule *RedirectRule) string { return rule.Target },
+	"type":       func(rule *RedirectRule) string { return strconv.Itoa(int(rule.Type)) },
+	"created_at": func(rule *RedirectRule) string { return sortableTime(rule.CreatedAt) },
+	"expires_at": func(rule *RedirectRule) string {
+		if rule.ExpiresAt == nil {
+			return "~" // 永不过期的规则排在最后
+		}
+		return sortableTime(*rule.ExpiresAt)
+	},
+}
+
+// HitSource 规则命中统计，用于按命中次数和最后命中时间排序
+type HitSource interface {
+	RuleHits(ruleID string) (total int64, lastHit time.Time)
+}
+
+// 依赖命中统计的排序字段（查询未提供 HitSource 时不可用）
+var hitSortFields = map[string]func(hits HitSource, rule *RedirectRule) string{
+	"hits": func(hits HitSource, rule *RedirectRule) string {
+		total, _ := hits.RuleHits(rule.ID)
+		return fmt.Sprintf("%020d", total)
+	},
+	"last_hit_at": func(hits HitSource, rule *RedirectRule) string {
+		_, last := hits.RuleHits(rule.ID)
+		if last.IsZero() {
+			return "" // 从未命中的规则排在最前
+		}
+		return sortableTime(last)
+	},
+}
+
+// 分页大小
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// RuleQuery 规则查询条件（零值表示不限制）
+type RuleQuery struct {
+	Domain string       // 域名，支持 *.example.com 形式匹配子域名
+	Type   RedirectType // 跳转类型
+	Search string       // 在目标 URL 和描述中搜索（不区分大小写）
+	State  string       // 规则状态，为空时等同 active
+	Sort   string       // 排序字段，前缀 - 表示倒序，默认 id
+	Limit  int          // 每页条数
+	Cursor string       // 上一页返回的 next_cursor
+	Hits   HitSource    // 命中统计（为 nil 时不能按命中排序）
+}
+
+// RulePage 规则查询结果
+type RulePage struct {
+	Rules      []*RedirectRule `json:"rules"`
+	Total      int             `json:"total"`                 // 满足条件的规则总数
+	NextCursor string          `json:"next_cursor,omitempty"` // 下一页游标（为空表示没有更多）
+}
+
+// pageCursor 游标内容：上一页最后一条规则的排序键和 ID
+type pageCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   string `json:"i"`
+}
+
+// Validate 校验查询条件并补全默认值
+func (q *RuleQuery) Validate() ValidationErrors {
+	var errs ValidationErrors
+	switch q.Type {
+	case 0, RedirectType301, RedirectType302, RedirectType307, RedirectTypeJS:
+	default:
+		errs.add("type", "跳转类型必须是 301、302、307 或 4（JavaScript）")
+	}
+	switch q.State {
+	case "":
+		q.State = StateActive
+	case StateActive, StateScheduled, StatePermanent, StateExpired:
+	default:
+		errs.add("state", "必须是 active、scheduled、permanent 或 expired")
+	}
+	if q.Sort == "" {
+		q.Sort = "id"
+	}
+	if q.sortKey() == nil {
+		errs.add("sort", "不支持的排序字段 %s", q.Sort)
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit < 0 || q.Limit > MaxPageSize {
+		errs.add("limit", "必须在 1 到 %d 之间", MaxPageSize)
+	}
+	if q.Cursor != "" {
+		if c, ok := decodeCursor(q.Cursor); !ok || c.Sort != q.Sort {
+			errs.add("cursor", "无效的游标（排序方式变化后需从第一页开始）")
+		}
+	}
+	return errs
+}
+
+// QueryRules 按条件查询规则并分页，visible 用于按权限过滤（为 nil 时不过滤）
+// 调用前需先调用 Validate
+func (c *Config) QueryRules(q RuleQuery, visible func(rule *RedirectRule) bool) (*RulePage, error) {
+	var candidates []*RedirectRule
+	if q.State == StateExpired {
+		expired, err := c.ExpiredRules()
+		if err != nil {
+			return nil, err
+		}
+		candidates = expired
+	} else {
+		candidates = c.GetAllRules()
+	}
+
+	search := strings.ToLower(q.Search)
+	var matched []*RedirectRule
+	for _, rule := range candidates {
+		if visible != nil && !visible(rule) {
+			continue
+		}
+		if q.Domain != "" && !matchDomainFilter(q.Domain, rule.Domain) {
+			continue
+		}
+		if q.Type != 0 && rule.Type != q.Type {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(rule.Target), search) &&
+			!strings.Contains(strings.ToLower(rule.Description), search) {
+			continue
+		}
+		if q.State == StateScheduled && rule.ExpiresAt == nil || q.State == StatePermanent && rule.ExpiresAt != nil {
+			continue
+		}
+		matched = append(matched, rule)
+	}
+
+	desc := strings.HasPrefix(q.Sort, "-")
+	key := q.sortKey()
+	less := func(a, b *RedirectRule) bool {
+		ka, kb := key(a), key(b)
+		if ka != kb {
+			return (ka < kb) != desc
+		}
+		return (a.ID < b.ID) != desc
+	}
+	sort.Slice(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+
+	page := &RulePage{Total: len(matched), Rules: []*RedirectRule{}}
+	start := 0
+	if cursor, ok := decodeCursor(q.Cursor); ok {
+		// 定位到游标之后的第一条（规则在翻页期间被修改或删除时也能继续）
+		start = sort.Search(len(matched), func(i int) bool {
+			ki := key(matched[i])
+			if ki != cursor.Key {
+				return (ki > cursor.Key) != desc
+			}
+			if desc {
+				return matched[i].ID < cursor.ID
+			}
+			return matched[i].ID > cursor.ID
+		})
+	}
+	end := min(start+q.Limit, len(matched))
+	page.Rules = append(page.Rules, matched[start:end]...)
+	if end < len(matched) {
+		last := matched[end-1]
+		page.NextCursor = encodeCursor(pageCursor{Sort: q.Sort, Key: key(last), ID: last.ID})
+	}
+	return page, nil
+}
+
+// sortKey 返回排序字段的排序键函数，不支持时返回 nil
+func (q *RuleQuery) sortKey() func(rule *RedirectRule) string {
+	field := strings.TrimPrefix(q.Sort, "-")
+	if key, ok := sortFields[field]; ok {
+		return key
+	}
+	if key, ok := hitSortFields[field]; ok && q.Hits != nil {
+		return func(rule *RedirectRule) string { return key(q.Hits, rule) }
+	}
+	return nil
+}
+
+// ExpiredRules 从存储中读取已过期的规则（内存中只保留未过期的规则）
+// 同 ID 已有未过期规则时忽略存储中的旧版本
+func (c *Config) ExpiredRules() ([]*RedirectRule, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.expiredRules()
+}
+
+// expiredRules 读取存储中已过期的规则，调用方需持有 c.mu
+func (c *Config) expiredRules() ([]*RedirectRule, error) {
+	rules, err := c.ruleStore().Load()
+	var partial *LoadError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+
+	active := make(map[string]bool)
+	for _, rule := range c.GetAllRules() {
+		active[rule.ID] = true
+	}
+	var expired []*RedirectRule
+	for _, rule := range rules {
+		if rule.IsExpired() && !active[rule.ID] {
+			expired = append(expired, rule)
+		}
+	}
+	return expired, nil
+}
+
+// matchDomainFilter 判断域名是否满足过滤条件（精确匹配，或 *.example.com 匹配其子域名）
+func matchDomainFilter(filter, domain string) bool {
+	if suffix, ok := strings.CutPrefix(filter, "*."); ok {
+		return strings.HasSuffix(domain, "."+suffix)
+	}
+	return domain == filter
+}
+
+// sortableTime 将时间格式化为可按字典序比较的字符串
+func sortableTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000000000")
+}
+
+// encodeCursor 编码游标
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解码游标
+func decodeCursor(s string) (pageCursor, bool) {
+	var c pageCursor
+	if s == "" {
+		return c, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &c) != nil {
+		return c, false
+	}
+	return c, true
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestConfig 创建只包含 rules 的规则集（不关联存储）
+func newTestConfig(rules ...*RedirectRule) *Config {
+	c := &Config{rules: &sync.Map{}, byID: &sync.Map{}}
+	for _, rule := range rules {
+		c.SetRule(rule)
+	}
+	return c
+}
+
+// queryRules 构造规则集用于查询测试：类型和创建时间有重复，便于检查排序键相同时的翻页
+func queryRules() []*RedirectRule {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := base.Add(time.Hour)
+	expires := base.AddDate(10, 0, 0)
+	return []*RedirectRule{
+		{ID: "a", Domain: "example.com", Path: "/a", Target: "https://example.net/a", Type: RedirectType301, CreatedAt: base, Description: "Spring sale"},
+		{ID: "b", Domain: "example.com", Path: "/b", Target: "https://example.net/b", Type: RedirectType302, CreatedAt: base},
+		{ID: "c", Domain: "shop.example.com", Path: "", Target: "https://shop.example.net/", Type: RedirectType301, CreatedAt: later},
+		{ID: "d", Domain: "blog.example.com", Path: "/old", Target: "https://blog.example.net/new", Type: RedirectType301, CreatedAt: base, ExpiresAt: &expires},
+		{ID: "e", Domain: "other.com", Path: "/x", Target: "https://SALE.example.org/", Type: RedirectType302, CreatedAt: later},
+		{ID: "f", Domain: "other.com", Path: "/y", Target: "https://example.org/y", Type: RedirectType307, CreatedAt: base},
+		{ID: "g", Domain: "other.com", Path: "/z", Target: "https://example.org/z", Type: RedirectType301, CreatedAt: later},
+	}
+}
+
+// ids 返回规则 ID 列表
+func ids(rules []*RedirectRule) []string {
+	out := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		out = append(out, rule.ID)
+	}
+	return out
+}
+
+// queryAll 按 limit 逐页查询，返回依次得到的全部规则 ID
+func queryAll(t *testing.T, c *Config, q RuleQuery) []string {
+	t.Helper()
+	var all []string
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatalf("sort=%s: paging does not terminate", q.Sort)
+		}
+		if errs := q.Validate(); len(errs) > 0 {
+			t.Fatalf("sort=%s: %v", q.Sort, errs)
+		}
+		page, err := c.QueryRules(q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Rules) > q.Limit {
+			t.Fatalf("sort=%s: page has %d rules, limit %d", q.Sort, len(page.Rules), q.Limit)
+		}
+		all = append(all, ids(page.Rules)...)
+		if page.NextCursor == "" {
+			return all
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func TestQueryRulesPaging(t *testing.T) {
+	c := newTestConfig(queryRules()...)
+	for _, tt := range []struct {
+		sort string
+		want []string
+	}{
+		{"id", []string{"a", "b", "c", "d", "e", "f", "g"}},
+		{"-id", []string{"g", "f", "e", "d", "c", "b", "a"}},
+		// 排序键相同时按 ID 排序（倒序时 ID 也倒序）
+		{"type", []string{"a", "c", "d", "g", "b", "e", "f"}},
+		{"-type", []string{"f", "e", "b", "g", "d", "c", "a"}},
+		{"created_at", []string{"a", "b", "d", "f", "c", "e", "g"}},
+		{"-created_at", []string{"g", "e", "c", "f", "d", "b", "a"}},
+		{"domain", []string{"d", "a", "b", "e", "f", "g", "c"}},
+		{"-expires_at", []string{"g", "f", "e", "c", "b", "a", "d"}},
+	} {
+		for _, limit := range []int{1, 2, 3, 7, 100} {
+			got := queryAll(t, c, RuleQuery{Sort: tt.sort, Limit: limit})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sort=%s limit=%d: got %v, want %v", tt.sort, limit, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestQueryRulesCursorAfterChange(t *testing.T) {
+	c := newTestConfig(queryRules()...)
+	q := RuleQuery{Sort: "-type", Limit: 3}
+	q.Validate()
+	first, err := c.QueryRules(q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(first.Rules); !reflect.DeepEqual(got, []string{"f", "e", "b"}) {
+		t.Fatalf("first page = %v", got)
+	}
+
+	// 游标所在的规则被删除后，下一页从其后一条继续
+	c.DeleteRule("example.com", "/b")
+	q.Cursor = first.NextCursor
+	second, err := c.QueryRules(q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(second.Rules); !reflect.DeepEqual(got, []string{"g", "d", "c"}) {
+		t.Errorf("second page = %v, want [g d c]", got)
+	}
+}
+
+func TestQueryRulesFilters(t *testing.T) {
+	c := newTestConfig(queryRules()...)
+	for _, tt := range []struct {
+		name string
+		q    RuleQuery
+		want []string
+	}{
+		{"all", RuleQuery{}, []string{"a", "b", "c", "d", "e", "f", "g"}},
+		{"domain", RuleQuery{Domain: "example.com"}, []string{"a", "b"}},
+		{"subdomains", RuleQuery{Domain: "*.example.com"}, []string{"c", "d"}},
+		{"type", RuleQuery{Type: RedirectType302}, []string{"b", "e"}},
+		{"search target and description", RuleQuery{Search: "sale"}, []string{"a", "e"}},
+		{"scheduled", RuleQuery{State: StateScheduled}, []string{"d"}},
+		{"permanent", RuleQuery{State: StatePermanent, Domain: "*.example.com"}, []string{"c"}},
+		{"combined", RuleQuery{Domain: "other.com", Type: RedirectType301}, []string{"g"}},
+	} {
+		if errs := tt.q.Validate(); len(errs) > 0 {
+			t.Fatalf("%s: %v", tt.name, errs)
+		}
+		page, err := c.QueryRules(tt.q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(page.Rules); !reflect.DeepEqual(got, tt.want) || page.Total != len(tt.want) {
+			t.Errorf("%s: got %v (total %d), want %v", tt.name, got, page.Total, tt.want)
+		}
+	}
+
+	// visible 过滤的规则不计入总数
+	q := RuleQuery{}
+	q.Validate()
+	page, _ := c.QueryRules(q, func(rule *RedirectRule) bool { return rule.Domain == "other.com" })
+	if got := ids(page.Rules); !reflect.DeepEqual(got, []string{"e", "f", "g"}) || page.Total != 3 {
+		t.Errorf("visible: got %v (total %d)", got, page.Total)
+	}
+}
+
+func TestRuleQueryValidate(t *testing.T) {
+	cursor := encodeCursor(pageCursor{Sort: "id", Key: "a", ID: "a"})
+	for _, tt := range []struct {
+		q     RuleQuery
+		field string // 为空表示校验通过
+	}{
+		{RuleQuery{}, ""},
+		{RuleQuery{Sort: "-created_at", Limit: MaxPageSize}, ""},
+		{RuleQuery{Sort: "id", Cursor: cursor}, ""},
+		{RuleQuery{Type: 303}, "type"},
+		{RuleQuery{State: "deleted"}, "state"},
+		{RuleQuery{Sort: "bogus"}, "sort"},
+		{RuleQuery{Sort: "hits"}, "sort"}, // 未提供命中统计
+		{RuleQuery{Limit: MaxPageSize + 1}, "limit"},
+		{RuleQuery{Limit: -1}, "limit"},
+		{RuleQuery{Cursor: "not-a-cursor"}, "cursor"},
+		{RuleQuery{Sort: "-id", Cursor: cursor}, "cursor"}, // 排序方式变化
+	} {
+		errs := tt.q.Validate()
+		switch {
+		case tt.field == "" && len(errs) > 0:
+			t.Errorf("%+v: unexpected errors %v", tt.q, errs)
+		case tt.field != "" && (len(errs) != 1 || errs[0].Field != tt.field):
+			t.Errorf("%+v: errors = %v, want one on %s", tt.q, errs, tt.field)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 存储后端类型
+const (
+	StoreJSON = "json" // JSON 文件（整文件读写）
+	StoreBolt = "bolt" // 嵌入式事务型 KV 存储（单文件，按规则读写）
+)
+
+// RuleStore 规则持久化存储接口
+type RuleStore interface {
+	// Load 加载全部规则
+	Load() ([]*RedirectRule, error)
+	// Put 写入规则（按 ID 新增或覆盖）
+	Put(rules ...*RedirectRule) error
+	// Delete 按 ID 删除规则
+	Delete(ids ...string) error
+	// Apply 在一次写入中写入和删除规则，失败时存储内容保持不变
+	Apply(puts []*RedirectRule, deleteIDs []string) error
+	// Replace 用给定规则全量替换存储内容
+	Replace(rules []*RedirectRule) error
+	// Close 关闭存储
+	Close() error
+}
+
+// RuleLoadError 单条规则加载错误
+type RuleLoadError struct {
+	Index   int              `json:"index"`            // 规则在文件中的序号（从 0 开始）
+	Line    int              `json:"line,omitempty"`   // 规则在文件中的起始行号（JSON 文件）
+	Key     string           `json:"key,omitempty"`    // 规则的存储键（KV 存储）
+	Message string           `json:"message"`          // 错误描述
+	Fields  ValidationErrors `json:"fields,omitempty"` // 字段校验错误
+}
+
+// Location 返回规则位置描述
+func (e RuleLoadError) Location() string {
+	if e.Key != "" {
+		return "key " + e.Key
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("index %d (line %d)", e.Index, e.Line)
+	}
+	return fmt.Sprintf("index %d", e.Index)
+}
+
+// LoadError 加载时被跳过的无效规则
+// 存储返回 LoadError 时，其余有效规则仍会正常返回
+type LoadError struct {
+	Source string          // 存储文件路径
+	Items  []RuleLoadError // 无效规则列表
+}
+
+// Error 实现 error 接口
+func (e *LoadError) Error() string {
+	parts := make([]string, 0, len(e.Items))
+	for _, item := range e.Items {
+		parts = append(parts, item.Location()+": "+item.Message)
+	}
+	return fmt.Sprintf("%s: 跳过 %d 条无效规则: %s", e.Source, len(e.Items), strings.Join(parts, "; "))
+}
+
+// decodeRule 解析并校验单条已存储的规则，失败时填写 item 的错误信息
+// 规则没有 ID 时生成 ID，taken 判断 ID 是否已被之前加载的规则占用
+func decodeRule(data []byte, item *RuleLoadError, taken func(id string) bool) (*RedirectRule, bool) {
+	var rule RedirectRule
+	if err := json.Unmarshal(data, &rule); err != nil {
+		item.Message = err.Error()
+		return nil, false
+	}
+	if errs := ValidateRule(&rule); len(errs) > 0 {
+		item.Message = "规则校验失败"
+		item.Fields = errs
+		return nil, false
+	}
+	if rule.ID == "" {
+		rule.ID = GenerateID(rule.Domain, rule.Path, taken)
+	}
+	return &rule, true
+}
+
+// OpenStore 按类型打开规则存储
+func OpenStore(kind, path string) (RuleStore, error) {
+	switch kind {
+	case "", StoreJSON:
+		return NewJSONFileStore(path), nil
+	case StoreBolt:
+		return NewBoltStore(path)
+	default:
+		return nil, fmt.Errorf("未知的存储类型: %s", kind)
+	}
+}
+
+// Migrate 将源存储中的全部规则写入目标存储（替换目标存储原有内容），返回写入的规则数
+// 源存储中的无效规则被跳过，写入成功后返回 *LoadError 说明跳过的规则
+func Migrate(src, dst RuleStore) (int, error) {
+	rules, loadErr := src.Load()
+	var partial *LoadError
+	if loadErr != nil && !errors.As(loadErr, &partial) {
+		return 0, fmt.Errorf("读取源存储失败: %w", loadErr)
+	}
+	if err := dst.Replace(rules); err != nil {
+		return 0, fmt.Errorf("写入目标存储失败: %w", err)
+	}
+	return len(rules), loadErr
+}
+
+// sortRules 按创建时间、ID 排序，保证持久化结果稳定
+func sortRules(rules []*RedirectRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if !rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+			return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+		}
+		return rules[i].ID < rules[j].ID
+	})
+}
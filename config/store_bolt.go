@@ -0,0 +1,130 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var rulesBucket = []byte("rules")
+
+// BoltStore 基于 bbolt 的规则存储
+// 纯 Go 实现的单文件事务型 KV 存储，每条规则独立写入
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore 打开（或创建）bbolt 存储文件
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(rulesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Load 加载全部规则，无效规则返回 *LoadError 并跳过
+func (s *BoltStore) Load() ([]*RedirectRule, error) {
+	var rules []*RedirectRule
+	var loadErr LoadError
+	err := s.db.View(func(tx *bolt.Tx) error {
+		index := 0
+		return tx.Bucket(rulesBucket).ForEach(func(k, v []byte) error {
+			item := RuleLoadError{Index: index, Key: string(k)}
+			index++
+			if rule, ok := decodeRule(v, &item, nil); ok {
+				rules = append(rules, rule)
+			} else {
+				loadErr.Items = append(loadErr.Items, item)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortRules(rules)
+
+	if len(loadErr.Items) > 0 {
+		loadErr.Source = s.db.Path()
+		return rules, &loadErr
+	}
+	return rules, nil
+}
+
+// Put 在单个事务内写入规则
+func (s *BoltStore) Put(rules ...*RedirectRule) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putRules(tx.Bucket(rulesBucket), rules)
+	})
+}
+
+// Delete 在单个事务内删除规则
+func (s *BoltStore) Delete(ids ...string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(rulesBucket)
+		for _, id := range ids {
+			if err := b.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Apply 在单个事务内写入和删除规则
+func (s *BoltStore) Apply(puts []*RedirectRule, deleteIDs []string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(rulesBucket)
+		for _, id := range deleteIDs {
+			if err := b.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return putRules(b, puts)
+	})
+}
+
+// Replace 在单个事务内全量替换规则
+func (s *BoltStore) Replace(rules []*RedirectRule) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(rulesBucket); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket(rulesBucket)
+		if err != nil {
+			return err
+		}
+		return putRules(b, rules)
+	})
+}
+
+// Close 关闭存储文件
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// putRules 将规则序列化写入 bucket
+func putRules(b *bolt.Bucket, rules []*RedirectRule) error {
+	for _, rule := range rules {
+		data, err := json.Marshal(rule)
+		if err != nil {
+			return err
+		}
+		if err := b.Put([]byte(rule.ID), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
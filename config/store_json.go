@@ -0,0 +1,245 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"mini_jump/atomicfile"
+)
+
+// JSONFileStore 基于 JSON 文件的规则存储
+// 每次写入都会重写整个文件，适合规则数量较少的场景
+// 加载时无效的规则会原样保留在文件中，等待人工修复
+type JSONFileStore struct {
+	path    string
+	rules   map[string]*RedirectRule
+	invalid []json.RawMessage
+	loaded  bool
+	mu      sync.Mutex
+}
+
+// NewJSONFileStore 创建 JSON 文件存储
+func NewJSONFileStore(path string) *JSONFileStore {
+	return &JSONFileStore{
+		path:  path,
+		rules: make(map[string]*RedirectRule),
+	}
+}
+
+// Load 从文件加载全部规则
+func (s *JSONFileStore) Load() ([]*RedirectRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loadErr := s.loadUnlocked()
+	var partial *LoadError
+	if loadErr != nil && !errors.As(loadErr, &partial) {
+		return nil, loadErr
+	}
+
+	rules := make([]*RedirectRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, rule)
+	}
+	sortRules(rules)
+	return rules, loadErr
+}
+
+// Put 写入规则并重写文件
+func (s *JSONFileStore) Put(rules ...*RedirectRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		s.rules[rule.ID] = rule
+	}
+	return s.writeUnlocked()
+}
+
+// Delete 删除规则并重写文件
+func (s *JSONFileStore) Delete(ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		delete(s.rules, id)
+	}
+	return s.writeUnlocked()
+}
+
+// Apply 写入和删除规则并重写一次文件，写入失败时恢复原内容
+func (s *JSONFileStore) Apply(puts []*RedirectRule, deleteIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+	original := s.rules
+	s.rules = make(map[string]*RedirectRule, len(original)+len(puts))
+	for id, rule := range original {
+		s.rules[id] = rule
+	}
+	for _, id := range deleteIDs {
+		delete(s.rules, id)
+	}
+	for _, rule := range puts {
+		s.rules[rule.ID] = rule
+	}
+	if err := s.writeUnlocked(); err != nil {
+		s.rules = original
+		return err
+	}
+	return nil
+}
+
+// Replace 全量替换文件内容
+func (s *JSONFileStore) Replace(rules []*RedirectRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules = make(map[string]*RedirectRule, len(rules))
+	for _, rule := range rules {
+		s.rules[rule.ID] = rule
+	}
+	s.loaded = true
+	return s.writeUnlocked()
+}
+
+// Close 关闭存储（JSON 文件无需释放资源）
+func (s *JSONFileStore) Close() error {
+	return nil
+}
+
+// ensureLoaded 确保写入前已读取文件，避免覆盖未加载的规则
+func (s *JSONFileStore) ensureLoaded() error {
+	if s.loaded {
+		return nil
+	}
+	var partial *LoadError
+	if err := s.loadUnlocked(); err != nil && !errors.As(err, &partial) {
+		return err
+	}
+	return nil
+}
+
+// loadUnlocked 读取文件（需要在锁内调用）
+// 逐条解析和校验规则，无效规则返回 *LoadError 并跳过，不影响其余规则
+func (s *JSONFileStore) loadUnlocked() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.rules = make(map[string]*RedirectRule)
+			s.invalid = nil
+			s.loaded = true
+			return nil // 文件不存在，使用空配置
+		}
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		if len(bytes.TrimSpace(data)) == 0 {
+			s.rules = make(map[string]*RedirectRule)
+			s.invalid = nil
+			s.loaded = true
+			return nil
+		}
+		return fmt.Errorf("%s: 配置文件必须是规则数组", s.path)
+	}
+
+	rules := make(map[string]*RedirectRule)
+	var invalid []json.RawMessage
+	var loadErr LoadError
+	for index := 0; dec.More(); index++ {
+		line := lineAt(data, dec.InputOffset())
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return fmt.Errorf("%s: 第 %d 行: 文件意外结束", s.path, line)
+			}
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				// Offset 位于出错字符之后，出错字符在行尾时会被算到下一行
+				return fmt.Errorf("%s: 第 %d 行: %v", s.path, lineAt(data, syntaxErr.Offset-1), err)
+			}
+			return fmt.Errorf("%s: 第 %d 行: %v", s.path, line, err)
+		}
+
+		item := RuleLoadError{Index: index, Line: line}
+		rule, ok := decodeRule(raw, &item, func(id string) bool {
+			_, dup := rules[id]
+			return dup
+		})
+		if ok {
+			if _, dup := rules[rule.ID]; !dup {
+				rules[rule.ID] = rule
+				continue
+			}
+			item.Message = "规则 ID 重复: " + rule.ID
+		}
+		invalid = append(invalid, raw)
+		loadErr.Items = append(loadErr.Items, item)
+	}
+
+	s.rules = rules
+	s.invalid = invalid
+	s.loaded = true
+
+	if len(loadErr.Items) > 0 {
+		loadErr.Source = s.path
+		return &loadErr
+	}
+	return nil
+}
+
+// lineAt 计算偏移量所在行号（跳过分隔符和空白，定位到下一个值的起始位置）
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	for offset < int64(len(data)) {
+		c := data[offset]
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' && c != ',' {
+			break
+		}
+		offset++
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// writeUnlocked 写入文件（先写临时文件再重命名，避免写入中断损坏文件）
+func (s *JSONFileStore) writeUnlocked() error {
+	rules := make([]*RedirectRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, rule)
+	}
+	sortRules(rules)
+
+	entries := make([]interface{}, 0, len(rules)+len(s.invalid))
+	for _, rule := range rules {
+		entries = append(entries, rule)
+	}
+	for _, raw := range s.invalid {
+		entries = append(entries, raw)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteData(s.path, data)
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// storeRules 构造存储测试用的规则（按 sortRules 的顺序排列）
+func storeRules() []*RedirectRule {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	expires := base.AddDate(1, 0, 0)
+	return []*RedirectRule{
+		{ID: "a", Domain: "example.com", Path: "/a", Target: "https://example.net/a", Type: RedirectType301, CreatedAt: base, Revision: 1},
+		{ID: "b", Domain: "example.com", Path: "/b", Target: "https://example.net/b", Type: RedirectType302, CreatedAt: base, Description: "促销", Revision: 3},
+		{ID: "c", Domain: "shop.example.com", Target: "https://shop.example.net/", Type: RedirectType307, CreatedAt: base.Add(time.Hour), ExpiresAt: &expires, Revision: 2},
+	}
+}
+
+// openTestStore 在临时目录中打开指定类型的存储，测试结束时关闭
+func openTestStore(t *testing.T, kind, path string) RuleStore {
+	t.Helper()
+	store, err := OpenStore(kind, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// mustLoad 加载规则并检查结果
+func mustLoad(t *testing.T, store RuleStore, want []*RedirectRule) {
+	t.Helper()
+	got, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %v, want %v", ids(got), ids(want))
+		for i := 0; i < len(got) && i < len(want); i++ {
+			if !reflect.DeepEqual(got[i], want[i]) {
+				t.Errorf("rule %d = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	for _, kind := range []string{StoreJSON, StoreBolt} {
+		t.Run(kind, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules")
+			store := openTestStore(t, kind, path)
+			mustLoad(t, store, nil)
+
+			rules := storeRules()
+			if err := store.Put(rules...); err != nil {
+				t.Fatal(err)
+			}
+			mustLoad(t, store, rules)
+
+			// 一次写入中修改 a、删除 b、新增 d
+			changed := *rules[0]
+			changed.Target = "https://example.net/changed"
+			changed.Revision = 2
+			added := &RedirectRule{ID: "d", Domain: "other.com", Path: "/d", Target: "https://other.net/d", Type: RedirectType302, CreatedAt: rules[2].CreatedAt}
+			if err := store.Apply([]*RedirectRule{&changed, added}, []string{"b"}); err != nil {
+				t.Fatal(err)
+			}
+			want := []*RedirectRule{&changed, rules[2], added}
+			mustLoad(t, store, want)
+
+			// 重新打开后内容不变
+			if err := store.Close(); err != nil {
+				t.Fatal(err)
+			}
+			store = openTestStore(t, kind, path)
+			mustLoad(t, store, want)
+
+			if err := store.Delete("a", "missing"); err != nil {
+				t.Fatal(err)
+			}
+			mustLoad(t, store, want[1:])
+
+			if err := store.Replace(rules[:1]); err != nil {
+				t.Fatal(err)
+			}
+			mustLoad(t, store, rules[:1])
+		})
+	}
+}
+
+func TestJSONStoreKeepsInvalidRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	content := `[
+  {"id": "a", "domain": "example.com", "path": "/a", "target": "https://example.net/a", "type": 301},
+  {"id": "bad", "domain": "example.com", "path": "/bad", "target": "not a url", "type": 301}
+]`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := openTestStore(t, StoreJSON, path)
+	added := &RedirectRule{ID: "b", Domain: "example.com", Path: "/b", Target: "https://example.net/b", Type: RedirectType302}
+	if err := store.Put(added); err != nil {
+		t.Fatal(err)
+	}
+
+	// 写入时保留无效规则的原始内容，修正前不会丢失
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"not a url"`) {
+		t.Errorf("invalid rule dropped on write:\n%s", data)
+	}
+	rules, err := NewJSONFileStore(path).Load()
+	var partial *LoadError
+	if !errors.As(err, &partial) || len(partial.Items) != 1 {
+		t.Fatalf("Load error = %v, want one invalid rule", err)
+	}
+	if got := ids(rules); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("loaded %v, want [a b]", got)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "rules.json")
+	src := openTestStore(t, StoreJSON, jsonPath)
+	rules := storeRules()
+	if err := src.Replace(rules); err != nil {
+		t.Fatal(err)
+	}
+
+	// JSON → bolt，目标存储中原有的规则被替换
+	dst := openTestStore(t, StoreBolt, filepath.Join(dir, "rules.db"))
+	if err := dst.Put(&RedirectRule{ID: "stale", Domain: "stale.com", Target: "https://stale.net/", Type: RedirectType302}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := Migrate(src, dst); err != nil || n != len(rules) {
+		t.Fatalf("Migrate = %d, %v", n, err)
+	}
+	mustLoad(t, dst, rules)
+
+	// bolt → JSON 后与原文件内容一致
+	back := openTestStore(t, StoreJSON, filepath.Join(dir, "back.json"))
+	if _, err := Migrate(dst, back); err != nil {
+		t.Fatal(err)
+	}
+	original, _ := os.ReadFile(jsonPath)
+	migrated, _ := os.ReadFile(filepath.Join(dir, "back.json"))
+	if string(original) != string(migrated) {
+		t.Errorf("round trip changed the file:\n%s\nwant:\n%s", migrated, original)
+	}
+}
+
+func TestMigrateSkipsInvalidRules(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "rules.json")
+	content := `[
+  {"id": "a", "domain": "example.com", "path": "/a", "target": "https://example.net/a", "type": 301},
+  {"id": "bad", "domain": "", "target": "https://example.net/", "type": 301}
+]`
+	if err := os.WriteFile(jsonPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dst := openTestStore(t, StoreBolt, filepath.Join(dir, "rules.db"))
+	n, err := Migrate(NewJSONFileStore(jsonPath), dst)
+	var partial *LoadError
+	if !errors.As(err, &partial) || len(partial.Items) != 1 || partial.Items[0].Line != 3 {
+		t.Fatalf("Migrate error = %v, want the rule on line 3 skipped", err)
+	}
+	if n != 1 {
+		t.Errorf("migrated %d rules, want 1", n)
+	}
+
+	// 源文件无法解析时不写入目标存储
+	if err := os.WriteFile(jsonPath, []byte(`{"id": "a"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Migrate(NewJSONFileStore(jsonPath), dst); err == nil || errors.As(err, &partial) {
+		t.Fatalf("Migrate error = %v, want a read failure", err)
+	}
+	rules, err := dst.Load()
+	if err != nil || len(rules) != 1 {
+		t.Errorf("target changed after failed migration: %v, %v", ids(rules), err)
+	}
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError 字段校验错误
+type FieldError struct {
+	Field   string `json:"field"`   // 字段名（JSON 字段名）
+	Message string `json:"message"` // 错误描述
+}
+
+// ValidationErrors 规则校验错误集合
+type ValidationErrors []FieldError
+
+// Error 实现 error 接口
+func (e ValidationErrors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, fe := range e {
+		parts = append(parts, fe.Field+": "+fe.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// add 追加字段错误
+func (e *ValidationErrors) add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+var (
+	domainLabelRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+	ruleIDRe      = regexp.MustCompile(`^[A-Za-z0-9_.\-]{1,128}$`)
+)
+
+// ValidateRule 校验规则结构（加载、创建、更新、导入共用）
+func ValidateRule(rule *RedirectRule) ValidationErrors {
+	var errs ValidationErrors
+
+	if rule.ID != "" && !ruleIDRe.MatchString(rule.ID) {
+		errs.add("id", "ID 只能包含字母、数字、下划线、点和连字符，且不超过 128 个字符")
+	}
+
+	if rule.Domain == "" {
+		errs.add("domain", "域名不能为空")
+	} else if msg := checkDomain(rule.Domain); msg != "" {
+		errs.add("domain", msg)
+	}
+
+	if rule.Path != "" {
+		switch {
+		case !strings.HasPrefix(rule.Path, "/"):
+			errs.add("path", "路径必须以 / 开头")
+		case strings.ContainsAny(rule.Path, "?# \t\r\n"):
+			errs.add("path", "路径不能包含查询参数、片段或空白字符")
+		}
+	}
+
+	if rule.Target == "" {
+		errs.add("target", "目标URL不能为空")
+	} else if msg := checkTarget(rule.Target); msg != "" {
+		errs.add("target", msg)
+	}
+
+	switch rule.Type {
+	case RedirectType301, RedirectType302, RedirectType307, RedirectTypeJS:
+	default:
+		errs.add("type", "跳转类型必须是 301、302、307 或 4（JavaScript）")
+	}
+
+	return errs
+}
+
+// ValidateRuleForSave 校验即将保存的规则，在结构校验基础上要求过期时间晚于当前时间
+func ValidateRuleForSave(rule *RedirectRule) ValidationErrors {
+	errs := ValidateRule(rule)
+	if rule.ExpiresAt != nil && !rule.ExpiresAt.After(time.Now()) {
+		errs.add("expires_at", "过期时间必须晚于当前时间")
+	}
+	return errs
+}
+
+// checkDomain 校验域名（允许带端口），返回错误描述
+func checkDomain(domain string) string {
+	host := domain
+	if idx := strings.LastIndex(domain, ":"); idx != -1 {
+		port, err := strconv.Atoi(domain[idx+1:])
+		if err != nil || port < 1 || port > 65535 {
+			return "端口无效"
+		}
+		host = domain[:idx]
+	}
+
+	if len(host) == 0 || len(host) > 253 {
+		return "域名长度无效"
+	}
+	if host != strings.ToLower(host) {
+		return "域名必须为小写"
+	}
+	for _, label := range strings.Split(host, ".") {
+		if !domainLabelRe.MatchString(label) {
+			return "域名格式无效（不能包含协议、路径或非法字符）"
+		}
+	}
+	return ""
+}
+
+// checkTarget 校验目标URL，返回错误描述
+func checkTarget(target string) string {
+	if strings.ContainsAny(target, " \t\r\n") {
+		return "目标URL不能包含空白字符"
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "目标URL格式无效"
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "目标URL必须以 http:// 或 https:// 开头"
+	}
+	if u.Host == "" {
+		return "目标URL缺少主机名"
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validRule 返回通过校验的规则，由 edit 修改后用于校验测试
+func validRule(edit func(rule *RedirectRule)) *RedirectRule {
+	rule := &RedirectRule{ID: "a", Domain: "example.com", Path: "/a", Target: "https://example.net/a", Type: RedirectType301}
+	if edit != nil {
+		edit(rule)
+	}
+	return rule
+}
+
+// fields 返回校验错误涉及的字段
+func fields(errs ValidationErrors) []string {
+	var out []string
+	for _, fe := range errs {
+		out = append(out, fe.Field)
+	}
+	return out
+}
+
+func TestValidateRule(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		edit   func(rule *RedirectRule)
+		fields []string // 为空表示校验通过
+	}{
+		{"valid", nil, nil},
+		{"no id", func(r *RedirectRule) { r.ID = "" }, nil},
+		{"domain only", func(r *RedirectRule) { r.Path = "" }, nil},
+		{"port", func(r *RedirectRule) { r.Domain = "example.com:8080" }, nil},
+		{"javascript", func(r *RedirectRule) { r.Type = RedirectTypeJS }, nil},
+		{"http target", func(r *RedirectRule) { r.Target = "http://example.net/a?b=c#d" }, nil},
+		{"id characters", func(r *RedirectRule) { r.ID = "a/b" }, []string{"id"}},
+		{"id length", func(r *RedirectRule) { r.ID = strings.Repeat("a", 129) }, []string{"id"}},
+		{"empty domain", func(r *RedirectRule) { r.Domain = "" }, []string{"domain"}},
+		{"domain scheme", func(r *RedirectRule) { r.Domain = "https://example.com" }, []string{"domain"}},
+		{"domain path", func(r *RedirectRule) { r.Domain = "example.com/a" }, []string{"domain"}},
+		{"uppercase domain", func(r *RedirectRule) { r.Domain = "Example.com" }, []string{"domain"}},
+		{"empty label", func(r *RedirectRule) { r.Domain = "example..com" }, []string{"domain"}},
+		{"bad port", func(r *RedirectRule) { r.Domain = "example.com:70000" }, []string{"domain"}},
+		{"relative path", func(r *RedirectRule) { r.Path = "a" }, []string{"path"}},
+		{"path query", func(r *RedirectRule) { r.Path = "/a?b=c" }, []string{"path"}},
+		{"path space", func(r *RedirectRule) { r.Path = "/a b" }, []string{"path"}},
+		{"empty target", func(r *RedirectRule) { r.Target = "" }, []string{"target"}},
+		{"target scheme", func(r *RedirectRule) { r.Target = "javascript:alert(1)" }, []string{"target"}},
+		{"target host", func(r *RedirectRule) { r.Target = "https:///a" }, []string{"target"}},
+		{"target space", func(r *RedirectRule) { r.Target = "https://example.net/a b" }, []string{"target"}},
+		{"type", func(r *RedirectRule) { r.Type = 303 }, []string{"type"}},
+		{"several fields", func(r *RedirectRule) { r.Domain = ""; r.Target = ""; r.Type = 0 }, []string{"domain", "target", "type"}},
+	} {
+		if got := fields(ValidateRule(validRule(tt.edit))); !reflect.DeepEqual(got, tt.fields) {
+			t.Errorf("%s: invalid fields %v, want %v", tt.name, got, tt.fields)
+		}
+	}
+}
+
+func TestValidateRuleForSave(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	// 过期时间只在保存时检查，加载时已过期的规则仍然有效
+	expired := validRule(func(r *RedirectRule) { r.ExpiresAt = &past })
+	if errs := ValidateRule(expired); len(errs) > 0 {
+		t.Errorf("ValidateRule(expired) = %v", errs)
+	}
+	if got := fields(ValidateRuleForSave(expired)); !reflect.DeepEqual(got, []string{"expires_at"}) {
+		t.Errorf("ValidateRuleForSave(expired) fields = %v", got)
+	}
+	if errs := ValidateRuleForSave(validRule(func(r *RedirectRule) { r.ExpiresAt = &future })); len(errs) > 0 {
+		t.Errorf("ValidateRuleForSave(future) = %v", errs)
+	}
+}
+
+func TestLoadReportsLineNumbers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	content := `[
+  {"id": "a", "domain": "example.com", "path": "/a", "target": "https://example.net/a", "type": 301},
+  {"id": "upper", "domain": "Example.com", "target": "https://example.net/", "type": 301},
+  {"id": "a", "domain": "example.com", "path": "/dup", "target": "https://example.net/dup", "type": 301},
+  {
+    "id": "type",
+    "domain": "example.com",
+    "target": "https://example.net/",
+    "type": "301"
+  },
+  {"domain": "example.com", "path": "/b", "target": "https://example.net/b", "type": 302}
+]`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := NewJSONFileStore(path).Load()
+	var partial *LoadError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Load error = %v, want *LoadError", err)
+	}
+	var got []string
+	for _, item := range partial.Items {
+		got = append(got, item.Location())
+	}
+	if want := []string{"index 1 (line 3)", "index 2 (line 4)", "index 3 (line 5)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid rules at %v, want %v", got, want)
+	}
+	if f := fields(partial.Items[0].Fields); !reflect.DeepEqual(f, []string{"domain"}) {
+		t.Errorf("line 3 fields = %v, want [domain]", f)
+	}
+	if msg := partial.Items[1].Message; !strings.Contains(msg, "ID 重复") {
+		t.Errorf("line 4 message = %q, want a duplicate ID", msg)
+	}
+	// 有效规则照常加载，缺少 ID 时按域名和路径生成
+	if got := ids(rules); !reflect.DeepEqual(got, []string{"a", "example_com_b"}) {
+		t.Errorf("loaded %v", got)
+	}
+}
+
+func TestLoadSyntaxErrorLine(t *testing.T) {
+	for _, tt := range []struct {
+		content string
+		want    string
+	}{
+		{"[\n  {\"id\": \"a\"},\n  {\"id\": \"b\",}\n]", "第 3 行"},
+		{"[\n  {\"id\": \"a\"},\n  {\"id\": ", "第 3 行: 文件意外结束"},
+		{`{"id": "a"}`, "必须是规则数组"},
+	} {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := NewJSONFileStore(path).Load()
+		var partial *LoadError
+		if err == nil || errors.As(err, &partial) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want it to contain %q", tt.content, err, tt.want)
+		}
+	}
+}
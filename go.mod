@@ -4,4 +4,8 @@ go 1.21
 
 require (
 	github.com/gorilla/mux v1.8.1
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/crypto v0.31.0
 )
+
+require golang.org/x/sys v0.28.0 // indirect
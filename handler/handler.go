@@ -1,19 +1,28 @@
 package handler
 
 import (
-	"fmt"
+	"html/template"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"mini_jump/analytics"
 	"mini_jump/config"
+	"mini_jump/hits"
 	"mini_jump/logger"
+	"mini_jump/metrics"
 )
 
 // Handler HTTP 请求处理器
 type Handler struct {
-	config *config.Config
-	logger *logger.Logger
+	config  *config.Config
+	logger  *logger.Logger
+	hits    *hits.Counter         // 规则命中计数（为 nil 时不统计）
+	stats   *analytics.Aggregator // 访问统计汇总（为 nil 时不汇总）
+	metrics *metrics.Metrics      // 监控指标（为 nil 时不记录）
+	// countryHeader 携带客户端国家/地区代码的请求头（如 CDN 添加的 CF-IPCountry），为空时不记录
+	countryHeader string
 }
 
 // NewHandler 创建处理器
@@ -24,68 +33,129 @@ func NewHandler(cfg *config.Config, log *logger.Logger) *Handler {
 	}
 }
 
+// SetHits 启用规则命中计数
+func (h *Handler) SetHits(c *hits.Counter) {
+	h.hits = c
+}
+
+// SetAnalytics 启用访问统计汇总
+func (h *Handler) SetAnalytics(a *analytics.Aggregator) {
+	h.stats = a
+}
+
+// SetMetrics 启用监控指标
+func (h *Handler) SetMetrics(m *metrics.Metrics) {
+	h.metrics = m
+}
+
+// SetCountryHeader 设置携带客户端国家/地区代码的请求头
+func (h *Handler) SetCountryHeader(name string) {
+	h.countryHeader = name
+}
+
+// Result 跳转匹配结果
+type Result struct {
+	Domain     string                  `json:"domain"`               // 请求域名
+	Path       string                  `json:"path"`                 // 请求路径
+	ClientIP   string                  `json:"client_ip"`            // 客户端 IP（与访问日志一致）
+	Rule       *config.RedirectRule    `json:"rule"`                 // 命中的规则（未命中为空）
+	Target     string                  `json:"target,omitempty"`     // 最终跳转目标
+	StatusCode int                     `json:"status_code"`          // 响应状态码
+	Candidates []config.MatchCandidate `json:"candidates,omitempty"` // 考察过的候选规则（仅调试模式）
+}
+
+// Evaluate 对请求执行匹配流程，不产生任何副作用（不写响应、不记录日志）
+// trace 为 true 时返回所有候选规则及未命中原因
+func (h *Handler) Evaluate(r *http.Request, trace bool) *Result {
+	result := &Result{
+		Domain:   r.Host,
+		Path:     r.URL.Path,
+		ClientIP: h.getClientIP(r),
+	}
+
+	rule, candidates := h.config.Match(result.Domain, result.Path, trace)
+	result.Candidates = candidates
+	if rule == nil {
+		result.StatusCode = http.StatusNotFound
+		return result
+	}
+
+	result.Rule = rule
+	result.Target = rule.Target
+	result.StatusCode = rule.Type.StatusCode()
+	return result
+}
+
 // HandleRedirect 处理跳转请求
 func (h *Handler) HandleRedirect(w http.ResponseWriter, r *http.Request) {
-	domain := r.Host
-	path := r.URL.Path
-
 	// 查找匹配的规则
-	rule, found := h.config.FindRule(domain, path)
-	if !found {
+	start := time.Now()
+	result := h.Evaluate(r, false)
+	h.metrics.ObserveMatch(time.Since(start), result.Rule != nil)
+	if result.Rule == nil {
+		h.metrics.Miss()
 		http.NotFound(w, r)
 		return
 	}
+	rule := result.Rule
 
 	// 记录访问日志
 	accessLog := &logger.AccessLog{
 		Timestamp:    time.Now(),
-		IP:           h.getClientIP(r),
+		IP:           result.ClientIP,
 		UserAgent:    r.UserAgent(),
 		Method:       r.Method,
-		Domain:       domain,
-		Path:         path,
-		Target:       rule.Target,
+		Domain:       result.Domain,
+		Path:         result.Path,
+		Target:       result.Target,
 		RedirectType: int(rule.Type),
-		StatusCode:   int(rule.Type),
+		StatusCode:   result.StatusCode,
+		RuleID:       rule.ID,
+		Referer:      r.Referer(),
+	}
+	if h.countryHeader != "" {
+		accessLog.Country = strings.ToUpper(strings.TrimSpace(r.Header.Get(h.countryHeader)))
 	}
 
 	// 执行跳转
-	switch rule.Type {
-	case config.RedirectType301:
-		http.Redirect(w, r, rule.Target, http.StatusMovedPermanently)
-		accessLog.StatusCode = http.StatusMovedPermanently
-	case config.RedirectType302:
-		http.Redirect(w, r, rule.Target, http.StatusFound)
-		accessLog.StatusCode = http.StatusFound
-	case config.RedirectType307:
-		http.Redirect(w, r, rule.Target, http.StatusTemporaryRedirect)
-		accessLog.StatusCode = http.StatusTemporaryRedirect
-	case config.RedirectTypeJS:
-		h.writeJavaScriptRedirect(w, rule.Target)
-		accessLog.StatusCode = http.StatusOK
-	default:
-		http.Redirect(w, r, rule.Target, http.StatusFound)
-		accessLog.StatusCode = http.StatusFound
+	if rule.Type == config.RedirectTypeJS {
+		h.writeJavaScriptRedirect(w, result.Target)
+	} else {
+		http.Redirect(w, r, result.Target, result.StatusCode)
 	}
 
-	// 异步记录日志
-	go h.logger.Log(accessLog)
+	h.metrics.Redirect(rule.Domain, typeLabel(rule.Type), result.StatusCode)
+
+	// 命中计数不加锁；日志进入有界队列由写入 goroutine 批量写入；访问统计只更新内存中的汇总
+	h.hits.Record(rule.ID, accessLog.Timestamp)
+	h.logger.Log(accessLog)
+	h.stats.Add(accessLog)
 }
 
-// writeJavaScriptRedirect 写入 JavaScript 跳转
-func (h *Handler) writeJavaScriptRedirect(w http.ResponseWriter, target string) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	html := fmt.Sprintf(`<!DOCTYPE html>
+// typeLabel 返回监控指标中的跳转类型（301、302、307、js）
+func typeLabel(t config.RedirectType) string {
+	if t == config.RedirectTypeJS {
+		return "js"
+	}
+	return strconv.Itoa(int(t))
+}
+
+// jsRedirectPage JavaScript 跳转页面（目标地址按上下文转义，防止注入）
+var jsRedirectPage = template.Must(template.New("redirect").Parse(`<!DOCTYPE html>
 <html>
 <head>
 <meta charset="utf-8">
-<meta http-equiv="refresh" content="0;url=%s">
-<script>window.location.href="%s";</script>
+<meta http-equiv="refresh" content="0;url={{.}}">
+<script>window.location.href={{.}};</script>
 </head>
-<body>正在跳转到 %s...</body>
-</html>`, target, target, target)
-	w.Write([]byte(html))
+<body>正在跳转到 {{.}}...</body>
+</html>`))
+
+// writeJavaScriptRedirect 写入 JavaScript 跳转
+func (h *Handler) writeJavaScriptRedirect(w http.ResponseWriter, target string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	jsRedirectPage.Execute(w, target)
 }
 
 // getClientIP 获取客户端 IP
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJavaScriptRedirectEscapesTarget(t *testing.T) {
+	h := &Handler{}
+	for _, tt := range []struct {
+		target string
+		want   []string // 响应中应出现的转义结果
+	}{
+		{
+			`https://example.net/a?b=1&c=2`,
+			[]string{`url=https://example.net/a?b=1&amp;c=2"`, `window.location.href="https://example.net/a?b=1\u0026c=2";`},
+		},
+		{
+			`https://example.net/"</script><script>alert(1)</script>`,
+			[]string{`href="https://example.net/\"\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e";`, `url=https://example.net/&#34;&lt;/script&gt;`},
+		},
+		{
+			`https://example.net/';alert(1);//`,
+			// 目标地址整体作为字符串字面量，单引号不会结束字符串
+			[]string{`href="https://example.net/';alert(1);//";`, `url=https://example.net/&#39;;alert(1);//"`},
+		},
+	} {
+		rec := httptest.NewRecorder()
+		h.writeJavaScriptRedirect(rec, tt.target)
+		body := rec.Body.String()
+		if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		// 页面中只有模板自带的一对 script 标签
+		if n := strings.Count(body, "<script>"); n != 1 {
+			t.Errorf("%s: %d script tags in\n%s", tt.target, n, body)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s: response does not contain %s:\n%s", tt.target, want, body)
+			}
+		}
+	}
+}
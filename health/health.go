@@ -0,0 +1,120 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"mini_jump/config"
+)
+
+// 探针路径
+const (
+	PathHealthz = "/healthz"
+	PathReadyz  = "/readyz"
+	PathVersion = "/version"
+)
+
+// checkOK 检查通过时的状态
+const checkOK = "ok"
+
+// check 一项就绪检查
+type check struct {
+	name string
+	fn   func() error
+}
+
+// Checker 存活、就绪检查
+type Checker struct {
+	mu      sync.RWMutex
+	checks  []check
+	started time.Time
+}
+
+// NewChecker 创建检查器
+func NewChecker() *Checker {
+	return &Checker{started: time.Now()}
+}
+
+// Add 添加一项就绪检查，fn 返回错误时服务未就绪
+func (c *Checker) Add(name string, fn func() error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// RulesCheck 规则检查：正在加载、从未成功加载或最近一次加载失败时未就绪
+func RulesCheck(cfg *config.Config) func() error {
+	return func() error {
+		status := cfg.LoadStatus()
+		switch {
+		case status.Loading:
+			return errors.New("rules are loading")
+		case status.Err != nil:
+			return fmt.Errorf("last reload failed: %v", status.Err)
+		case status.LoadedAt == nil:
+			return errors.New("rules have not been loaded")
+		}
+		return nil
+	}
+}
+
+// readiness 就绪检查结果
+type readiness struct {
+	Status string            `json:"status"` // ready 或 not ready
+	Checks map[string]string `json:"checks"` // 检查项 -> ok 或失败原因
+}
+
+// RegisterRoutes 注册探针路由
+// 探针无需认证，需在跳转路由之前注册，避免被跳转路由覆盖
+func (c *Checker) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc(PathHealthz, c.Healthz).Methods("GET", "HEAD")
+	r.HandleFunc(PathReadyz, c.Readyz).Methods("GET", "HEAD")
+	r.HandleFunc(PathVersion, c.Version).Methods("GET", "HEAD")
+}
+
+// Healthz 存活检查：进程能处理请求即返回 200
+func (c *Checker) Healthz(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "ok",
+		"uptime": time.Since(c.started).Round(time.Second).String(),
+	})
+}
+
+// Readyz 就绪检查：全部检查项通过时返回 200，否则返回 503 及失败原因
+func (c *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	checks := append([]check(nil), c.checks...)
+	c.mu.RUnlock()
+
+	result := readiness{Status: "ready", Checks: make(map[string]string, len(checks))}
+	status := http.StatusOK
+	for _, ch := range checks {
+		if err := ch.fn(); err != nil {
+			result.Checks[ch.name] = err.Error()
+			result.Status = "not ready"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		result.Checks[ch.name] = checkOK
+	}
+	respondJSON(w, status, result)
+}
+
+// Version 返回构建信息
+func (c *Checker) Version(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, GetBuildInfo())
+}
+
+// respondJSON 返回 JSON 响应，探针结果不允许缓存
+func respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
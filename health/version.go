@@ -0,0 +1,45 @@
+package health
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// 构建信息，由构建脚本通过 -ldflags 注入：
+//
+//	go build -ldflags "-X mini_jump/health.Version=1.2.0 -X mini_jump/health.Commit=abc1234 -X mini_jump/health.BuildTime=2024-01-01T00:00:00Z"
+var (
+	Version   = "dev" // 版本号
+	Commit    = ""    // Git 提交
+	BuildTime = ""    // 构建时间（RFC 3339）
+)
+
+// BuildInfo 构建信息
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"` // GOOS/GOARCH
+}
+
+// GetBuildInfo 返回构建信息，未注入提交时尝试使用 go build 记录的 VCS 信息
+func GetBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if info.Commit == "" {
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range bi.Settings {
+				if setting.Key == "vcs.revision" {
+					info.Commit = setting.Value
+				}
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,236 @@
+package hits
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Days 按天统计保留的天数
+const Days = 30
+
+// dateLayout 按天统计的日期格式（服务器本地时区）
+const dateLayout = "2006-01-02"
+
+// Summary 规则命中概况
+type Summary struct {
+	Total     int64      `json:"total"`                 // 累计命中次数
+	LastHitAt *time.Time `json:"last_hit_at,omitempty"` // 最后命中时间（从未命中时省略）
+}
+
+// DailyHits 某一天的命中次数
+type DailyHits struct {
+	Date string `json:"date"` // 日期，格式 2006-01-02（服务器本地时区）
+	Hits int64  `json:"hits"`
+}
+
+// Stats 规则命中统计
+type Stats struct {
+	RuleID string `json:"rule_id"`
+	Summary
+	Daily []DailyHits `json:"daily"` // 最近 Days 天每天的命中次数，按日期升序，包括今天
+}
+
+// ruleHits 单条规则的计数器，全部字段通过原子操作更新
+type ruleHits struct {
+	total   atomic.Int64
+	lastHit atomic.Int64 // 最后命中时间（UnixNano，0 表示从未命中）
+	// 按天计数的环形缓冲，下标为日期序号对 Days 取模
+	// 每个槽高 32 位为日期序号，低 32 位为当天命中次数，日期变化时整体替换
+	daily [Days]atomic.Uint64
+}
+
+// Counter 规则命中计数器
+// 记录命中不加锁，定期保存到文件，重启后继续累计
+type Counter struct {
+	path   string
+	rules  sync.Map   // 规则 ID -> *ruleHits
+	saveMu sync.Mutex // 串行化保存
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// Open 从 path 加载命中统计（文件不存在时从零开始），并每隔 interval 保存一次
+// interval 不大于 0 时只在 Close 时保存
+func Open(path string, interval time.Duration) (*Counter, error) {
+	c := &Counter{path: path, done: make(chan struct{})}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		c.wg.Add(1)
+		go c.savePeriodically(interval)
+	}
+	return c, nil
+}
+
+// Record 记录一次命中（为 nil 时忽略）
+func (c *Counter) Record(ruleID string, at time.Time) {
+	if c == nil {
+		return
+	}
+	c.get(ruleID).record(at)
+}
+
+// Summary 返回规则的命中概况（为 nil 或从未命中时为零值）
+func (c *Counter) Summary(ruleID string) Summary {
+	if c == nil {
+		return Summary{}
+	}
+	h, ok := c.lookup(ruleID)
+	if !ok {
+		return Summary{}
+	}
+	return h.summary()
+}
+
+// RuleHits 返回规则的累计命中次数和最后命中时间，用于规则列表排序
+func (c *Counter) RuleHits(ruleID string) (int64, time.Time) {
+	s := c.Summary(ruleID)
+	if s.LastHitAt == nil {
+		return s.Total, time.Time{}
+	}
+	return s.Total, *s.LastHitAt
+}
+
+// Stats 返回规则的命中统计，包括最近 Days 天每天的命中次数
+func (c *Counter) Stats(ruleID string, now time.Time) *Stats {
+	stats := &Stats{RuleID: ruleID, Daily: make([]DailyHits, 0, Days)}
+	h, ok := c.lookup(ruleID)
+	if ok {
+		stats.Summary = h.summary()
+	}
+	today := dayNumber(now)
+	for day := today - Days + 1; day <= today; day++ {
+		entry := DailyHits{Date: dayDate(day)}
+		if ok {
+			entry.Hits = h.dayHits(day)
+		}
+		stats.Daily = append(stats.Daily, entry)
+	}
+	return stats
+}
+
+// Close 停止定期保存并保存最后一次
+func (c *Counter) Close() error {
+	if c == nil {
+		return nil
+	}
+	close(c.done)
+	c.wg.Wait()
+	return c.Save()
+}
+
+// get 获取规则的计数器，不存在时创建
+func (c *Counter) get(ruleID string) *ruleHits {
+	if h, ok := c.rules.Load(ruleID); ok {
+		return h.(*ruleHits)
+	}
+	h, _ := c.rules.LoadOrStore(ruleID, &ruleHits{})
+	return h.(*ruleHits)
+}
+
+// lookup 查找规则的计数器
+func (c *Counter) lookup(ruleID string) (*ruleHits, bool) {
+	if c == nil {
+		return nil, false
+	}
+	h, ok := c.rules.Load(ruleID)
+	if !ok {
+		return nil, false
+	}
+	return h.(*ruleHits), true
+}
+
+// savePeriodically 定期保存命中统计
+func (c *Counter) savePeriodically(interval time.Duration) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Save(); err != nil {
+				log.Printf("Failed to save hit counters: %v\n", err)
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// record 记录一次命中
+func (h *ruleHits) record(at time.Time) {
+	h.total.Add(1)
+
+	ns := at.UnixNano()
+	for {
+		last := h.lastHit.Load()
+		if last >= ns || h.lastHit.CompareAndSwap(last, ns) {
+			break
+		}
+	}
+
+	day := dayNumber(at)
+	slot := &h.daily[day%Days]
+	for {
+		old := slot.Load()
+		next := uint64(day)<<32 | 1
+		if old>>32 == uint64(day) {
+			next = old + 1
+		}
+		if slot.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// add 累加持久化的计数（加载时使用）
+func (h *ruleHits) add(day int64, hits int64) {
+	slot := &h.daily[day%Days]
+	old := slot.Load()
+	if old>>32 == uint64(day) {
+		hits += int64(old & 0xffffffff)
+	}
+	slot.Store(uint64(day)<<32 | uint64(hits)&0xffffffff)
+}
+
+// summary 返回命中概况
+func (h *ruleHits) summary() Summary {
+	s := Summary{Total: h.total.Load()}
+	if ns := h.lastHit.Load(); ns != 0 {
+		t := time.Unix(0, ns)
+		s.LastHitAt = &t
+	}
+	return s
+}
+
+// dayHits 返回指定日期的命中次数（超出保留天数时为 0）
+func (h *ruleHits) dayHits(day int64) int64 {
+	v := h.daily[day%Days].Load()
+	if v>>32 != uint64(day) {
+		return 0
+	}
+	return int64(v & 0xffffffff)
+}
+
+// dayNumber 返回时间在服务器本地时区的日期序号（自 1970-01-01 起的天数）
+func dayNumber(t time.Time) int64 {
+	y, m, d := t.In(time.Local).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
+
+// dayDate 将日期序号格式化为日期
+func dayDate(day int64) string {
+	return time.Unix(day*86400, 0).UTC().Format(dateLayout)
+}
+
+// parseDay 解析日期为日期序号
+func parseDay(date string) (int64, bool) {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return 0, false
+	}
+	return t.Unix() / 86400, true
+}
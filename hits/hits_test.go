@@ -0,0 +1,128 @@
+package hits
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// openTestCounter 在临时目录中打开只在 Close 时保存的计数器
+func openTestCounter(t *testing.T, path string) *Counter {
+	t.Helper()
+	c, err := Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// dailyHits 返回 Stats 中各日期的命中次数（省略为 0 的日期）
+func dailyHits(stats *Stats) map[string]int64 {
+	out := make(map[string]int64)
+	for _, d := range stats.Daily {
+		if d.Hits != 0 {
+			out[d.Date] = d.Hits
+		}
+	}
+	return out
+}
+
+func TestRecordAndStats(t *testing.T) {
+	c := openTestCounter(t, filepath.Join(t.TempDir(), "hits.json"))
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	c.Record("a", now)
+	c.Record("a", now)
+	c.Record("a", yesterday)
+	// 乱序到达的较早命中不改变最后命中时间
+	c.Record("a", now.Add(-time.Minute))
+
+	s := c.Summary("a")
+	if s.Total != 4 || s.LastHitAt == nil || !s.LastHitAt.Equal(now) {
+		t.Errorf("summary = %+v", s)
+	}
+	stats := c.Stats("a", now)
+	if len(stats.Daily) != Days || stats.Daily[Days-1].Date != now.Format(dateLayout) {
+		t.Fatalf("daily = %d entries ending %s", len(stats.Daily), stats.Daily[len(stats.Daily)-1].Date)
+	}
+	want := map[string]int64{now.Format(dateLayout): 3, yesterday.Format(dateLayout): 1}
+	if got := dailyHits(stats); !reflect.DeepEqual(got, want) {
+		t.Errorf("daily hits = %v, want %v", got, want)
+	}
+
+	// 从未命中的规则和 nil 计数器返回零值
+	if s := c.Summary("missing"); s.Total != 0 || s.LastHitAt != nil {
+		t.Errorf("summary of missing rule = %+v", s)
+	}
+	if got := dailyHits(c.Stats("missing", now)); len(got) != 0 {
+		t.Errorf("daily hits of missing rule = %v", got)
+	}
+	var nilCounter *Counter
+	nilCounter.Record("a", now)
+	if total, last := nilCounter.RuleHits("a"); total != 0 || !last.IsZero() {
+		t.Errorf("nil counter = %d, %v", total, last)
+	}
+}
+
+func TestDailyRingRollsOver(t *testing.T) {
+	c := openTestCounter(t, filepath.Join(t.TempDir(), "hits.json"))
+	now := time.Now()
+	old := now.AddDate(0, 0, -Days)
+	// old 与 now 落在环形缓冲的同一个槽中
+	c.Record("a", old)
+	c.Record("a", old)
+	if got := c.Stats("a", old).Daily[Days-1].Hits; got != 2 {
+		t.Fatalf("hits on %s = %d, want 2", old.Format(dateLayout), got)
+	}
+
+	// 新的一天整体替换该槽，不累加旧日期的计数
+	c.Record("a", now)
+	stats := c.Stats("a", now)
+	if got := dailyHits(stats); len(got) != 1 || got[now.Format(dateLayout)] != 1 {
+		t.Errorf("daily hits = %v, want only today = 1", got)
+	}
+	if stats.Total != 3 {
+		t.Errorf("total = %d, want 3", stats.Total)
+	}
+	// 查询旧日期时对应的槽已属于新日期
+	if got := c.Stats("a", old).Daily[Days-1].Hits; got != 0 {
+		t.Errorf("hits on %s after rollover = %d, want 0", old.Format(dateLayout), got)
+	}
+}
+
+func TestCounterPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hits.json")
+	c := openTestCounter(t, path)
+	now := time.Now()
+	c.Record("a", now)
+	c.Record("a", now.AddDate(0, 0, -2))
+	c.Record("b", now.AddDate(0, 0, -Days-5)) // 超出保留天数，只计入总数
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开后继续累计
+	c = openTestCounter(t, path)
+	defer c.Close()
+	c.Record("a", now)
+	if s := c.Summary("a"); s.Total != 3 || !s.LastHitAt.Equal(now) {
+		t.Errorf("summary of a = %+v", s)
+	}
+	want := map[string]int64{now.Format(dateLayout): 2, now.AddDate(0, 0, -2).Format(dateLayout): 1}
+	if got := dailyHits(c.Stats("a", now)); !reflect.DeepEqual(got, want) {
+		t.Errorf("daily hits of a = %v, want %v", got, want)
+	}
+	if stats := c.Stats("b", now); stats.Total != 1 || len(dailyHits(stats)) != 0 {
+		t.Errorf("stats of b = total %d, daily %v", stats.Total, dailyHits(stats))
+	}
+
+	// 文件损坏时拒绝打开，避免覆盖已有的统计
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, 0); err == nil {
+		t.Error("Open succeeded with a corrupt file")
+	}
+}
@@ -0,0 +1,81 @@
+package hits
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"mini_jump/atomicfile"
+)
+
+// savedHits 文件中单条规则的命中统计
+type savedHits struct {
+	Total     int64            `json:"total"`
+	LastHitAt *time.Time       `json:"last_hit_at,omitempty"`
+	Daily     map[string]int64 `json:"daily,omitempty"` // 日期 -> 命中次数，只保存最近 Days 天
+}
+
+// load 从文件加载命中统计
+func (c *Counter) load() error {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved map[string]*savedHits
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("invalid hit counter file %s: %w", c.path, err)
+	}
+
+	oldest := dayNumber(time.Now()) - Days + 1
+	for id, s := range saved {
+		h := c.get(id)
+		h.total.Store(s.Total)
+		if s.LastHitAt != nil {
+			h.lastHit.Store(s.LastHitAt.UnixNano())
+		}
+		for date, n := range s.Daily {
+			if day, ok := parseDay(date); ok && day >= oldest {
+				h.add(day, n)
+			}
+		}
+	}
+	return nil
+}
+
+// Save 将命中统计写入文件（先写临时文件再替换，避免写坏）
+func (c *Counter) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	oldest := dayNumber(time.Now()) - Days + 1
+	saved := make(map[string]*savedHits)
+	c.rules.Range(func(key, value interface{}) bool {
+		h := value.(*ruleHits)
+		s := &savedHits{Total: h.total.Load(), LastHitAt: h.summary().LastHitAt}
+		for i := range h.daily {
+			v := h.daily[i].Load()
+			if day := int64(v >> 32); v != 0 && day >= oldest {
+				if s.Daily == nil {
+					s.Daily = make(map[string]int64)
+				}
+				s.Daily[dayDate(day)] = int64(v & 0xffffffff)
+			}
+		}
+		saved[key.(string)] = s
+		return true
+	})
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteData(c.path, data)
+}
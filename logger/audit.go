@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// 审计结果
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// AuditLog 管理操作审计日志
+type AuditLog struct {
+	Timestamp    time.Time       `json:"timestamp"`
+	RequestID    string          `json:"request_id"`
+	Actor        string          `json:"actor"`                   // 操作者（用户名或 Token 名称，未启用认证时为空）
+	AuthMethod   string          `json:"auth_method,omitempty"`   // 认证方式
+	IP           string          `json:"ip"`                      // 连接的来源地址
+	ForwardedFor string          `json:"forwarded_for,omitempty"` // X-Forwarded-For 请求头（可由客户端伪造，仅供参考）
+	Action       string          `json:"action"`                  // 操作类型：create、update、delete、reload、save、import、login 等
+	RuleID       string          `json:"rule_id,omitempty"`       // 操作的规则 ID
+	Before       json.RawMessage `json:"before,omitempty"`        // 修改前的内容
+	After        json.RawMessage `json:"after,omitempty"`         // 修改后的内容
+	Changes      []string        `json:"changes,omitempty"`       // 发生变化的字段
+	StatusCode   int             `json:"status_code"`
+	Outcome      string          `json:"outcome"`         // success 或 failure
+	Error        string          `json:"error,omitempty"` // 失败原因
+}
+
+// AuditFilter 审计日志查询条件（零值表示不限制）
+type AuditFilter struct {
+	Since  time.Time
+	Until  time.Time
+	Actor  string
+	Action string
+	RuleID string
+	Limit  int // 最多返回条数，按时间倒序
+}
+
+// match 判断审计日志是否满足查询条件
+func (f *AuditFilter) match(entry *AuditLog) bool {
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Timestamp.After(f.Until) {
+		return false
+	}
+	if f.Actor != "" && entry.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && entry.Action != f.Action {
+		return false
+	}
+	if f.RuleID != "" && entry.RuleID != f.RuleID {
+		return false
+	}
+	return true
+}
+
+// QueryAudit 从日志文件及其轮转文件（包括已压缩的）中查询审计日志，按时间倒序返回
+// 只识别内置轮转生成的文件，外部 logrotate 移走的文件不在查询范围内
+func (l *Logger) QueryAudit(filter AuditFilter) ([]*AuditLog, error) {
+	q, f := l.fileSink()
+	if f == nil {
+		return nil, errors.New("audit log has no log file")
+	}
+	var entries []*AuditLog
+	// 在写入 goroutine 中读取，保证不会读到写了一半的行，读取期间也不会轮转
+	err := q.do(func() error {
+		if err := q.flush(); err != nil {
+			return err
+		}
+		var err error
+		entries, err = readAuditFiles(f.file, filter)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 倒序，最新的在前
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// readAuditFiles 从当前文件开始由新到旧读取轮转文件，按时间顺序返回满足条件的审计日志
+// 已取满 Limit 条，或轮转文件早于 Since 时不再读取更早的文件
+func readAuditFiles(f *rotatingFile, filter AuditFilter) ([]*AuditLog, error) {
+	entries, err := readAudit(f.path, filter)
+	if err != nil {
+		return nil, err
+	}
+	backups, err := f.backups()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range backups {
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+		// 轮转时间之后的日志都在更新的文件中
+		if !filter.Since.IsZero() && b.at.Before(filter.Since) {
+			break
+		}
+		older, err := readAudit(b.path, filter)
+		if os.IsNotExist(err) && !strings.HasSuffix(b.path, compressSuffix) {
+			// 列出文件后后台压缩已完成
+			older, err = readAudit(b.path+compressSuffix, filter)
+		}
+		if os.IsNotExist(err) {
+			// 已被清理
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(older, entries...)
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+// readAudit 按时间顺序读取单个文件中满足条件的审计日志，超过 Limit 时保留最新的
+func readAudit(path string, filter AuditFilter) ([]*AuditLog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, compressSuffix) {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	var entries []*AuditLog
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry AuditLog
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if filter.match(&entry) {
+			entries = append(entries, &entry)
+			if filter.Limit > 0 && len(entries) > filter.Limit {
+				entries = entries[1:]
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
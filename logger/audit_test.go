@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestQueryAuditReadsRotatedFiles(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		t.Run("compress="+strconv.FormatBool(compress), func(t *testing.T) {
+			l, err := NewLogger(filepath.Join(t.TempDir(), "audit.log"), 1, 180)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			// 每个文件约容纳两条记录
+			l.SetRotation(RotateOptions{MaxSize: 400, Compress: compress})
+
+			base := time.Now().Add(-time.Hour).Truncate(time.Second)
+			const n = 10
+			for i := 0; i < n; i++ {
+				entry := &AuditLog{Timestamp: base.Add(time.Duration(i) * time.Minute), Actor: "admin", Action: "update", RuleID: strconv.Itoa(i), Outcome: OutcomeSuccess}
+				if i%3 == 0 {
+					entry.Actor = "editor"
+				}
+				if err := l.LogSync(entry); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, f := l.fileSink()
+			if backups, _ := f.file.backups(); len(backups) < 3 {
+				t.Fatalf("%d rotated files, want several", len(backups))
+			}
+
+			for _, tt := range []struct {
+				name   string
+				filter AuditFilter
+				want   []string
+			}{
+				{"all", AuditFilter{}, []string{"9", "8", "7", "6", "5", "4", "3", "2", "1", "0"}},
+				{"limit", AuditFilter{Limit: 3}, []string{"9", "8", "7"}},
+				{"limit across files", AuditFilter{Actor: "editor", Limit: 3}, []string{"9", "6", "3"}},
+				{"since", AuditFilter{Since: base.Add(7 * time.Minute)}, []string{"9", "8", "7"}},
+				{"until", AuditFilter{Until: base.Add(2 * time.Minute)}, []string{"2", "1", "0"}},
+				{"rule", AuditFilter{RuleID: "0"}, []string{"0"}},
+			} {
+				entries, err := l.QueryAudit(tt.filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []string
+				for _, entry := range entries {
+					got = append(got, entry.RuleID)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				}
+			}
+		})
+	}
+}
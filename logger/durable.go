@@ -0,0 +1,230 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync/atomic"
+)
+
+// maxPendingBytes 写入失败时内存中最多保留的字节数，超过后转存到溢出文件（未配置时丢弃）
+const maxPendingBytes = 16 * 1024 * 1024
+
+// replayChunkSize 补写溢出文件时每次读取的字节数
+const replayChunkSize = 64 * 1024
+
+// SyncPolicy 同步到磁盘（fsync）的策略
+type SyncPolicy int32
+
+const (
+	SyncNone     SyncPolicy = iota // 不主动同步，由操作系统决定写回时机
+	SyncFlush                      // 每次写入文件后同步
+	SyncInterval                   // 按固定间隔同步
+)
+
+// String 返回策略名称
+func (p SyncPolicy) String() string {
+	switch p {
+	case SyncFlush:
+		return "flush"
+	case SyncInterval:
+		return "interval"
+	default:
+		return "none"
+	}
+}
+
+// ParseSyncPolicy 解析同步策略：none、flush、interval
+func ParseSyncPolicy(name string) (SyncPolicy, error) {
+	for _, p := range []SyncPolicy{SyncNone, SyncFlush, SyncInterval} {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return SyncNone, fmt.Errorf("invalid log fsync policy %q (none, flush, interval)", name)
+}
+
+// retryWriter 位于写入缓冲和日志文件之间，写入失败的内容不会丢失：
+// 先保留在内存中，下次写入时按原顺序补写；超过 maxPendingBytes 后转存到溢出文件，恢复后再补写回日志文件
+// 对 bufio.Writer 总是报告写入成功，避免其在出错后拒绝后续写入；错误通过 err 字段获取
+// 只在 FileSink 的写入 goroutine 中调用
+type retryWriter struct {
+	file      *rotatingFile
+	spillPath string       // 溢出文件路径（为空时超出部分丢弃）
+	spilled   int64        // 溢出文件中尚未补写的字节数
+	offset    int64        // 溢出文件中已补写的字节数
+	pending   []byte       // 等待补写的内容
+	size      atomic.Int64 // 等待补写的总字节数（内存和溢出文件），供监控读取
+	err       error        // 最近一次写入的错误
+	failing   bool         // 处于写入失败状态（用于只在状态变化时输出日志）
+	onDrop    func(lines int64)
+}
+
+// Write 先补写之前失败的内容，再写入 p；失败的部分保留下来稍后重试
+func (w *retryWriter) Write(p []byte) (int, error) {
+	if !w.retry() {
+		w.hold(p)
+		return len(p), nil
+	}
+	n, err := w.file.Write(p)
+	if err != nil {
+		w.fail(err)
+		w.hold(p[n:])
+	}
+	return len(p), nil
+}
+
+// hasPending 是否有等待补写的内容
+func (w *retryWriter) hasPending() bool {
+	return len(w.pending) > 0 || w.spilled > 0
+}
+
+// retry 按顺序补写溢出文件和内存中的内容，全部写入成功时返回 true
+func (w *retryWriter) retry() bool {
+	defer w.updateSize()
+	if !w.hasPending() {
+		return true
+	}
+	if w.spilled > 0 {
+		if err := w.replaySpill(); err != nil {
+			w.fail(err)
+			return false
+		}
+	}
+	if len(w.pending) > 0 {
+		// 补写不检查轮转，保证之前写了一半的行在同一个文件中写完
+		n, err := w.file.writeRaw(w.pending)
+		w.pending = w.pending[n:]
+		if err != nil {
+			w.fail(err)
+			return false
+		}
+		w.pending = nil
+	}
+	if w.failing {
+		log.Printf("Log %s is writable again, pending entries have been written\n", w.file.path)
+		w.failing = false
+	}
+	w.err = nil
+	return true
+}
+
+// hold 保留写入失败的内容，超过上限时转存到溢出文件
+func (w *retryWriter) hold(p []byte) {
+	defer w.updateSize()
+	w.pending = append(w.pending, p...)
+	if len(w.pending) > maxPendingBytes {
+		w.spill()
+	}
+}
+
+// spill 将内存中等待补写的内容转存到溢出文件，未配置或转存失败时丢弃并计入丢弃条数
+func (w *retryWriter) spill() {
+	defer w.updateSize()
+	if len(w.pending) == 0 {
+		return
+	}
+	if w.spillPath != "" {
+		err := appendFile(w.spillPath, w.pending)
+		if err == nil {
+			w.spilled += int64(len(w.pending))
+			w.pending = nil
+			return
+		}
+		// 去掉写了一半的内容，保证溢出文件中只有完整记录的内容
+		os.Truncate(w.spillPath, w.offset+w.spilled)
+		log.Printf("Failed to spill log %s to %s: %v\n", w.file.path, w.spillPath, err)
+	}
+	lines := int64(bytes.Count(w.pending, []byte{'\n'}))
+	log.Printf("Dropped %d log entries of %s that could not be written\n", lines, w.file.path)
+	w.onDrop(lines)
+	w.pending = nil
+}
+
+// replaySpill 将溢出文件中尚未补写的内容写回日志文件，全部写回后清空溢出文件
+func (w *retryWriter) replaySpill() error {
+	file, err := os.Open(w.spillPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Seek(w.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	buf := make([]byte, replayChunkSize)
+	for w.spilled > 0 {
+		n, err := file.Read(buf)
+		if n > 0 {
+			written, werr := w.file.writeRaw(buf[:n])
+			w.offset += int64(written)
+			w.spilled -= int64(written)
+			if werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	w.spilled = 0
+	w.offset = 0
+	return os.Truncate(w.spillPath, 0)
+}
+
+// fail 记录写入错误，进入失败状态时输出一次日志
+func (w *retryWriter) fail(err error) {
+	w.err = err
+	if !w.failing {
+		log.Printf("Failed to write log %s, entries will be retried: %v\n", w.file.path, err)
+		w.failing = true
+	}
+}
+
+// updateSize 更新等待补写的字节数
+func (w *retryWriter) updateSize() {
+	w.size.Store(int64(len(w.pending)) + w.spilled)
+}
+
+// setSpill 设置溢出文件，文件中已有的内容（上次未补写完）会在下次写入时补写
+func (w *retryWriter) setSpill(path string) error {
+	w.spillPath = path
+	w.spilled = 0
+	w.offset = 0
+	if path == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	w.spilled = info.Size()
+	w.updateSize()
+	return nil
+}
+
+// appendFile 追加写入文件并同步到磁盘
+func appendFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,270 @@
+package logger
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// breakFile 关闭日志文件，之后的写入全部失败，直到 repairFile 重新打开
+func breakFile(f *FileSink) {
+	f.file.file.Close()
+}
+
+// repairFile 重新打开日志文件，模拟磁盘恢复可写
+func repairFile(t *testing.T, f *FileSink) {
+	t.Helper()
+	if err := f.file.open(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeLines 写入 n 条日志（编号从 first 开始，pad 为每条附加的填充长度），返回写入的内容
+func writeLines(f *FileSink, first, n, pad int) []string {
+	var lines []string
+	for i := first; i < first+n; i++ {
+		line := strconv.Itoa(i) + strings.Repeat("x", pad)
+		f.Write([]byte(line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fileLines 读取文件中的全部行
+func fileLines(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+// newTestFileSink 在临时目录中创建日志文件输出目标，测试结束时关闭
+func newTestFileSink(t *testing.T) *FileSink {
+	t.Helper()
+	f, err := NewFileSink(filepath.Join(t.TempDir(), "access.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestFileSinkRetriesFailedWrites(t *testing.T) {
+	f := newTestFileSink(t)
+	want := writeLines(f, 0, 2, 0)
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	breakFile(f)
+	for i := 0; i < 3; i++ {
+		want = append(want, writeLines(f, 2+i*2, 2, 0)...)
+		if err := f.Flush(); err == nil {
+			t.Fatal("Flush succeeded on a closed file")
+		}
+	}
+	if f.Pending() == 0 || f.Dropped() != 0 {
+		t.Fatalf("pending %d, dropped %d", f.Pending(), f.Dropped())
+	}
+
+	// 恢复后按原顺序补写，不丢失也不重复
+	repairFile(t, f)
+	want = append(want, writeLines(f, 8, 1, 0)...)
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := fileLines(t, f.path); !reflect.DeepEqual(got, want) {
+		t.Errorf("file has %v, want %v", got, want)
+	}
+	if f.Pending() != 0 {
+		t.Errorf("pending %d after recovery", f.Pending())
+	}
+}
+
+func TestFileSinkSpill(t *testing.T) {
+	f := newTestFileSink(t)
+	spillPath := filepath.Join(t.TempDir(), "access.spill")
+	if err := f.SetSpillFile(spillPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// 写入失败期间累计超过 16MB，超出的部分转存到溢出文件
+	breakFile(f)
+	const pad = 1024
+	n := maxPendingBytes/pad + 100
+	want := writeLines(f, 0, n, pad)
+	f.Flush()
+	info, err := os.Stat(spillPath)
+	if err != nil || info.Size() == 0 {
+		t.Fatalf("spill file: %v, %v", info, err)
+	}
+	if f.Dropped() != 0 {
+		t.Fatalf("dropped %d entries with a spill file", f.Dropped())
+	}
+
+	// 溢出文件之后的日志仍然排在后面
+	want = append(want, writeLines(f, n, 10, pad)...)
+	f.Flush()
+
+	repairFile(t, f)
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := fileLines(t, f.path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("file has %d lines, want %d in order", len(got), len(want))
+	}
+	if info, err := os.Stat(spillPath); err != nil || info.Size() != 0 {
+		t.Errorf("spill file not emptied: %v, %v", info, err)
+	}
+	if f.Pending() != 0 {
+		t.Errorf("pending %d after replay", f.Pending())
+	}
+}
+
+func TestFileSinkDropsWithoutSpill(t *testing.T) {
+	f := newTestFileSink(t)
+	breakFile(f)
+	const pad = 1024
+	n := maxPendingBytes/pad + 100
+	writeLines(f, 0, n, pad)
+	f.Flush()
+	dropped := f.Dropped()
+	if dropped == 0 {
+		t.Fatal("nothing dropped over the memory limit")
+	}
+
+	// 丢弃的是超出上限时保留的全部内容，之后的日志照常补写
+	repairFile(t, f)
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(fileLines(t, f.path)); int64(got)+dropped != int64(n) {
+		t.Errorf("%d lines written + %d dropped, want %d", got, dropped, n)
+	}
+}
+
+func TestCloseFlushesAndSpills(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	spillPath := filepath.Join(dir, "access.spill")
+
+	// 正常关闭时写完全部排队和缓冲的日志
+	l, err := NewLogger(path, 100, 180)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := []string{"/0", "/1", "/2"}
+	for _, p := range first {
+		l.Log(&AccessLog{Timestamp: time.Now(), Path: p})
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readPaths(t, path); !reflect.DeepEqual(got, first) {
+		t.Fatalf("file has %v after Close, want %v", got, first)
+	}
+
+	// 关闭时仍无法写入的日志转存到溢出文件，下次启动后补写
+	l, err = NewLogger(path, 100, 180)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetSpillFile(spillPath); err != nil {
+		t.Fatal(err)
+	}
+	q, f := l.fileSink()
+	q.do(func() error { breakFile(f); return nil })
+	second := []string{"/3", "/4"}
+	for _, p := range second {
+		l.Log(&AccessLog{Timestamp: time.Now(), Path: p})
+	}
+	l.Close()
+	if info, err := os.Stat(spillPath); err != nil || info.Size() == 0 {
+		t.Fatalf("spill file after Close: %v, %v", info, err)
+	}
+
+	l, err = NewLogger(path, 100, 180)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := l.SetSpillFile(spillPath); err != nil {
+		t.Fatal(err)
+	}
+	l.Log(&AccessLog{Timestamp: time.Now(), Path: "/5"})
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := append(append(first, second...), "/5")
+	if got := readPaths(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("file has %v, want %v", got, want)
+	}
+}
+
+func TestSyncPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		policy   SyncPolicy
+		interval time.Duration
+		tick     time.Duration // 写入 goroutine 定时调用 Tick 的间隔
+		synced   bool          // Flush 之后是否已同步
+	}{
+		{SyncNone, 0, 0, false},
+		{SyncFlush, 0, 0, true},
+		{SyncInterval, time.Second, time.Second, false},
+	} {
+		f := newTestFileSink(t)
+		f.SetSyncPolicy(tt.policy, tt.interval)
+		if got := f.TickInterval(); got != tt.tick {
+			t.Errorf("%s: TickInterval = %v, want %v", tt.policy, got, tt.tick)
+		}
+		writeLines(f, 0, 1, 0)
+		if err := f.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if f.unsynced == tt.synced {
+			t.Errorf("%s: synced after Flush = %v, want %v", tt.policy, !f.unsynced, tt.synced)
+		}
+		if err := f.Tick(); err != nil || f.unsynced {
+			t.Errorf("%s: Tick = %v, unsynced = %v", tt.policy, err, f.unsynced)
+		}
+	}
+
+	// 同步失败时返回错误，保留未同步状态以便下次重试
+	f := newTestFileSink(t)
+	writeLines(f, 0, 1, 0)
+	f.Flush()
+	breakFile(f)
+	if err := f.Sync(); err == nil || !f.unsynced {
+		t.Errorf("Sync on a closed file = %v, unsynced = %v", err, f.unsynced)
+	}
+	repairFile(t, f)
+	if err := f.Sync(); err != nil || f.unsynced {
+		t.Errorf("Sync after recovery = %v, unsynced = %v", err, f.unsynced)
+	}
+
+	for _, p := range []SyncPolicy{SyncNone, SyncFlush, SyncInterval} {
+		if got, err := ParseSyncPolicy(p.String()); err != nil || got != p {
+			t.Errorf("ParseSyncPolicy(%q) = %v, %v", p, got, err)
+		}
+	}
+	if _, err := ParseSyncPolicy("always"); err == nil {
+		t.Error("ParseSyncPolicy(always) succeeded")
+	}
+}
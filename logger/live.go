@@ -0,0 +1,76 @@
+package logger
+
+import "sync/atomic"
+
+// subscriberBuffer 每个订阅者缓冲的日志条数，缓冲满时丢弃新日志
+const subscriberBuffer = 256
+
+// Subscription 实时访问日志订阅
+type Subscription struct {
+	C       <-chan *AccessLog // 匹配的访问日志，日志管理器关闭或取消订阅后关闭
+	ch      chan *AccessLog
+	match   func(log *AccessLog) bool
+	dropped atomic.Int64
+	logger  *Logger
+}
+
+// Subscribe 订阅实时访问日志，match 为 nil 时接收全部日志
+// 推送不会阻塞日志记录：订阅者处理不及时、缓冲已满时丢弃日志并计数
+func (l *Logger) Subscribe(match func(log *AccessLog) bool) *Subscription {
+	ch := make(chan *AccessLog, subscriberBuffer)
+	s := &Subscription{C: ch, ch: ch, match: match, logger: l}
+
+	l.subMu.Lock()
+	defer l.subMu.Unlock()
+	if l.closed {
+		close(ch)
+		return s
+	}
+	if l.subscribers == nil {
+		l.subscribers = make(map[*Subscription]struct{})
+	}
+	l.subscribers[s] = struct{}{}
+	return s
+}
+
+// Dropped 因缓冲已满丢弃的日志条数
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.logger.subMu.Lock()
+	defer s.logger.subMu.Unlock()
+	if _, ok := s.logger.subscribers[s]; ok {
+		delete(s.logger.subscribers, s)
+		close(s.ch)
+	}
+}
+
+// publish 将访问日志推送给匹配的订阅者，不阻塞
+func (l *Logger) publish(log *AccessLog) {
+	l.subMu.RLock()
+	defer l.subMu.RUnlock()
+	for s := range l.subscribers {
+		if s.match != nil && !s.match(log) {
+			continue
+		}
+		select {
+		case s.ch <- log:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// closeSubscribers 关闭所有订阅
+func (l *Logger) closeSubscribers() {
+	l.subMu.Lock()
+	defer l.subMu.Unlock()
+	l.closed = true
+	for s := range l.subscribers {
+		close(s.ch)
+	}
+	l.subscribers = nil
+}
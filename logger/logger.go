@@ -2,10 +2,14 @@ package logger
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"log"
+	"strings"
 	"sync"
 	"time"
+
+	"mini_jump/metrics"
 )
 
 // AccessLog 访问日志
@@ -19,104 +23,311 @@ type AccessLog struct {
 	Target      string    `json:"target"`
 	RedirectType int      `json:"redirect_type"`
 	StatusCode  int       `json:"status_code"`
+	RuleID      string    `json:"rule_id"`           // 命中的规则 ID
+	Referer     string    `json:"referer,omitempty"` // 请求来源页面
+	Country     string    `json:"country,omitempty"` // 国家/地区代码（来自 CDN 请求头）
+}
+
+// ErrClosed 日志管理器已关闭
+var ErrClosed = errors.New("logger is closed")
+
+// FileSinkName 日志文件输出目标的名称
+const FileSinkName = "file"
+
+// OverflowPolicy 日志队列已满时的处理策略
+type OverflowPolicy int32
+
+const (
+	OverflowBlock      OverflowPolicy = iota // 阻塞等待队列有空位（不丢日志）
+	OverflowDropNewest                       // 丢弃新日志
+	OverflowDropOldest                       // 丢弃队列中最早的日志
+)
+
+// String 返回策略名称
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	default:
+		return "block"
+	}
+}
+
+// ParseOverflowPolicy 解析队列已满时的处理策略：block、drop-newest、drop-oldest
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	for _, p := range []OverflowPolicy{OverflowBlock, OverflowDropNewest, OverflowDropOldest} {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return OverflowBlock, fmt.Errorf("invalid log overflow policy %q (block, drop-newest, drop-oldest)", name)
 }
 
 // Logger 日志管理器
+// 每条日志序列化为一行 JSON，分发给全部输出目标（见 Sink），访问日志和审计日志共用
+// 每个输出目标有独立的有界队列和写入 goroutine，记录日志不会等待 I/O，一个输出目标失败不影响其他输出目标
 type Logger struct {
-	logFile       string
-	bufferSize    int
-	flushInterval time.Duration
-	buffer        []*AccessLog
-	mu            sync.Mutex
-	file          *os.File
-	ticker        *time.Ticker
-	done          chan struct{}
+	sinkMu  sync.RWMutex
+	sinks   []*sinkQueue
+	metrics *metrics.Metrics // 监控指标（为 nil 时不记录）
+	name    string           // 监控指标中的日志名称
+
+	// 实时日志订阅者（见 Subscribe）
+	subMu       sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
 }
 
-// NewLogger 创建日志管理器
-func NewLogger(logFile string, bufferSize int, flushIntervalSeconds int) (*Logger, error) {
-	l := &Logger{
-		logFile:       logFile,
-		bufferSize:    bufferSize,
-		flushInterval: time.Duration(flushIntervalSeconds) * time.Second,
-		buffer:        make([]*AccessLog, 0, bufferSize),
-		done:          make(chan struct{}),
-	}
+// New 创建没有输出目标的日志管理器，通过 AddSink 添加
+func New() *Logger {
+	return &Logger{}
+}
 
+// NewLogger 创建写入文件的日志管理器，bufferSize 为队列长度
+func NewLogger(logFile string, bufferSize int, flushIntervalSeconds int) (*Logger, error) {
 	// 打开或创建日志文件
-	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	sink, err := NewFileSink(logFile)
 	if err != nil {
 		return nil, err
 	}
-	l.file = file
-
-	// 启动定时刷新
-	l.ticker = time.NewTicker(l.flushInterval)
-	go l.flushPeriodically()
 
+	l := New()
+	l.AddSink(FileSinkName, sink, SinkOptions{
+		BufferSize:    bufferSize,
+		FlushInterval: time.Duration(flushIntervalSeconds) * time.Second,
+	})
 	return l, nil
 }
 
-// Log 记录访问日志
-func (l *Logger) Log(log *AccessLog) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+// AddSink 添加输出目标，name 用于日志和监控指标，不能重复
+func (l *Logger) AddSink(name string, sink Sink, opts SinkOptions) error {
+	l.sinkMu.Lock()
+	defer l.sinkMu.Unlock()
+	for _, q := range l.sinks {
+		if q.name == name {
+			return fmt.Errorf("duplicate log sink %q", name)
+		}
+	}
+	q := newSinkQueue(name, sink, opts)
+	if l.metrics != nil {
+		q.setMetrics(l.metrics, l.name)
+	}
+	l.sinks = append(l.sinks, q)
+	return nil
+}
+
+// queues 返回全部输出目标的队列
+func (l *Logger) queues() []*sinkQueue {
+	l.sinkMu.RLock()
+	defer l.sinkMu.RUnlock()
+	return l.sinks
+}
+
+// eachFile 在各个日志文件输出目标的写入 goroutine 中执行操作，返回全部错误
+func (l *Logger) eachFile(fn func(f *FileSink) error) error {
+	var errs []error
+	for _, q := range l.queues() {
+		f, ok := q.sink.(*FileSink)
+		if !ok {
+			continue
+		}
+		if err := q.do(func() error { return fn(f) }); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fileSink 返回第一个日志文件输出目标及其队列，没有时返回 nil
+func (l *Logger) fileSink() (*sinkQueue, *FileSink) {
+	for _, q := range l.queues() {
+		if f, ok := q.sink.(*FileSink); ok {
+			return q, f
+		}
+	}
+	return nil, nil
+}
+
+// SetOverflowPolicy 设置日志文件队列已满时的处理策略（默认阻塞），其他输出目标在 AddSink 时设置
+func (l *Logger) SetOverflowPolicy(p OverflowPolicy) {
+	for _, q := range l.queues() {
+		if _, ok := q.sink.(*FileSink); ok {
+			q.overflow.Store(int32(p))
+		}
+	}
+}
+
+// SetSyncPolicy 设置日志文件同步到磁盘的策略，interval 为 SyncInterval 时的同步间隔
+func (l *Logger) SetSyncPolicy(p SyncPolicy, interval time.Duration) {
+	l.eachFile(func(f *FileSink) error {
+		f.SetSyncPolicy(p, interval)
+		return nil
+	})
+}
 
-	l.buffer = append(l.buffer, log)
+// SetSpillFile 设置日志文件写入失败时的溢出文件（见 FileSink.SetSpillFile）
+func (l *Logger) SetSpillFile(path string) error {
+	return l.eachFile(func(f *FileSink) error {
+		return f.SetSpillFile(path)
+	})
+}
+
+// SetRotation 设置日志轮转
+func (l *Logger) SetRotation(opts RotateOptions) {
+	l.eachFile(func(f *FileSink) error {
+		f.SetRotation(opts)
+		return nil
+	})
+}
+
+// Rotate 立即轮转日志文件（先写入已排队的日志）
+func (l *Logger) Rotate() error {
+	return l.eachFile((*FileSink).Rotate)
+}
+
+// Reopen 重新打开日志文件，用于外部 logrotate 移走文件之后
+// 已排队的日志先写入原文件，之后的日志写入新文件
+func (l *Logger) Reopen() error {
+	return l.eachFile((*FileSink).Reopen)
+}
+
+// SetMetrics 启用监控指标，name 用于区分访问日志、审计日志，之后添加的输出目标同样记录
+func (l *Logger) SetMetrics(m *metrics.Metrics, name string) {
+	if m == nil {
+		return
+	}
+	l.sinkMu.Lock()
+	defer l.sinkMu.Unlock()
+	l.metrics = m
+	l.name = name
+	for _, q := range l.sinks {
+		q.setMetrics(m, name)
+	}
+}
+
+// Buffered 返回各个队列中尚未写入的日志条数之和
+func (l *Logger) Buffered() int {
+	n := 0
+	for _, q := range l.queues() {
+		n += q.Buffered()
+	}
+	return n
+}
 
-	// 达到缓冲大小，立即刷新
-	if len(l.buffer) >= l.bufferSize {
-		l.flushUnlocked()
+// Dropped 返回各个输出目标丢弃的日志条数之和（队列已满，或写入失败且无法保留）
+func (l *Logger) Dropped() int64 {
+	var n int64
+	for _, q := range l.queues() {
+		n += q.Dropped()
 	}
+	return n
 }
 
-// flushUnlocked 刷新日志到文件（不加锁版本，需要在锁内调用）
-func (l *Logger) flushUnlocked() {
-	if len(l.buffer) == 0 {
+// Pending 返回写入失败、等待重试的字节数之和
+func (l *Logger) Pending() int64 {
+	var n int64
+	for _, q := range l.queues() {
+		n += q.Pending()
+	}
+	return n
+}
+
+// Log 记录访问日志，分发给全部输出目标，并推送给实时日志订阅者
+// 各个队列已满时按各自的 OverflowPolicy 处理；关闭后的日志被丢弃
+func (l *Logger) Log(log *AccessLog) {
+	l.publish(log)
+
+	line, err := marshal(log)
+	if err != nil {
 		return
 	}
+	for _, q := range l.queues() {
+		q.enqueue(line)
+	}
+}
 
-	for _, log := range l.buffer {
-		data, err := json.Marshal(log)
+// LogSync 记录日志并立即写入全部输出目标、同步到磁盘，返回写入错误
+// 用于审计日志等不允许丢失的记录，不受队列策略影响
+func (l *Logger) LogSync(entry interface{}) error {
+	line, err := marshal(entry)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, q := range l.queues() {
+		err := q.do(func() error {
+			q.write(line)
+			if err := q.flush(); err != nil {
+				return err
+			}
+			return q.sync()
+		})
 		if err != nil {
-			fmt.Printf("Error marshaling log: %v\n", err)
-			continue
+			errs = append(errs, err)
 		}
-		l.file.Write(append(data, '\n'))
 	}
+	return errors.Join(errs...)
+}
 
-	l.buffer = l.buffer[:0] // 清空缓冲
+// marshal 将日志序列化为一行 JSON
+func marshal(entry interface{}) ([]byte, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Error marshaling log: %v\n", err)
+	}
+	return data, err
 }
 
-// Flush 刷新日志到文件
-func (l *Logger) Flush() error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.flushUnlocked()
-	return nil
+// Err 返回最近一次写入日志文件的错误，最近一次写入成功时为 nil
+// 只包括本地日志文件，syslog、HTTP 收集器等远程输出目标不可用时不影响就绪检查，通过监控指标查看
+func (l *Logger) Err() error {
+	var errs []error
+	for _, q := range l.queues() {
+		if _, ok := q.sink.(*FileSink); !ok {
+			continue
+		}
+		if err := q.Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
-// flushPeriodically 定期刷新日志
-func (l *Logger) flushPeriodically() {
-	for {
-		select {
-		case <-l.ticker.C:
-			l.Flush()
-		case <-l.done:
-			return
+// Flush 将全部输出目标已排队的日志写出
+func (l *Logger) Flush() error {
+	var errs []error
+	for _, q := range l.queues() {
+		if err := q.do(q.flush); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", q.name, err))
 		}
 	}
+	return errors.Join(errs...)
 }
 
-// Close 关闭日志管理器
+// Close 写入剩余日志并关闭全部输出目标，可以重复调用
 func (l *Logger) Close() error {
-	close(l.done)
-	if l.ticker != nil {
-		l.ticker.Stop()
+	l.closeSubscribers()
+
+	var errs []error
+	for _, q := range l.queues() {
+		if err := q.close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", q.name, err))
+		}
 	}
-	l.Flush()
-	if l.file != nil {
-		return l.file.Close()
+	return errors.Join(errs...)
+}
+
+// String 返回输出目标名称，用于日志
+func (l *Logger) String() string {
+	var names []string
+	for _, q := range l.queues() {
+		if f, ok := q.sink.(*FileSink); ok {
+			names = append(names, f.Path())
+			continue
+		}
+		names = append(names, q.name)
 	}
-	return nil
+	return strings.Join(names, ", ")
 }
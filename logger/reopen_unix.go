@@ -0,0 +1,30 @@
+//go:build !windows
+
+package logger
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReopenOnSignal 收到 SIGUSR1 时重新打开日志文件，配合外部 logrotate 使用
+func ReopenOnSignal(loggers ...*Logger) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGUSR1)
+	go func() {
+		for range sigChan {
+			for _, l := range loggers {
+				if l == nil {
+					continue
+				}
+				if err := l.Reopen(); err != nil {
+					log.Printf("Failed to reopen log %s: %v\n", l, err)
+					continue
+				}
+				log.Printf("Reopened log %s\n", l)
+			}
+		}
+	}()
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package logger
+
+import (
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReopenOnSignal(t *testing.T) {
+	l := newRotatingLogger(t, RotateOptions{})
+	_, f := l.fileSink()
+	ReopenOnSignal(l)
+
+	// 模拟外部 logrotate：移走日志文件后发送 SIGUSR1
+	first := logEntries(t, l, 0, 3)
+	moved := f.path + ".1"
+	if err := os.Rename(f.path, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); !exists(f.path); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("log file not reopened")
+		}
+	}
+	second := logEntries(t, l, 3, 2)
+
+	if got := readPaths(t, moved); !reflect.DeepEqual(got, first) {
+		t.Errorf("moved file has %v, want %v", got, first)
+	}
+	if got := readPaths(t, f.path); !reflect.DeepEqual(got, second) {
+		t.Errorf("reopened file has %v, want %v", got, second)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build windows
+
+package logger
+
+// ReopenOnSignal Windows 没有 SIGUSR1，不做任何事（请使用内置轮转）
+func ReopenOnSignal(loggers ...*Logger) {}
@@ -0,0 +1,282 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mini_jump/atomicfile"
+)
+
+// backupTimeLayout 轮转文件名中的时间格式（服务器本地时区），如 access.log.20240101-150405.000
+// 同名的轮转文件已存在时（同一毫秒内多次轮转、夏令时回拨）添加 -2、-3 等序号，如 access.log.20240101-150405.000-2
+const backupTimeLayout = "20060102-150405.000"
+
+// compressSuffix 压缩后的轮转文件后缀
+const compressSuffix = ".gz"
+
+// RotateOptions 日志轮转配置，零值表示不轮转
+type RotateOptions struct {
+	MaxSize    int64         // 文件超过该字节数时轮转（0 表示不按大小轮转）
+	Daily      bool          // 每天（服务器本地时区）第一次写入时轮转
+	MaxBackups int           // 最多保留的轮转文件数（0 表示不限制）
+	MaxAge     time.Duration // 轮转文件的保留时间（0 表示不限制）
+	Compress   bool          // 轮转后用 gzip 压缩
+}
+
+// rotatingFile 支持轮转和重新打开的日志文件
+// 本身不加锁，只在 FileSink 的写入 goroutine 中调用；每次写入的都是完整的行，
+// 轮转发生在两次写入之间，不会丢失或重复写入
+type rotatingFile struct {
+	path   string
+	opts   RotateOptions
+	file   *os.File
+	size   int64          // 当前文件大小
+	day    string         // 当前文件开始写入的日期（按天轮转时使用）
+	bgMu   sync.Mutex     // 串行化后台压缩和清理
+	bgWait sync.WaitGroup // 等待后台任务完成
+}
+
+// openRotatingFile 以追加方式打开日志文件
+func openRotatingFile(path string) (*rotatingFile, error) {
+	f := &rotatingFile{path: path}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open 打开（或创建）日志文件，并记录文件大小和日期
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	// 已有内容的文件按最后修改日期计算，使重启后也能按天轮转
+	f.day = dayOf(time.Now())
+	if info.Size() > 0 {
+		f.day = dayOf(info.ModTime())
+	}
+	return nil
+}
+
+// Write 写入一行日志，写入前按需轮转
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	if f.needRotate(len(p)) {
+		if err := f.Rotate(); err != nil {
+			// 轮转失败时继续写入原文件，不丢日志
+			log.Printf("Failed to rotate log %s: %v\n", f.path, err)
+		}
+	}
+	return f.writeRaw(p)
+}
+
+// writeRaw 不检查轮转直接写入，用于补写之前写入失败的内容
+func (f *rotatingFile) writeRaw(p []byte) (int, error) {
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// needRotate 判断写入 n 字节前是否需要轮转
+func (f *rotatingFile) needRotate(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.opts.MaxSize > 0 && f.size+int64(n) > f.opts.MaxSize {
+		return true
+	}
+	return f.opts.Daily && dayOf(time.Now()) != f.day
+}
+
+// Rotate 将当前文件重命名为带时间的轮转文件并打开新文件，随后在后台压缩和清理旧文件
+func (f *rotatingFile) Rotate() error {
+	backup := f.backupName(time.Now())
+	if err := f.file.Close(); err != nil {
+		log.Printf("Failed to close log %s: %v\n", f.path, err)
+	}
+	renameErr := os.Rename(f.path, backup)
+	// 无论重命名是否成功都重新打开，保证后续日志可以写入
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+
+	f.bgWait.Add(1)
+	go f.afterRotate(backup, f.opts)
+	return nil
+}
+
+// backupName 返回不与已有轮转文件（包括已压缩的）重名的轮转文件路径
+func (f *rotatingFile) backupName(now time.Time) string {
+	base := f.path + "." + now.Format(backupTimeLayout)
+	name := base
+	for seq := 2; exists(name) || exists(name+compressSuffix); seq++ {
+		name = base + "-" + strconv.Itoa(seq)
+	}
+	return name
+}
+
+// exists 判断文件是否存在
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return !os.IsNotExist(err)
+}
+
+// Reopen 关闭并重新打开日志文件，用于外部 logrotate 移走文件之后
+func (f *rotatingFile) Reopen() error {
+	if err := f.file.Close(); err != nil {
+		log.Printf("Failed to close log %s: %v\n", f.path, err)
+	}
+	return f.open()
+}
+
+// Sync 同步到磁盘
+func (f *rotatingFile) Sync() error {
+	return f.file.Sync()
+}
+
+// Close 关闭文件，并等待后台压缩和清理完成
+func (f *rotatingFile) Close() error {
+	err := f.file.Close()
+	f.bgWait.Wait()
+	return err
+}
+
+// afterRotate 压缩轮转文件并清理超出保留数量或时间的轮转文件
+func (f *rotatingFile) afterRotate(backup string, opts RotateOptions) {
+	defer f.bgWait.Done()
+	f.bgMu.Lock()
+	defer f.bgMu.Unlock()
+
+	if opts.Compress {
+		// 连续轮转时后台任务不保证按顺序执行，文件可能已被之后的清理删除
+		if err := compressFile(backup); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to compress rotated log %s: %v\n", backup, err)
+		}
+	}
+	if err := f.prune(opts); err != nil {
+		log.Printf("Failed to remove old rotated logs of %s: %v\n", f.path, err)
+	}
+}
+
+// prune 删除超出保留数量或保留时间的轮转文件
+func (f *rotatingFile) prune(opts RotateOptions) error {
+	if opts.MaxBackups <= 0 && opts.MaxAge <= 0 {
+		return nil
+	}
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-opts.MaxAge)
+	var firstErr error
+	for i, b := range backups {
+		expired := opts.MaxAge > 0 && b.at.Before(cutoff)
+		if !expired && (opts.MaxBackups <= 0 || i < opts.MaxBackups) {
+			continue
+		}
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// backup 一个轮转文件
+type backup struct {
+	path string
+	at   time.Time // 轮转时间
+	seq  int       // 同名轮转文件的序号（无序号时为 1）
+}
+
+// backups 返回全部轮转文件（包括已压缩的），按轮转时间从新到旧排序
+func (f *rotatingFile) backups() ([]backup, error) {
+	dir := filepath.Dir(f.path)
+	prefix := filepath.Base(f.path) + "."
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var result []backup
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		at, seq, ok := parseBackupName(strings.TrimSuffix(name, compressSuffix))
+		if !ok {
+			continue
+		}
+		result = append(result, backup{path: filepath.Join(dir, entry.Name()), at: at, seq: seq})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].at.Equal(result[j].at) {
+			return result[i].at.After(result[j].at)
+		}
+		return result[i].seq > result[j].seq
+	})
+	return result, nil
+}
+
+// parseBackupName 解析轮转文件名中的时间和序号（不含日志文件名前缀和压缩后缀）
+func parseBackupName(name string) (time.Time, int, bool) {
+	if len(name) < len(backupTimeLayout) {
+		return time.Time{}, 0, false
+	}
+	at, err := time.ParseInLocation(backupTimeLayout, name[:len(backupTimeLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	seq := 1
+	if rest := name[len(backupTimeLayout):]; rest != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(rest, "-"))
+		if err != nil || !strings.HasPrefix(rest, "-") || n < 2 {
+			return time.Time{}, 0, false
+		}
+		seq = n
+	}
+	return at, seq, true
+}
+
+// compressFile 用 gzip 压缩文件，成功后删除原文件（先写临时文件再替换，避免留下不完整的压缩文件）
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	err = atomicfile.Write(path+compressSuffix, func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		if _, err := io.Copy(zw, src); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// dayOf 返回时间在服务器本地时区的日期
+func dayOf(t time.Time) string {
+	return t.In(time.Local).Format("2006-01-02")
+}
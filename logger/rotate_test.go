@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logPaths 按写入顺序返回日志文件及其全部轮转文件中记录的访问路径
+func logPaths(t *testing.T, l *Logger) []string {
+	t.Helper()
+	_, f := l.fileSink()
+	backups, err := f.file.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for i := len(backups) - 1; i >= 0; i-- {
+		paths = append(paths, readPaths(t, backups[i].path)...)
+	}
+	return append(paths, readPaths(t, f.path)...)
+}
+
+// readPaths 读取一个日志文件（可以是已压缩的）中记录的访问路径
+func readPaths(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if strings.HasSuffix(path, compressSuffix) {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		r = zr
+	}
+	var paths []string
+	dec := json.NewDecoder(r)
+	for {
+		var entry AccessLog
+		if err := dec.Decode(&entry); err == io.EOF {
+			return paths
+		} else if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		paths = append(paths, entry.Path)
+	}
+}
+
+// logEntries 逐条记录并写入 n 条访问日志，路径从 /first 开始编号
+func logEntries(t *testing.T, l *Logger, first, n int) []string {
+	t.Helper()
+	var paths []string
+	for i := first; i < first+n; i++ {
+		path := "/" + strconv.Itoa(i)
+		l.Log(&AccessLog{Timestamp: time.Now(), Domain: "example.com", Path: path, StatusCode: 302})
+		if err := l.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// newRotatingLogger 创建按 opts 轮转的访问日志
+func newRotatingLogger(t *testing.T, opts RotateOptions) *Logger {
+	t.Helper()
+	l, err := NewLogger(filepath.Join(t.TempDir(), "access.log"), 100, 180)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	l.SetRotation(opts)
+	return l
+}
+
+func TestRotateBySize(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		t.Run("compress="+strconv.FormatBool(compress), func(t *testing.T) {
+			const maxSize = 1024
+			l := newRotatingLogger(t, RotateOptions{MaxSize: maxSize, Compress: compress})
+			want := logEntries(t, l, 0, 100)
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// 所有日志按顺序出现且只出现一次
+			if got := logPaths(t, l); !reflect.DeepEqual(got, want) {
+				t.Fatalf("logged %d lines across files, want %d in order: %v", len(got), len(want), got)
+			}
+			_, f := l.fileSink()
+			backups, _ := f.file.backups()
+			if len(backups) < 5 {
+				t.Fatalf("%d rotated files, want several", len(backups))
+			}
+			for _, b := range backups {
+				if strings.HasSuffix(b.path, compressSuffix) != compress {
+					t.Errorf("%s: compressed = %v, want %v", b.path, !compress, compress)
+				}
+				if compress {
+					continue
+				}
+				if info, err := os.Stat(b.path); err != nil || info.Size() > maxSize {
+					t.Errorf("%s: %v, size over %d", b.path, err, maxSize)
+				}
+			}
+		})
+	}
+}
+
+func TestRotateDaily(t *testing.T) {
+	l := newRotatingLogger(t, RotateOptions{Daily: true})
+	first := logEntries(t, l, 0, 3)
+
+	// 当天内不轮转
+	q, f := l.fileSink()
+	if backups, _ := f.file.backups(); len(backups) != 0 {
+		t.Fatalf("rotated within the day: %v", backups)
+	}
+	// 模拟日期变化：当前文件是昨天开始写入的
+	q.do(func() error {
+		f.file.day = dayOf(time.Now().AddDate(0, 0, -1))
+		return nil
+	})
+	second := logEntries(t, l, 3, 2)
+
+	backups, _ := f.file.backups()
+	if len(backups) != 1 {
+		t.Fatalf("%d rotated files, want 1", len(backups))
+	}
+	if got := readPaths(t, backups[0].path); !reflect.DeepEqual(got, first) {
+		t.Errorf("rotated file has %v, want %v", got, first)
+	}
+	if got := readPaths(t, f.path); !reflect.DeepEqual(got, second) {
+		t.Errorf("current file has %v, want %v", got, second)
+	}
+}
+
+func TestRotatePrune(t *testing.T) {
+	l := newRotatingLogger(t, RotateOptions{MaxBackups: 2, Compress: true})
+	var want [][]string
+	for i := 0; i < 5; i++ {
+		want = append(want, logEntries(t, l, i*2, 2))
+		if err := l.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只保留最新的两个轮转文件，且全部已压缩，没有留下临时文件
+	_, f := l.fileSink()
+	backups, _ := f.file.backups()
+	if len(backups) != 2 {
+		t.Fatalf("%d rotated files, want 2", len(backups))
+	}
+	for i, b := range backups {
+		if !strings.HasSuffix(b.path, compressSuffix) {
+			t.Errorf("%s not compressed", b.path)
+		}
+		if got := readPaths(t, b.path); !reflect.DeepEqual(got, want[len(want)-1-i]) {
+			t.Errorf("%s has %v, want %v", b.path, got, want[len(want)-1-i])
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Dir(f.path))
+	if len(entries) != 3 {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("files left: %v", names)
+	}
+}
+
+func TestBackupNameNeverOverwrites(t *testing.T) {
+	f := &rotatingFile{path: filepath.Join(t.TempDir(), "access.log")}
+	now := time.Now()
+	base := f.path + "." + now.Format(backupTimeLayout)
+	for _, tt := range []struct {
+		existing string // 在上一步基础上新增的文件
+		want     string
+	}{
+		{"", base},
+		{base, base + "-2"},
+		// 已压缩的轮转文件同样占用文件名
+		{base + "-2" + compressSuffix, base + "-3"},
+		{base + "-3", base + "-4"},
+	} {
+		if tt.existing != "" {
+			if err := os.WriteFile(tt.existing, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := f.backupName(now); got != tt.want {
+			t.Errorf("backupName = %s, want %s", filepath.Base(got), filepath.Base(tt.want))
+		}
+	}
+
+	// 同一毫秒内连续轮转也不覆盖之前的轮转文件
+	l := newRotatingLogger(t, RotateOptions{})
+	var want []string
+	for i := 0; i < 10; i++ {
+		want = append(want, logEntries(t, l, i, 1)...)
+		if err := l.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := logPaths(t, l); !reflect.DeepEqual(got, want) {
+		t.Errorf("logged %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,336 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mini_jump/metrics"
+)
+
+// Sink 日志输出目标（文件、标准输出、syslog、HTTP 收集器等）
+// 每个输出目标有独立的队列和写入 goroutine，方法只在该 goroutine 中调用，无需加锁；
+// 一个输出目标变慢或失败只影响自己的队列，不影响其他输出目标
+type Sink interface {
+	// Write 写入一条日志（一行 JSON，不含换行符），可以先缓冲；line 由全部输出目标共享，不能修改
+	Write(line []byte) error
+	// Flush 将缓冲的日志写出，在刷新间隔到达、执行操作前和关闭前调用
+	Flush() error
+	// Close 关闭输出目标，调用前已执行 Flush
+	Close() error
+}
+
+// batcher 批量写出的输出目标，Full 返回 true 时先刷新再写入下一条 n 字节的日志
+type batcher interface {
+	Full(n int) bool
+}
+
+// syncer 支持同步到磁盘的输出目标，LogSync 和关闭时调用
+type syncer interface {
+	Sync() error
+}
+
+// dropper 自身会丢弃日志的输出目标（如收集器长时间不可用），返回丢弃的条数
+type dropper interface {
+	Dropped() int64
+}
+
+// pender 保留写入失败的日志等待重试的输出目标，返回等待重试的字节数
+type pender interface {
+	Pending() int64
+}
+
+// ticker 需要定期执行操作的输出目标（如按间隔同步到磁盘），TickInterval 为 0 时不执行
+// 每次执行 do 的操作后重新读取间隔
+type ticker interface {
+	TickInterval() time.Duration
+	Tick() error
+}
+
+// SinkOptions 输出目标的队列配置
+type SinkOptions struct {
+	BufferSize    int            // 队列长度
+	FlushInterval time.Duration  // 定期刷新间隔
+	Overflow      OverflowPolicy // 队列已满时的处理策略
+}
+
+// request 交给写入 goroutine 执行的操作，执行前先写入已排队的日志
+type request struct {
+	fn     func() error
+	result chan error
+}
+
+// sinkQueue 一个输出目标的有界队列和写入 goroutine
+type sinkQueue struct {
+	name          string
+	sink          Sink
+	flushInterval time.Duration
+	overflow      atomic.Int32 // OverflowPolicy
+	dropped       atomic.Int64 // 队列已满或关闭后丢弃的日志条数
+	entries       chan []byte
+	requests      chan request
+	stop          chan struct{} // 关闭时通知写入 goroutine
+	done          chan struct{} // 写入 goroutine 退出后关闭
+	stopMu        sync.RWMutex  // 关闭时等待正在入队的日志
+	stopped       bool
+	closeErr      error
+	errMu         sync.Mutex
+	writeErr      error // 最近一次写入的错误（写入成功后清除）
+
+	// 以下字段只在写入 goroutine 中访问
+	dirty   bool             // 上次刷新后有新写入
+	tick    *time.Ticker     // 输出目标的定期操作（见 ticker）
+	every   time.Duration    // 定期操作的当前间隔
+	metrics *metrics.Metrics // 监控指标（为 nil 时不记录）
+	log     string           // 监控指标中的日志名称
+}
+
+// newSinkQueue 创建输出目标的队列并启动写入 goroutine
+func newSinkQueue(name string, sink Sink, opts SinkOptions) *sinkQueue {
+	q := &sinkQueue{
+		name:          name,
+		sink:          sink,
+		flushInterval: opts.FlushInterval,
+		entries:       make(chan []byte, max(opts.BufferSize, 1)),
+		requests:      make(chan request),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	if q.flushInterval <= 0 {
+		q.flushInterval = time.Second
+	}
+	q.overflow.Store(int32(opts.Overflow))
+	go q.run()
+	return q
+}
+
+// enqueue 将一条日志放入队列，队列已满时按 OverflowPolicy 处理；关闭后的日志被丢弃
+func (q *sinkQueue) enqueue(line []byte) {
+	q.stopMu.RLock()
+	defer q.stopMu.RUnlock()
+	if q.stopped {
+		q.dropped.Add(1)
+		return
+	}
+
+	switch OverflowPolicy(q.overflow.Load()) {
+	case OverflowDropNewest:
+		select {
+		case q.entries <- line:
+		default:
+			q.dropped.Add(1)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case q.entries <- line:
+				return
+			default:
+			}
+			select {
+			case <-q.entries:
+				q.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		q.entries <- line
+	}
+}
+
+// Dropped 返回丢弃的日志条数（队列中丢弃的和输出目标自身丢弃的）
+func (q *sinkQueue) Dropped() int64 {
+	n := q.dropped.Load()
+	if d, ok := q.sink.(dropper); ok {
+		n += d.Dropped()
+	}
+	return n
+}
+
+// Pending 返回写入失败、等待重试的字节数
+func (q *sinkQueue) Pending() int64 {
+	if p, ok := q.sink.(pender); ok {
+		return p.Pending()
+	}
+	return 0
+}
+
+// Buffered 返回队列中尚未写入的日志条数
+func (q *sinkQueue) Buffered() int {
+	return len(q.entries)
+}
+
+// setMetrics 启用监控指标
+func (q *sinkQueue) setMetrics(m *metrics.Metrics, log string) {
+	q.do(func() error {
+		q.metrics = m
+		q.log = log
+		return nil
+	})
+	m.SetLogBuffer(log, q.name, q.Buffered)
+	m.SetLogDropped(log, q.name, q.Dropped)
+	if _, ok := q.sink.(pender); ok {
+		m.SetLogPending(log, q.name, q.Pending)
+	}
+}
+
+// Err 返回最近一次写入的错误，最近一次写入成功时为 nil
+func (q *sinkQueue) Err() error {
+	q.errMu.Lock()
+	defer q.errMu.Unlock()
+	return q.writeErr
+}
+
+// setWriteErr 记录写入结果
+func (q *sinkQueue) setWriteErr(err error) {
+	q.errMu.Lock()
+	defer q.errMu.Unlock()
+	q.writeErr = err
+}
+
+// do 在写入 goroutine 中执行操作并等待结果，执行前先写入已排队的日志
+func (q *sinkQueue) do(fn func() error) error {
+	q.stopMu.RLock()
+	if q.stopped {
+		q.stopMu.RUnlock()
+		return ErrClosed
+	}
+	req := request{fn: fn, result: make(chan error, 1)}
+	q.requests <- req
+	q.stopMu.RUnlock()
+	return <-req.result
+}
+
+// run 写入 goroutine：依次写入队列中的日志，定期及关闭时刷新
+func (q *sinkQueue) run() {
+	defer close(q.done)
+	flushTicker := time.NewTicker(q.flushInterval)
+	defer flushTicker.Stop()
+	q.resetTick()
+
+	for {
+		select {
+		case line := <-q.entries:
+			q.write(line)
+		case req := <-q.requests:
+			q.drain()
+			req.result <- req.fn()
+			q.resetTick()
+		case <-flushTicker.C:
+			q.flush()
+		case <-q.tickC():
+			if err := q.sink.(ticker).Tick(); err != nil {
+				q.metrics.ObserveLogFlush(q.log, q.name, 0, err)
+				q.setWriteErr(err)
+			}
+		case <-q.stop:
+			// 关闭时已不再有新日志入队，写完剩余日志后关闭输出目标
+			for len(q.entries) > 0 {
+				q.write(<-q.entries)
+			}
+			err := q.flush()
+			if err == nil {
+				err = q.sync()
+			}
+			if q.tick != nil {
+				q.tick.Stop()
+			}
+			if closeErr := q.sink.Close(); err == nil {
+				err = closeErr
+			}
+			q.closeErr = err
+			return
+		}
+	}
+}
+
+// drain 写入当前已排队的日志
+func (q *sinkQueue) drain() {
+	for n := len(q.entries); n > 0; n-- {
+		q.write(<-q.entries)
+	}
+}
+
+// write 将一条日志交给输出目标，批量写出的输出目标缓冲已满时先刷新
+func (q *sinkQueue) write(line []byte) {
+	if b, ok := q.sink.(batcher); ok && b.Full(len(line)) {
+		q.flush()
+	}
+	q.dirty = true
+	if err := q.sink.Write(line); err != nil {
+		q.setWriteErr(err)
+	}
+}
+
+// flush 刷新输出目标；没有新写入且上次刷新成功时跳过
+func (q *sinkQueue) flush() error {
+	if !q.dirty && q.Err() == nil {
+		return nil
+	}
+	start := time.Now()
+	err := q.sink.Flush()
+	q.metrics.ObserveLogFlush(q.log, q.name, time.Since(start), err)
+	q.setWriteErr(err)
+	q.dirty = false
+	return err
+}
+
+// sync 同步到磁盘（输出目标支持时）
+func (q *sinkQueue) sync() error {
+	s, ok := q.sink.(syncer)
+	if !ok {
+		return nil
+	}
+	err := s.Sync()
+	if err != nil {
+		q.metrics.ObserveLogFlush(q.log, q.name, 0, err)
+	}
+	q.setWriteErr(err)
+	return err
+}
+
+// resetTick 输出目标的定期操作间隔变化时重置定时器
+func (q *sinkQueue) resetTick() {
+	t, ok := q.sink.(ticker)
+	if !ok {
+		return
+	}
+	interval := max(t.TickInterval(), 0)
+	if interval == q.every {
+		return
+	}
+	q.every = interval
+	switch {
+	case interval == 0:
+		q.tick.Stop()
+		q.tick = nil
+	case q.tick == nil:
+		q.tick = time.NewTicker(interval)
+	default:
+		q.tick.Reset(interval)
+	}
+}
+
+// tickC 返回定期操作的定时器通道（未启用时为 nil，永远不会触发）
+func (q *sinkQueue) tickC() <-chan time.Time {
+	if q.tick == nil {
+		return nil
+	}
+	return q.tick.C
+}
+
+// close 写入剩余日志并关闭输出目标，可以重复调用
+func (q *sinkQueue) close() error {
+	q.stopMu.Lock()
+	if q.stopped {
+		q.stopMu.Unlock()
+		<-q.done
+		return q.closeErr
+	}
+	q.stopped = true
+	q.stopMu.Unlock()
+
+	close(q.stop)
+	<-q.done
+	return q.closeErr
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"bufio"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// writeBufferSize 批量写入文件的缓冲大小
+const writeBufferSize = 64 * 1024
+
+// FileSink 写入本地文件的输出目标：经缓冲批量写入，支持轮转、写入失败补写和同步策略
+// 配置方法与其他方法一样只能在写入 goroutine 中调用，添加到 Logger 之后请使用 Logger 的同名方法
+type FileSink struct {
+	path         string
+	file         *rotatingFile
+	out          *retryWriter // 写入失败时保留内容稍后补写
+	writer       *bufio.Writer
+	dropped      atomic.Int64 // 写入失败且无法保留而丢弃的日志条数
+	syncPolicy   SyncPolicy
+	syncInterval time.Duration // 按间隔同步时的同步间隔
+	unsynced     bool          // 上次同步后有新写入文件的内容
+}
+
+// NewFileSink 以追加方式打开日志文件
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := openRotatingFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &FileSink{path: path, file: file}
+	f.out = &retryWriter{file: file, onDrop: func(lines int64) { f.dropped.Add(lines) }}
+	f.writer = bufio.NewWriterSize(f.out, writeBufferSize)
+	return f, nil
+}
+
+// Path 返回日志文件路径
+func (f *FileSink) Path() string {
+	return f.path
+}
+
+// SetRotation 设置日志轮转
+func (f *FileSink) SetRotation(opts RotateOptions) {
+	f.file.opts = opts
+}
+
+// SetSyncPolicy 设置同步到磁盘的策略，interval 为 SyncInterval 时的同步间隔
+func (f *FileSink) SetSyncPolicy(p SyncPolicy, interval time.Duration) {
+	f.syncPolicy = p
+	f.syncInterval = interval
+}
+
+// SetSpillFile 设置溢出文件：日志文件持续写入失败、内存中保留的内容超过上限时转存到该文件，
+// 恢复后按顺序补写回日志文件。为空时超出部分丢弃并计入丢弃条数
+// 溢出文件应位于另一个磁盘上，上次未补写完的内容会在下次写入时补写
+func (f *FileSink) SetSpillFile(path string) error {
+	return f.out.setSpill(path)
+}
+
+// Write 将一条日志写入缓冲
+func (f *FileSink) Write(line []byte) error {
+	if len(line)+1 > f.writer.Available() {
+		// 超过缓冲大小的日志连同换行符一次写入，保证轮转不会把一行拆到两个文件
+		f.writer.Write(append(append(make([]byte, 0, len(line)+1), line...), '\n'))
+		return nil
+	}
+	f.writer.Write(line)
+	f.writer.WriteByte('\n')
+	return nil
+}
+
+// Full 缓冲放不下下一条日志时返回 true，保证每次写入文件的都是完整的行
+func (f *FileSink) Full(n int) bool {
+	return f.writer.Buffered() > 0 && f.writer.Available() < n+1
+}
+
+// Flush 将缓冲写入文件（先补写之前失败的内容），按同步策略同步到磁盘
+func (f *FileSink) Flush() error {
+	if f.writer.Buffered() == 0 && !f.out.hasPending() {
+		return nil
+	}
+	f.writer.Flush()
+	f.out.retry()
+	if f.out.err != nil {
+		return f.out.err
+	}
+	f.unsynced = true
+	if f.syncPolicy == SyncFlush {
+		return f.Sync()
+	}
+	return nil
+}
+
+// Sync 将已写入文件的内容同步到磁盘
+func (f *FileSink) Sync() error {
+	if !f.unsynced {
+		return nil
+	}
+	if err := f.file.Sync(); err != nil {
+		log.Printf("Failed to sync log %s: %v\n", f.path, err)
+		return err
+	}
+	f.unsynced = false
+	return nil
+}
+
+// TickInterval 按间隔同步时返回同步间隔
+func (f *FileSink) TickInterval() time.Duration {
+	if f.syncPolicy != SyncInterval {
+		return 0
+	}
+	return f.syncInterval
+}
+
+// Tick 按间隔同步到磁盘
+func (f *FileSink) Tick() error {
+	return f.Sync()
+}
+
+// Rotate 立即轮转日志文件（先写入缓冲中的日志）
+func (f *FileSink) Rotate() error {
+	f.Flush()
+	return f.file.Rotate()
+}
+
+// Reopen 重新打开日志文件，用于外部 logrotate 移走文件之后
+// 缓冲中的日志先写入原文件，之后的日志写入新文件
+func (f *FileSink) Reopen() error {
+	f.Flush()
+	return f.file.Reopen()
+}
+
+// Dropped 返回写入失败且无法保留而丢弃的日志条数
+func (f *FileSink) Dropped() int64 {
+	return f.dropped.Load()
+}
+
+// Pending 返回写入失败、等待补写的字节数
+func (f *FileSink) Pending() int64 {
+	return f.out.size.Load()
+}
+
+// Close 关闭文件；仍无法写入的日志转存到溢出文件，下次启动后补写
+func (f *FileSink) Close() error {
+	if f.out.hasPending() {
+		f.out.spill()
+	}
+	return f.file.Close()
+}
@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// HTTP 收集器重试间隔，发送失败后从最小值开始倍增
+const (
+	httpMinRetry = time.Second
+	httpMaxRetry = time.Minute
+)
+
+// httpContentType 请求体格式：每行一条 JSON
+const httpContentType = "application/x-ndjson"
+
+// HTTPOptions HTTP 收集器输出目标配置
+type HTTPOptions struct {
+	URL       string        // 收集器地址，URL 中的用户名密码作为 Basic 认证发送
+	Header    http.Header   // 附加的请求头（如 Authorization）
+	BatchSize int           // 每个请求最多包含的日志条数，默认 500
+	Timeout   time.Duration // 请求超时，默认 10 秒
+}
+
+// httpBatch 一个请求的日志
+type httpBatch struct {
+	body  []byte
+	lines int64
+}
+
+// HTTPSink 批量 POST 到 HTTP 收集器的输出目标，请求体为换行分隔的 JSON（application/x-ndjson）
+// 攒满 BatchSize 条或刷新间隔到达时发送；收集器返回 2xx 表示成功
+// 发送失败的请求按递增的间隔重试，保留的日志超过 16MB 时丢弃最早的请求；
+// 收集器返回 4xx（408、429 除外）时认为请求本身无效，丢弃不重试
+type HTTPSink struct {
+	opts    HTTPOptions
+	target  string // 隐藏密码的收集器地址，用于日志
+	client  *http.Client
+	batch   bytes.Buffer // 正在攒的请求
+	lines   int64        // 正在攒的日志条数
+	failed  []httpBatch  // 等待重试的请求，按发送顺序
+	pending atomic.Int64 // 等待重试的字节数，供监控读取
+	dropped atomic.Int64 // 丢弃的日志条数
+	retry   time.Duration
+	next    time.Time // 下次重试时间
+	failing bool
+}
+
+// NewHTTPSink 创建 HTTP 收集器输出目标
+func NewHTTPSink(opts HTTPOptions) (*HTTPSink, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid collector URL %q", u.Redacted())
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &HTTPSink{opts: opts, target: u.Redacted(), client: &http.Client{Timeout: opts.Timeout}}, nil
+}
+
+// Write 将一条日志加入正在攒的请求
+func (h *HTTPSink) Write(line []byte) error {
+	h.batch.Write(line)
+	h.batch.WriteByte('\n')
+	h.lines++
+	return nil
+}
+
+// Full 正在攒的请求已满时返回 true
+func (h *HTTPSink) Full(n int) bool {
+	return h.lines >= int64(h.opts.BatchSize) || (h.lines > 0 && h.batch.Len()+n+1 > maxPendingBytes)
+}
+
+// Flush 按顺序发送等待重试的请求和正在攒的请求；重试间隔未到时只保留，不发送
+func (h *HTTPSink) Flush() error {
+	return h.send(false)
+}
+
+// Pending 返回等待重试的字节数
+func (h *HTTPSink) Pending() int64 {
+	return h.pending.Load()
+}
+
+// Dropped 返回丢弃的日志条数
+func (h *HTTPSink) Dropped() int64 {
+	return h.dropped.Load()
+}
+
+// Close 最后尝试发送一次（忽略重试间隔），仍失败的日志丢弃
+func (h *HTTPSink) Close() error {
+	err := h.send(true)
+	if len(h.failed) > 0 {
+		var lines int64
+		for _, b := range h.failed {
+			lines += b.lines
+		}
+		log.Printf("Dropped %d log entries that could not be sent to %s\n", lines, h.target)
+		h.dropped.Add(lines)
+		h.failed = nil
+		h.pending.Store(0)
+	}
+	h.client.CloseIdleConnections()
+	return err
+}
+
+// send 发送请求，force 为 true 时忽略重试间隔
+func (h *HTTPSink) send(force bool) error {
+	if h.lines > 0 {
+		h.failed = append(h.failed, httpBatch{body: bytes.Clone(h.batch.Bytes()), lines: h.lines})
+		h.batch.Reset()
+		h.lines = 0
+		h.trim()
+	}
+	defer h.updatePending()
+
+	for len(h.failed) > 0 {
+		if !force && time.Now().Before(h.next) {
+			return fmt.Errorf("waiting to retry sending logs to %s", h.target)
+		}
+		b := h.failed[0]
+		retryable, err := h.post(b.body)
+		if err != nil && retryable {
+			h.fail(err)
+			return err
+		}
+		if err != nil {
+			log.Printf("Dropped %d log entries rejected by %s: %v\n", b.lines, h.target, err)
+			h.dropped.Add(b.lines)
+		}
+		h.failed[0] = httpBatch{}
+		h.failed = h.failed[1:]
+	}
+	h.retry = 0
+	if h.failing {
+		log.Printf("Log collector %s is reachable again, pending entries have been sent\n", h.target)
+		h.failing = false
+	}
+	return nil
+}
+
+// post 发送一个请求，返回错误是否可以重试
+func (h *HTTPSink) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, h.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for name, values := range h.opts.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", httpContentType)
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return false, fmt.Errorf("collector responded %s", resp.Status)
+	default:
+		return true, fmt.Errorf("collector responded %s", resp.Status)
+	}
+}
+
+// fail 推迟下次重试，进入失败状态时输出一次日志
+func (h *HTTPSink) fail(err error) {
+	h.retry = min(max(h.retry*2, httpMinRetry), httpMaxRetry)
+	h.next = time.Now().Add(h.retry)
+	if !h.failing {
+		log.Printf("Failed to send logs to %s, entries will be retried: %v\n", h.target, err)
+		h.failing = true
+	}
+}
+
+// trim 等待重试的日志超过 maxPendingBytes 时丢弃最早的请求
+func (h *HTTPSink) trim() {
+	size := 0
+	for _, b := range h.failed {
+		size += len(b.body)
+	}
+	for size > maxPendingBytes && len(h.failed) > 1 {
+		b := h.failed[0]
+		log.Printf("Dropped %d log entries that could not be sent to %s\n", b.lines, h.target)
+		h.dropped.Add(b.lines)
+		size -= len(b.body)
+		h.failed[0] = httpBatch{}
+		h.failed = h.failed[1:]
+	}
+}
+
+// updatePending 更新等待重试的字节数
+func (h *HTTPSink) updatePending() {
+	var size int64
+	for _, b := range h.failed {
+		size += int64(len(b.body))
+	}
+	h.pending.Store(size)
+}
@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// collector 本地 HTTP 收集器，记录每个请求，按 status 返回状态码
+type collector struct {
+	*httptest.Server
+	status atomic.Int32
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]string // 每个请求体中的日志行
+}
+
+// newCollector 启动返回 200 的收集器
+func newCollector(t *testing.T) *collector {
+	c := &collector{}
+	c.status.Store(http.StatusOK)
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var lines []string
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		c.mu.Lock()
+		c.requests = append(c.requests, r)
+		c.bodies = append(c.bodies, lines)
+		c.mu.Unlock()
+		w.WriteHeader(int(c.status.Load()))
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// received 返回收到的请求体
+func (c *collector) received() [][]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]string(nil), c.bodies...)
+}
+
+func TestHTTPSinkBatching(t *testing.T) {
+	c := newCollector(t)
+	sink, err := NewHTTPSink(HTTPOptions{URL: c.URL + "/ingest", BatchSize: 3, Header: http.Header{"X-Source": {"edge"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New()
+	// 刷新间隔足够长，请求只会因为攒满一批或关闭而发送
+	if err := l.AddSink("http", sink, SinkOptions{BufferSize: 100, FlushInterval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/1", "/2", "/3", "/4", "/5", "/6", "/7"} {
+		l.Log(&AccessLog{Path: path})
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	bodies := c.received()
+	if len(bodies) != 3 || len(bodies[0]) != 3 || len(bodies[1]) != 3 || len(bodies[2]) != 1 {
+		t.Fatalf("batches = %q, want 3 + 3 + 1 lines", bodies)
+	}
+	if !bytes.Contains([]byte(bodies[2][0]), []byte(`"path":"/7"`)) {
+		t.Errorf("last line = %s, want /7", bodies[2][0])
+	}
+	r := c.requests[0]
+	if r.Method != http.MethodPost || r.URL.Path != "/ingest" {
+		t.Errorf("request = %s %s", r.Method, r.URL.Path)
+	}
+	if got := r.Header.Get("Content-Type"); got != httpContentType {
+		t.Errorf("Content-Type = %q, want %q", got, httpContentType)
+	}
+	if got := r.Header.Get("X-Source"); got != "edge" {
+		t.Errorf("X-Source = %q, want edge", got)
+	}
+	if sink.Dropped() != 0 || sink.Pending() != 0 {
+		t.Errorf("dropped = %d, pending = %d", sink.Dropped(), sink.Pending())
+	}
+}
+
+func TestHTTPSinkRetry(t *testing.T) {
+	c := newCollector(t)
+	c.status.Store(http.StatusServiceUnavailable)
+	sink, err := NewHTTPSink(HTTPOptions{URL: c.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Write([]byte(`{"n":1}`))
+	sink.Write([]byte(`{"n":2}`))
+
+	if err := sink.Flush(); err == nil {
+		t.Fatal("Flush succeeded while the collector returns 503")
+	}
+	if sink.Pending() == 0 {
+		t.Error("failed batch is not kept for retry")
+	}
+	// 重试间隔内不发送，之后写入的日志排在失败的请求之后
+	sink.Write([]byte(`{"n":3}`))
+	if err := sink.Flush(); err == nil {
+		t.Error("Flush succeeded before the retry delay")
+	}
+	if n := len(c.received()); n != 1 {
+		t.Errorf("collector received %d requests before the retry delay, want 1", n)
+	}
+
+	c.status.Store(http.StatusOK)
+	sink.next = time.Now()
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	bodies := c.received()
+	if len(bodies) != 3 || len(bodies[1]) != 2 || len(bodies[2]) != 1 || bodies[2][0] != `{"n":3}` {
+		t.Errorf("requests = %q, want the failed batch retried before the new one", bodies)
+	}
+	if sink.Pending() != 0 || sink.Dropped() != 0 || sink.retry != 0 {
+		t.Errorf("pending = %d, dropped = %d, retry = %v", sink.Pending(), sink.Dropped(), sink.retry)
+	}
+	sink.Close()
+}
+
+func TestHTTPSinkRejected(t *testing.T) {
+	c := newCollector(t)
+	c.status.Store(http.StatusBadRequest)
+	sink, err := NewHTTPSink(HTTPOptions{URL: c.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Write([]byte(`{"n":1}`))
+	sink.Write([]byte(`{"n":2}`))
+
+	// 收集器认为请求无效（4xx），丢弃不重试
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if sink.Dropped() != 2 || sink.Pending() != 0 {
+		t.Errorf("dropped = %d, pending = %d, want 2, 0", sink.Dropped(), sink.Pending())
+	}
+	sink.Close()
+}
+
+func TestHTTPSinkCloseDropsUnsent(t *testing.T) {
+	c := newCollector(t)
+	c.status.Store(http.StatusInternalServerError)
+	sink, err := NewHTTPSink(HTTPOptions{URL: c.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Write([]byte(`{"n":1}`))
+	sink.Flush()
+
+	// 关闭时忽略重试间隔再发送一次，仍失败的日志计入丢弃
+	if err := sink.Close(); err == nil {
+		t.Error("Close succeeded while the collector returns 500")
+	}
+	if n := len(c.received()); n != 2 {
+		t.Errorf("collector received %d requests, want 2", n)
+	}
+	if sink.Dropped() != 1 || sink.Pending() != 0 {
+		t.Errorf("dropped = %d, pending = %d, want 1, 0", sink.Dropped(), sink.Pending())
+	}
+}
@@ -0,0 +1,263 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SinkConfig 由输出目标描述创建的输出目标
+type SinkConfig struct {
+	Name    string
+	Sink    Sink
+	Options SinkOptions
+}
+
+// sinkDefaults 各类输出目标的默认刷新间隔
+var sinkDefaults = map[string]time.Duration{
+	"stdout": time.Second,
+	"file":   time.Minute,
+	"syslog": time.Second,
+	"http":   5 * time.Second,
+}
+
+// sinkKeys 各类输出目标支持的选项（不含通用选项）
+var sinkKeys = map[string][]string{
+	"stdout": nil,
+	"file":   {"max-size", "daily", "max-backups", "max-age", "compress", "fsync"},
+	"syslog": {"facility", "tag", "timeout"},
+	"http":   {"batch", "timeout", "header"},
+}
+
+// commonSinkKeys 全部输出目标支持的选项
+var commonSinkKeys = []string{"name", "buffer", "flush", "overflow"}
+
+// OpenSink 按描述创建输出目标，描述为 URL，# 之后为查询字符串格式的选项：
+//
+//	stdout
+//	file:///var/log/minijump/access.json#max-size=100&daily=true（相对路径为 file:access.json）
+//	syslog+udp://127.0.0.1:514#facility=local0&tag=minijump
+//	syslog+tcp://logs.example.com:601
+//	syslog+unix:///dev/log
+//	https://collector.example.com/ingest#batch=500&header=Authorization:Bearer%20token
+//
+// 通用选项：name（名称，默认为类型，文件为文件名）、buffer（队列长度，默认 1000）、
+// flush（刷新间隔秒数）、overflow（队列已满时的处理策略，默认 drop-newest，远程输出目标不可用时不阻塞请求）
+func OpenSink(spec string) (*SinkConfig, error) {
+	kind, target, query, err := parseSinkSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSinkKeys(kind, query); err != nil {
+		return nil, err
+	}
+
+	cfg := &SinkConfig{
+		Name: query.Get("name"),
+		Options: SinkOptions{
+			BufferSize:    1000,
+			FlushInterval: sinkDefaults[kind],
+			Overflow:      OverflowDropNewest,
+		},
+	}
+	if v := query.Get("buffer"); v != "" {
+		if cfg.Options.BufferSize, err = positiveInt("buffer", v); err != nil {
+			return nil, err
+		}
+	}
+	if v := query.Get("flush"); v != "" {
+		if cfg.Options.FlushInterval, err = seconds("flush", v); err != nil {
+			return nil, err
+		}
+	}
+	if v := query.Get("overflow"); v != "" {
+		if cfg.Options.Overflow, err = ParseOverflowPolicy(v); err != nil {
+			return nil, err
+		}
+	}
+
+	switch kind {
+	case "stdout":
+		cfg.Sink = NewStreamSink("stdout", os.Stdout)
+	case "file":
+		cfg.Sink, err = openFileSinkSpec(target.Path, query)
+	case "syslog":
+		cfg.Sink, err = openSyslogSinkSpec(target, query)
+	case "http":
+		cfg.Sink, err = openHTTPSinkSpec(target, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Name == "" {
+		cfg.Name = kind
+		if kind == "file" {
+			cfg.Name = filepath.Base(target.Path)
+		}
+	}
+	return cfg, nil
+}
+
+// parseSinkSpec 解析输出目标描述，返回类型、去掉选项的 URL 和选项
+func parseSinkSpec(spec string) (string, *url.URL, url.Values, error) {
+	target, err := url.Parse(spec)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("invalid log sink %q: %v", spec, err)
+	}
+	query, err := url.ParseQuery(target.EscapedFragment())
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("invalid log sink options %q: %v", target.Fragment, err)
+	}
+	target.Fragment = ""
+	target.RawFragment = ""
+
+	switch {
+	case target.Scheme == "" && target.Path == "stdout":
+		return "stdout", target, query, nil
+	case target.Scheme == "file":
+		if target.Path == "" {
+			// file:access.json 形式的相对路径
+			target.Path, target.Opaque = target.Opaque, ""
+		}
+		if target.Path != "" {
+			return "file", target, query, nil
+		}
+	case strings.HasPrefix(target.Scheme, "syslog+"):
+		return "syslog", target, query, nil
+	case (target.Scheme == "http" || target.Scheme == "https") && target.Host != "":
+		return "http", target, query, nil
+	}
+	return "", nil, nil, fmt.Errorf("unsupported log sink %q (stdout, file://, syslog+udp://, syslog+tcp://, syslog+unix://, http://, https://)", target.Redacted())
+}
+
+// checkSinkKeys 检查选项名称，避免拼写错误的选项被静默忽略
+func checkSinkKeys(kind string, query url.Values) error {
+	for key := range query {
+		known := false
+		for _, k := range append(commonSinkKeys, sinkKeys[kind]...) {
+			known = known || k == key
+		}
+		if !known {
+			return fmt.Errorf("unknown %s log sink option %q", kind, key)
+		}
+	}
+	return nil
+}
+
+// openFileSinkSpec 创建文件输出目标
+func openFileSinkSpec(path string, query url.Values) (*FileSink, error) {
+	var opts RotateOptions
+	var err error
+	if v := query.Get("max-size"); v != "" {
+		size, err := positiveInt("max-size", v)
+		if err != nil {
+			return nil, err
+		}
+		opts.MaxSize = int64(size) * 1024 * 1024
+	}
+	if v := query.Get("daily"); v != "" {
+		if opts.Daily, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid daily %q", v)
+		}
+	}
+	if v := query.Get("max-backups"); v != "" {
+		if opts.MaxBackups, err = positiveInt("max-backups", v); err != nil {
+			return nil, err
+		}
+	}
+	if v := query.Get("max-age"); v != "" {
+		days, err := positiveInt("max-age", v)
+		if err != nil {
+			return nil, err
+		}
+		opts.MaxAge = time.Duration(days) * 24 * time.Hour
+	}
+	opts.Compress = true
+	if v := query.Get("compress"); v != "" {
+		if opts.Compress, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid compress %q", v)
+		}
+	}
+	policy := SyncNone
+	if v := query.Get("fsync"); v != "" {
+		if policy, err = ParseSyncPolicy(v); err != nil {
+			return nil, err
+		}
+	}
+
+	sink, err := NewFileSink(path)
+	if err != nil {
+		return nil, err
+	}
+	sink.SetRotation(opts)
+	sink.SetSyncPolicy(policy, time.Second)
+	return sink, nil
+}
+
+// openSyslogSinkSpec 创建 syslog 输出目标
+func openSyslogSinkSpec(target *url.URL, query url.Values) (*SyslogSink, error) {
+	opts := SyslogOptions{
+		Network:  strings.TrimPrefix(target.Scheme, "syslog+"),
+		Addr:     target.Host,
+		Facility: query.Get("facility"),
+		Tag:      query.Get("tag"),
+	}
+	if opts.Network == "unix" {
+		opts.Addr = target.Path
+	}
+	if opts.Addr == "" {
+		return nil, fmt.Errorf("missing syslog address in %q", target)
+	}
+	if v := query.Get("timeout"); v != "" {
+		var err error
+		if opts.Timeout, err = seconds("timeout", v); err != nil {
+			return nil, err
+		}
+	}
+	return NewSyslogSink(opts)
+}
+
+// openHTTPSinkSpec 创建 HTTP 收集器输出目标
+func openHTTPSinkSpec(target *url.URL, query url.Values) (*HTTPSink, error) {
+	opts := HTTPOptions{URL: target.String(), Header: http.Header{}}
+	var err error
+	if v := query.Get("batch"); v != "" {
+		if opts.BatchSize, err = positiveInt("batch", v); err != nil {
+			return nil, err
+		}
+	}
+	if v := query.Get("timeout"); v != "" {
+		if opts.Timeout, err = seconds("timeout", v); err != nil {
+			return nil, err
+		}
+	}
+	for _, h := range query["header"] {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header %q (Name:Value)", h)
+		}
+		opts.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return NewHTTPSink(opts)
+}
+
+// positiveInt 解析正整数选项
+func positiveInt(key, v string) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return n, nil
+}
+
+// seconds 解析秒数选项
+func seconds(key, v string) (time.Duration, error) {
+	n, err := positiveInt(key, v)
+	return time.Duration(n) * time.Second, err
+}
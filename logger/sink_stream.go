@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"sync/atomic"
+)
+
+// StreamSink 写入标准输出等流的输出目标，用于容器中由平台收集标准输出
+type StreamSink struct {
+	name    string
+	w       io.Writer
+	writer  *bufio.Writer
+	lines   int64        // 缓冲中的日志条数
+	dropped atomic.Int64 // 写入失败丢弃的日志条数
+	failing bool
+}
+
+// NewStreamSink 创建写入 w 的输出目标，name 用于错误日志；关闭时不关闭 w
+func NewStreamSink(name string, w io.Writer) *StreamSink {
+	return &StreamSink{name: name, w: w, writer: bufio.NewWriterSize(w, writeBufferSize)}
+}
+
+// Write 将一条日志写入缓冲
+func (s *StreamSink) Write(line []byte) error {
+	s.writer.Write(line)
+	s.writer.WriteByte('\n')
+	s.lines++
+	return nil
+}
+
+// Full 缓冲放不下下一条日志时返回 true，保证每次写出的都是完整的行
+func (s *StreamSink) Full(n int) bool {
+	return s.writer.Buffered() > 0 && s.writer.Available() < n+1
+}
+
+// Flush 将缓冲写出；失败时丢弃缓冲中的日志，避免流阻塞或关闭后日志无限堆积
+func (s *StreamSink) Flush() error {
+	err := s.writer.Flush()
+	if err != nil {
+		if !s.failing {
+			log.Printf("Failed to write log to %s, entries are dropped: %v\n", s.name, err)
+			s.failing = true
+		}
+		s.dropped.Add(s.lines)
+		s.writer.Reset(s.w)
+	} else if s.failing {
+		log.Printf("Log %s is writable again\n", s.name)
+		s.failing = false
+	}
+	s.lines = 0
+	return err
+}
+
+// Dropped 返回写入失败丢弃的日志条数
+func (s *StreamSink) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close 关闭输出目标（不关闭底层的流）
+func (s *StreamSink) Close() error {
+	return nil
+}
@@ -0,0 +1,232 @@
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// syslog 重连间隔，连接失败后从最小值开始倍增
+const (
+	syslogMinRedial = time.Second
+	syslogMaxRedial = 30 * time.Second
+)
+
+// syslogSeverity 日志级别（informational）
+const syslogSeverity = 6
+
+// syslogHeaderSize 消息头（PRI、时间、主机名等）和分帧的预留长度
+const syslogHeaderSize = 256
+
+// syslogTimeLayout RFC 5424 时间格式
+const syslogTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// syslogFacilities 设施名称
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// errSyslogDisconnected 等待重连期间写入的日志被丢弃
+var errSyslogDisconnected = errors.New("syslog server is not connected")
+
+// SyslogOptions syslog 输出目标配置
+type SyslogOptions struct {
+	Network  string        // udp、tcp 或 unix
+	Addr     string        // host:port 或 unix socket 路径
+	Facility string        // 设施名称，默认 local0
+	Tag      string        // APP-NAME，默认 minijump
+	Timeout  time.Duration // 连接和写入超时，默认 10 秒
+}
+
+// SyslogSink 按 RFC 5424 格式发送到 syslog 服务器的输出目标
+// UDP 和 unix 数据报每条日志一个数据报；TCP 按 RFC 6587 长度前缀分帧并批量写出；unix 流以换行分隔
+// 连接失败或断开时丢弃日志并计数，按递增的间隔重连，不影响其他输出目标
+type SyslogSink struct {
+	opts     SyslogOptions
+	priority int
+	hostname string
+	pid      string
+
+	network  string // 实际使用的网络类型（unix 会先尝试 unixgram）
+	conn     net.Conn
+	writer   *bufio.Writer // 流式连接的写入缓冲
+	lines    int64         // 缓冲中的日志条数
+	redial   time.Duration // 当前重连间隔
+	nextDial time.Time     // 下次重连时间
+	dropped  atomic.Int64  // 发送失败丢弃的日志条数
+	failing  bool
+}
+
+// NewSyslogSink 创建 syslog 输出目标，第一次写入时连接
+func NewSyslogSink(opts SyslogOptions) (*SyslogSink, error) {
+	switch opts.Network {
+	case "udp", "tcp", "unix":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q (udp, tcp, unix)", opts.Network)
+	}
+	if opts.Facility == "" {
+		opts.Facility = "local0"
+	}
+	facility, ok := syslogFacilities[opts.Facility]
+	if !ok {
+		return nil, fmt.Errorf("invalid syslog facility %q", opts.Facility)
+	}
+	if opts.Tag == "" {
+		opts.Tag = "minijump"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &SyslogSink{
+		opts:     opts,
+		priority: facility*8 + syslogSeverity,
+		hostname: hostname,
+		pid:      strconv.Itoa(os.Getpid()),
+	}, nil
+}
+
+// Write 发送一条日志；流式连接先写入缓冲
+func (s *SyslogSink) Write(line []byte) error {
+	if err := s.connect(); err != nil {
+		s.dropped.Add(1)
+		return err
+	}
+	msg := s.format(line)
+	if s.writer == nil {
+		// 数据报：每条日志一个数据报
+		s.conn.SetWriteDeadline(time.Now().Add(s.opts.Timeout))
+		if _, err := s.conn.Write(msg); err != nil {
+			s.dropped.Add(1)
+			s.fail(err)
+			return err
+		}
+		s.reachable()
+		return nil
+	}
+	if s.network == "tcp" {
+		// RFC 6587 octet counting：MSG-LEN SP SYSLOG-MSG
+		s.writer.WriteString(strconv.Itoa(len(msg)))
+		s.writer.WriteByte(' ')
+		s.writer.Write(msg)
+	} else {
+		s.writer.Write(msg)
+		s.writer.WriteByte('\n')
+	}
+	s.lines++
+	return nil
+}
+
+// Full 流式连接的缓冲放不下下一条日志时返回 true
+func (s *SyslogSink) Full(n int) bool {
+	return s.writer != nil && s.writer.Buffered() > 0 && s.writer.Available() < n+syslogHeaderSize
+}
+
+// Flush 写出流式连接的缓冲，失败时丢弃缓冲中的日志并断开连接
+func (s *SyslogSink) Flush() error {
+	if s.writer == nil || s.writer.Buffered() == 0 {
+		return nil
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.opts.Timeout))
+	err := s.writer.Flush()
+	if err != nil {
+		s.dropped.Add(s.lines)
+		s.fail(err)
+	} else {
+		s.reachable()
+	}
+	s.lines = 0
+	return err
+}
+
+// Dropped 返回发送失败丢弃的日志条数
+func (s *SyslogSink) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close 关闭连接
+func (s *SyslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	s.writer = nil
+	return err
+}
+
+// format 按 RFC 5424 格式化：<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (s *SyslogSink) format(line []byte) []byte {
+	header := fmt.Sprintf("<%d>1 %s %s %s %s - - ",
+		s.priority, time.Now().Format(syslogTimeLayout), s.hostname, s.opts.Tag, s.pid)
+	return append([]byte(header), line...)
+}
+
+// connect 未连接时连接 syslog 服务器，重连间隔未到时返回错误
+func (s *SyslogSink) connect() error {
+	if s.conn != nil {
+		return nil
+	}
+	if time.Now().Before(s.nextDial) {
+		return errSyslogDisconnected
+	}
+
+	network := s.opts.Network
+	if network == "unix" {
+		// 本机 syslog（如 /dev/log）通常是数据报 socket，失败时再尝试流式
+		network = "unixgram"
+	}
+	conn, err := net.DialTimeout(network, s.opts.Addr, s.opts.Timeout)
+	if err != nil && network == "unixgram" {
+		network = "unix"
+		conn, err = net.DialTimeout(network, s.opts.Addr, s.opts.Timeout)
+	}
+	if err != nil {
+		s.fail(err)
+		return err
+	}
+	s.network = network
+	s.conn = conn
+	s.writer = nil
+	if network == "tcp" || network == "unix" {
+		s.writer = bufio.NewWriterSize(conn, writeBufferSize)
+	}
+	return nil
+}
+
+// fail 断开连接并推迟重连，进入失败状态时输出一次日志
+func (s *SyslogSink) fail(err error) {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+		s.writer = nil
+	}
+	s.redial = min(max(s.redial*2, syslogMinRedial), syslogMaxRedial)
+	s.nextDial = time.Now().Add(s.redial)
+	if !s.failing {
+		log.Printf("Failed to send log to syslog %s://%s, entries are dropped until reconnected: %v\n",
+			s.opts.Network, s.opts.Addr, err)
+		s.failing = true
+	}
+}
+
+// reachable 发送成功后重置重连间隔，从失败状态恢复时输出一次日志
+func (s *SyslogSink) reachable() {
+	s.redial = 0
+	if s.failing {
+		log.Printf("Syslog %s://%s is reachable again\n", s.opts.Network, s.opts.Addr)
+		s.failing = false
+	}
+}
@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSyslogSink 创建发送到 addr 的 syslog 输出目标，测试结束时关闭
+func newSyslogSink(t *testing.T, network, addr string) *SyslogSink {
+	t.Helper()
+	sink, err := NewSyslogSink(SyslogOptions{Network: network, Addr: addr, Tag: "edge", Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sink.Close() })
+	return sink
+}
+
+// checkSyslogMessage 检查 RFC 5424 消息头和内容
+func checkSyslogMessage(t *testing.T, msg, want string) {
+	t.Helper()
+	// local0.info：16*8+6
+	if !strings.HasPrefix(msg, "<134>1 ") {
+		t.Errorf("message %q does not start with <134>1", msg)
+	}
+	fields := strings.SplitN(msg, " ", 8)
+	if len(fields) != 8 {
+		t.Fatalf("message %q has %d fields, want 8", msg, len(fields))
+	}
+	if _, err := time.Parse(syslogTimeLayout, fields[1]); err != nil {
+		t.Errorf("timestamp %q: %v", fields[1], err)
+	}
+	if fields[3] != "edge" || fields[4] != strconv.Itoa(os.Getpid()) || fields[5] != "-" || fields[6] != "-" {
+		t.Errorf("header = %q, want tag edge, pid %d", fields[:7], os.Getpid())
+	}
+	if fields[7] != want {
+		t.Errorf("MSG = %q, want %q", fields[7], want)
+	}
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	sink := newSyslogSink(t, "udp", pc.LocalAddr().String())
+
+	lines := []string{`{"path":"/a"}`, `{"path":"/b"}`}
+	for _, line := range lines {
+		if err := sink.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 每条日志一个数据报
+	buf := make([]byte, 64*1024)
+	for _, line := range lines {
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSyslogMessage(t, string(buf[:n]), line)
+	}
+	if sink.Dropped() != 0 {
+		t.Errorf("dropped = %d, want 0", sink.Dropped())
+	}
+}
+
+func TestSyslogSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	conns := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			conns <- conn
+		}
+	}()
+	sink := newSyslogSink(t, "tcp", ln.Addr().String())
+
+	lines := []string{`{"path":"/a"}`, `{"path":"/b b"}`}
+	for _, line := range lines {
+		if err := sink.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var conn net.Conn
+	select {
+	case conn = <-conns:
+	case <-time.After(5 * time.Second):
+		t.Fatal("syslog sink did not connect")
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	// RFC 6587 octet counting：MSG-LEN SP SYSLOG-MSG
+	for _, line := range lines {
+		size, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(size, " "))
+		if err != nil {
+			t.Fatalf("frame length %q: %v", size, err)
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			t.Fatal(err)
+		}
+		checkSyslogMessage(t, string(msg), line)
+	}
+}
+
+func TestSyslogSinkUnreachable(t *testing.T) {
+	// 取一个已释放的端口，连接会被拒绝
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	sink := newSyslogSink(t, "tcp", addr)
+
+	if err := sink.Write([]byte(`{"n":1}`)); err == nil {
+		t.Fatal("Write succeeded without a syslog server")
+	}
+	// 重连间隔内不再连接，直接丢弃
+	if err := sink.Write([]byte(`{"n":2}`)); !errors.Is(err, errSyslogDisconnected) {
+		t.Errorf("Write during redial delay = %v, want %v", err, errSyslogDisconnected)
+	}
+	if sink.Dropped() != 2 {
+		t.Errorf("dropped = %d, want 2", sink.Dropped())
+	}
+	if sink.redial != syslogMinRedial {
+		t.Errorf("redial = %v, want %v", sink.redial, syslogMinRedial)
+	}
+}
@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateSink 记录写入的日志路径；第一次写入时通知 started，并等待 release 关闭后才返回
+type gateSink struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+
+	mu    sync.Mutex
+	paths []string
+}
+
+// newGateSink 创建第一次写入时阻塞的输出目标
+func newGateSink() *gateSink {
+	return &gateSink{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (s *gateSink) Write(line []byte) error {
+	s.once.Do(func() {
+		close(s.started)
+		<-s.release
+	})
+	var entry AccessLog
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.paths = append(s.paths, entry.Path)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *gateSink) Flush() error { return nil }
+func (s *gateSink) Close() error { return nil }
+
+// written 返回已写入的日志路径
+func (s *gateSink) written() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.paths...)
+}
+
+// blockedLogger 创建只有一个输出目标的日志管理器，写入第 0 条日志后输出目标阻塞，
+// 再写入 1..n-1 条，返回时队列（长度 2）已满
+func blockedLogger(t *testing.T, policy OverflowPolicy, n int) (*Logger, *gateSink) {
+	t.Helper()
+	sink := newGateSink()
+	l := New()
+	if err := l.AddSink("gate", sink, SinkOptions{BufferSize: 2, FlushInterval: time.Hour, Overflow: policy}); err != nil {
+		t.Fatal(err)
+	}
+	l.Log(&AccessLog{Path: "0"})
+	select {
+	case <-sink.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sink did not start writing")
+	}
+	for i := 1; i < n; i++ {
+		l.Log(&AccessLog{Path: strconv.Itoa(i)})
+	}
+	return l, sink
+}
+
+func TestOverflowDrop(t *testing.T) {
+	for _, tt := range []struct {
+		policy OverflowPolicy
+		want   []string
+	}{
+		{OverflowDropNewest, []string{"0", "1", "2"}},
+		{OverflowDropOldest, []string{"0", "3", "4"}},
+	} {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			l, sink := blockedLogger(t, tt.policy, 5)
+			if got := l.queues()[0].Dropped(); got != 2 {
+				t.Errorf("dropped = %d, want 2", got)
+			}
+			close(sink.release)
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := sink.written(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("written = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverflowBlock(t *testing.T) {
+	l, sink := blockedLogger(t, OverflowBlock, 3)
+	logged := make(chan struct{})
+	go func() {
+		l.Log(&AccessLog{Path: "3"})
+		close(logged)
+	}()
+	select {
+	case <-logged:
+		t.Fatal("Log returned while the queue is full")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(sink.release)
+	select {
+	case <-logged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Log still blocked after the sink resumed")
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sink.written(), []string{"0", "1", "2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("written = %v, want %v", got, want)
+	}
+	if got := l.queues()[0].Dropped(); got != 0 {
+		t.Errorf("dropped = %d, want 0", got)
+	}
+}
+
+func TestOverflowPerSink(t *testing.T) {
+	// 一个输出目标阻塞并丢弃日志，另一个输出目标照常写入
+	slow, fast := newGateSink(), newGateSink()
+	close(fast.release)
+	l := New()
+	if err := l.AddSink("slow", slow, SinkOptions{BufferSize: 1, FlushInterval: time.Hour, Overflow: OverflowDropNewest}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddSink("fast", fast, SinkOptions{BufferSize: 100, FlushInterval: time.Hour, Overflow: OverflowBlock}); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for i := 0; i < 10; i++ {
+		want = append(want, strconv.Itoa(i))
+		l.Log(&AccessLog{Path: want[i]})
+	}
+	if err := l.queues()[1].do(func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if got := fast.written(); !reflect.DeepEqual(got, want) {
+		t.Errorf("fast sink written = %v, want %v", got, want)
+	}
+	if got := l.queues()[1].Dropped(); got != 0 {
+		t.Errorf("fast sink dropped = %d, want 0", got)
+	}
+	if got := l.queues()[0].Dropped(); got == 0 {
+		t.Error("slow sink dropped nothing while blocked")
+	}
+
+	close(slow.release)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	total := int64(len(slow.written())) + l.queues()[0].Dropped()
+	if total != 10 {
+		t.Errorf("slow sink written + dropped = %d, want 10", total)
+	}
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	for _, p := range []OverflowPolicy{OverflowBlock, OverflowDropNewest, OverflowDropOldest} {
+		got, err := ParseOverflowPolicy(p.String())
+		if err != nil || got != p {
+			t.Errorf("ParseOverflowPolicy(%q) = %v, %v", p, got, err)
+		}
+	}
+	if _, err := ParseOverflowPolicy("drop"); err == nil {
+		t.Error(`ParseOverflowPolicy("drop") succeeded`)
+	}
+}
@@ -1,27 +1,60 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"runtime"
+	"strconv"
+	"strings"
 
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	"mini_jump/analytics"
 	"mini_jump/api"
+	"mini_jump/auth"
 	"mini_jump/config"
 	"mini_jump/handler"
+	"mini_jump/health"
+	"mini_jump/hits"
 	"mini_jump/logger"
 	"mini_jump/manager"
+	"mini_jump/metrics"
 	"mini_jump/service"
+	"mini_jump/webhook"
 )
 
+// expirySweepInterval 检查规则过期的间隔
+const expirySweepInterval = 30 * time.Second
+
+// shutdownTimeout 优雅关闭时等待进行中请求完成的最长时间
+const shutdownTimeout = 30 * time.Second
+
+// stringList 可以重复指定的字符串参数
+type stringList []string
+
+// String 返回参数值
+func (s *stringList) String() string {
+	return strings.Join(*s, " ")
+}
+
+// Set 添加一个参数值
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func main() {
 	// 检查是否为 install 或 uninstall 命令
 	if len(os.Args) > 1 {
@@ -33,93 +66,425 @@ func main() {
 		case "uninstall":
 			handleUninstall()
 			return
+		case "migrate":
+			handleMigrate()
+			return
+		case "hash-password":
+			handleHashPassword()
+			return
+		case "gen-token":
+			handleGenToken()
+			return
+		case "version":
+			handleVersion()
+			return
 		}
 	}
 
 	// 解析命令行参数
 	port := flag.Int("port", 18082, "服务端口")
 	configFile := flag.String("config", "rules.json", "配置文件路径")
-	logFile := flag.String("log", "access.log", "日志文件路径")
-	logBufferSize := flag.Int("log-buffer", 1000, "日志缓冲大小")
+	storeType := flag.String("store", config.StoreJSON, "规则存储类型（json/bolt）")
+	maxChain := flag.Int("max-chain", 3, "跳转链最大长度，超过时告警（0 表示不限制）")
+	authFile := flag.String("auth-file", "", "认证凭据文件路径（为空时不启用认证）")
+	adminAddr := flag.String("admin-addr", "", "管理接口独立监听地址（host:port 或 unix:/path/to.sock，为空时与跳转服务共用端口）")
+	adminSocketMode := flag.String("admin-socket-mode", "0660", "管理接口 unix socket 文件权限")
+	managerPath := flag.String("manager-path", manager.DefaultPath, "管理页面路径")
+	managerDir := flag.String("manager-dir", "", "管理页面静态文件目录（覆盖内置文件，为空时使用内置文件）")
+	metricsPath := flag.String("metrics-path", "/metrics", "Prometheus 监控指标路径（为空时不启用）")
+	logFile := flag.String("log", "access.log", "日志文件路径（为空时不写入文件）")
+	var logSinks stringList
+	flag.Var(&logSinks, "log-sink", "访问日志的其他输出目标，可重复指定（stdout、syslog+udp://host:514、https://collector/ingest 等）")
+	logBufferSize := flag.Int("log-buffer", 1000, "日志队列长度")
+	logOverflow := flag.String("log-overflow", "block", "日志队列已满时的处理策略（block/drop-newest/drop-oldest）")
 	logFlushInterval := flag.Int("log-flush", 180, "日志刷新间隔（秒）")
+	logFsync := flag.String("log-fsync", "none", "日志同步到磁盘的策略（none/flush/interval）")
+	logFsyncInterval := flag.Int("log-fsync-interval", 1, "按间隔同步时的同步间隔（秒）")
+	logSpill := flag.String("log-spill", "", "日志文件写入失败时的溢出文件路径（为空时超出内存上限的日志丢弃）")
+	logMaxSize := flag.Int("log-max-size", 0, "访问日志和审计日志超过该大小（MB）时轮转（0 表示不按大小轮转）")
+	logRotateDaily := flag.Bool("log-daily", false, "访问日志和审计日志每天轮转")
+	logMaxBackups := flag.Int("log-max-backups", 0, "最多保留的轮转文件数（0 表示不限制）")
+	logMaxAge := flag.Int("log-max-age", 0, "轮转文件保留天数（0 表示不限制）")
+	logCompress := flag.Bool("log-compress", true, "轮转后 gzip 压缩")
+	auditLogFile := flag.String("audit-log", "audit.log", "审计日志文件路径（为空时不记录审计日志）")
+	webhookDB := flag.String("webhook-db", "", "Webhook 存储文件路径（为空时不启用 Webhook）")
+	hitsFile := flag.String("hits-file", "hits.json", "规则命中统计文件路径（为空时不统计）")
+	hitsFlushInterval := flag.Int("hits-flush", 60, "命中统计保存间隔（秒）")
+	analyticsDir := flag.String("analytics-dir", "analytics", "访问统计目录（为空时不统计）")
+	analyticsFlushInterval := flag.Int("analytics-flush", 60, "访问统计保存间隔（秒）")
+	analyticsRetention := flag.Int("analytics-retention", 90, "访问统计保留天数（0 表示永久保留）")
+	countryHeader := flag.String("country-header", "", "携带客户端国家/地区代码的请求头，如 CF-IPCountry（为空时不记录）")
+	var trustedProxies stringList
+	flag.Var(&trustedProxies, "trusted-proxy", "可信反向代理地址（IP 或 CIDR），可重复指定，只有来自这些地址的请求才采用 X-Forwarded-Host")
 	flag.Parse()
 
 	// 初始化配置
 	cfg := config.GetDefaultConfig()
 	cfg.Port = *port
 	cfg.ConfigFile = *configFile
+	cfg.StoreType = *storeType
+	cfg.MaxChainLength = *maxChain
 	cfg.LogFile = *logFile
+	cfg.LogSinks = logSinks
 	cfg.LogBufferSize = *logBufferSize
 	cfg.LogFlushInterval = *logFlushInterval
+	cfg.LogOverflow = *logOverflow
+	cfg.LogFsync = *logFsync
+	cfg.LogFsyncInterval = *logFsyncInterval
+	cfg.LogSpillFile = *logSpill
+	cfg.LogMaxSize = *logMaxSize
+	cfg.LogRotateDaily = *logRotateDaily
+	cfg.LogMaxBackups = *logMaxBackups
+	cfg.LogMaxAge = *logMaxAge
+	cfg.LogCompress = *logCompress
+	cfg.AuditLogFile = *auditLogFile
+	cfg.WebhookDB = *webhookDB
+	cfg.HitsFile = *hitsFile
+	cfg.HitsFlushInterval = *hitsFlushInterval
+	cfg.AnalyticsDir = *analyticsDir
+	cfg.AnalyticsFlushInterval = *analyticsFlushInterval
+	cfg.AnalyticsRetention = *analyticsRetention
+	cfg.CountryHeader = *countryHeader
+	cfg.TrustedProxies = trustedProxies
+
+	// 打开规则存储
+	store, err := config.OpenStore(cfg.StoreType, cfg.ConfigFile)
+	if err != nil {
+		log.Fatalf("Failed to open rule store: %v\n", err)
+	}
+	cfg.SetStore(store)
+	defer func() {
+		if err := cfg.Close(); err != nil {
+			log.Printf("Failed to close rule store: %v\n", err)
+		}
+	}()
 
 	// 加载配置
-	if err := cfg.LoadFromFile(); err != nil {
-		log.Printf("Warning: Failed to load config: %v\n", err)
+	if err := cfg.Load(); err != nil {
+		logLoadError(err)
+	}
+
+	// 初始化监控指标
+	var monitor *metrics.Metrics
+	if *metricsPath != "" {
+		monitor = metrics.New()
+		monitor.SetRuleCount(func() int { return len(cfg.GetAllRules()) })
 	}
 
 	// 初始化日志
-	accessLogger, err := logger.NewLogger(cfg.LogFile, cfg.LogBufferSize, cfg.LogFlushInterval)
+	overflow, err := logger.ParseOverflowPolicy(cfg.LogOverflow)
+	if err != nil {
+		log.Fatalf("Invalid -log-overflow: %v\n", err)
+	}
+	syncPolicy, err := logger.ParseSyncPolicy(cfg.LogFsync)
 	if err != nil {
-		log.Fatalf("Failed to create logger: %v\n", err)
+		log.Fatalf("Invalid -log-fsync: %v\n", err)
+	}
+	// 未设置日志文件时不写入文件，仍可通过 -log-sink 输出到其他目标
+	var accessLogger *logger.Logger
+	if cfg.LogFile != "" {
+		accessLogger, err = logger.NewLogger(cfg.LogFile, cfg.LogBufferSize, cfg.LogFlushInterval)
+		if err != nil {
+			log.Fatalf("Failed to create logger: %v\n", err)
+		}
+	} else {
+		accessLogger = logger.New()
+	}
+	// 关闭时写完队列和缓冲中的全部日志并同步到磁盘
+	defer func() {
+		if err := accessLogger.Close(); err != nil {
+			log.Printf("Failed to close access log: %v\n", err)
+		}
+	}()
+	accessLogger.SetOverflowPolicy(overflow)
+	accessLogger.SetSyncPolicy(syncPolicy, time.Duration(cfg.LogFsyncInterval)*time.Second)
+	if err := accessLogger.SetSpillFile(cfg.LogSpillFile); err != nil {
+		log.Fatalf("Failed to open log spill file: %v\n", err)
+	}
+	accessLogger.SetMetrics(monitor, "access")
+	// 访问日志和审计日志使用相同的轮转配置
+	rotation := logger.RotateOptions{
+		MaxSize:    int64(cfg.LogMaxSize) * 1024 * 1024,
+		Daily:      cfg.LogRotateDaily,
+		MaxBackups: cfg.LogMaxBackups,
+		MaxAge:     time.Duration(cfg.LogMaxAge) * 24 * time.Hour,
+		Compress:   cfg.LogCompress,
+	}
+	accessLogger.SetRotation(rotation)
+
+	// 访问日志的其他输出目标，各自有独立的队列，一个输出目标不可用不影响其他输出目标
+	for _, spec := range cfg.LogSinks {
+		sink, err := logger.OpenSink(spec)
+		if err != nil {
+			log.Fatalf("Invalid -log-sink: %v\n", err)
+		}
+		if err := accessLogger.AddSink(sink.Name, sink.Sink, sink.Options); err != nil {
+			log.Fatalf("Invalid -log-sink: %v, set a different name with #name=\n", err)
+		}
+	}
+
+	// 初始化审计日志（每条立即写入磁盘）
+	var auditLogger *logger.Logger
+	if cfg.AuditLogFile != "" {
+		auditLogger, err = logger.NewLogger(cfg.AuditLogFile, 1, cfg.LogFlushInterval)
+		if err != nil {
+			log.Fatalf("Failed to create audit logger: %v\n", err)
+		}
+		defer func() {
+			if err := auditLogger.Close(); err != nil {
+				log.Printf("Failed to close audit log: %v\n", err)
+			}
+		}()
+		auditLogger.SetMetrics(monitor, "audit")
+		auditLogger.SetRotation(rotation)
+	}
+
+	// 收到 SIGUSR1 时重新打开日志文件（配合外部 logrotate）
+	logger.ReopenOnSignal(accessLogger, auditLogger)
+
+	// 初始化规则命中计数
+	var hitCounter *hits.Counter
+	if cfg.HitsFile != "" {
+		hitCounter, err = hits.Open(cfg.HitsFile, time.Duration(cfg.HitsFlushInterval)*time.Second)
+		if err != nil {
+			log.Fatalf("Failed to load hit counters: %v\n", err)
+		}
+		defer func() {
+			if err := hitCounter.Close(); err != nil {
+				log.Printf("Failed to save hit counters: %v\n", err)
+			}
+		}()
+	}
+
+	// 初始化访问统计
+	var stats *analytics.Aggregator
+	if cfg.AnalyticsDir != "" {
+		stats, err = analytics.Open(cfg.AnalyticsDir, time.Duration(cfg.AnalyticsFlushInterval)*time.Second, cfg.AnalyticsRetention)
+		if err != nil {
+			log.Fatalf("Failed to open analytics directory: %v\n", err)
+		}
+		defer func() {
+			if err := stats.Close(); err != nil {
+				log.Printf("Failed to save analytics: %v\n", err)
+			}
+		}()
 	}
-	defer accessLogger.Close()
 
 	// 初始化处理器
 	redirectHandler := handler.NewHandler(cfg, accessLogger)
+	redirectHandler.SetHits(hitCounter)
+	redirectHandler.SetAnalytics(stats)
+	redirectHandler.SetCountryHeader(cfg.CountryHeader)
+	redirectHandler.SetMetrics(monitor)
+
+	// 初始化认证
+	var authenticator *auth.Authenticator
+	if *authFile != "" {
+		authenticator, err = auth.NewAuthenticator(*authFile)
+		if err != nil {
+			log.Fatalf("Failed to load credentials: %v\n", err)
+		}
+	} else {
+		log.Println("Warning: Authentication is disabled, admin API and manager page are open to anyone")
+	}
 
 	// 初始化 API
-	apiHandler := api.NewAPI(cfg)
+	apiHandler := api.NewAPI(cfg, redirectHandler)
+	apiHandler.SetAuth(authenticator)
+	apiHandler.SetAuditLogger(auditLogger)
+	apiHandler.SetAccessLogger(accessLogger)
+	apiHandler.SetHits(hitCounter)
+	apiHandler.SetAnalytics(stats)
+	apiHandler.SetMetrics(monitor)
+	proxies, err := auth.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Failed to parse trusted proxies: %v\n", err)
+	}
+	apiHandler.SetTrustedProxies(proxies)
+
+	// 初始化 Webhook
+	var hooks *webhook.Dispatcher
+	if cfg.WebhookDB != "" {
+		hooks, err = webhook.NewDispatcher(cfg.WebhookDB)
+		if err != nil {
+			log.Fatalf("Failed to open webhook store: %v\n", err)
+		}
+		defer func() {
+			if err := hooks.Close(); err != nil {
+				log.Printf("Failed to close webhook store: %v\n", err)
+			}
+		}()
+		apiHandler.SetWebhooks(hooks)
+
+		// 规则过期时发送事件
+		cfg.OnExpire(func(rule *config.RedirectRule) {
+			go hooks.Emit(&webhook.Event{Type: webhook.EventRuleExpired, RuleID: rule.ID, Rule: rule})
+		})
+	}
+
+	// 定期清理过期规则，未启用 Webhook 时同样释放内存，启用时及时发送过期事件
+	go func() {
+		for range time.Tick(expirySweepInterval) {
+			cfg.SweepExpired()
+		}
+	}()
 
 	// 初始化管理页面
-	managerHandler := manager.NewManager()
+	managerHandler, err := manager.NewManager(*managerPath, *managerDir)
+	if err != nil {
+		log.Fatalf("Failed to create manager page: %v\n", err)
+	}
 
 	// 设置路由
 	router := mux.NewRouter()
 
-	// 管理页面路由
-	router.HandleFunc("/manager558630", managerHandler.ServeManager)
+	// 配置了独立管理地址时，管理页面和 API 只挂载在管理监听上，
+	// 跳转服务的 /api 和管理页面路径可以正常用于跳转
+	adminRouter := router
+	if *adminAddr != "" {
+		adminRouter = mux.NewRouter()
+	}
+
+	// 管理页面路由（未登录时显示登录页，静态资源无需登录）
+	adminRouter.Handle(managerHandler.BasePath(), authenticator.Protect(
+		http.HandlerFunc(managerHandler.ServeManager),
+		http.HandlerFunc(managerHandler.ServeLogin),
+	))
+	adminRouter.PathPrefix(managerHandler.BasePath() + "/").HandlerFunc(managerHandler.ServeAsset)
 
 	// API 路由
-	apiHandler.RegisterRoutes(router)
+	apiHandler.RegisterRoutes(adminRouter)
+
+	// 监控指标路由（启用认证时需要任意角色的凭据）
+	if monitor != nil {
+		adminRouter.Handle(*metricsPath, authenticator.Protect(
+			monitor.Handler(),
+			http.HandlerFunc(auth.Unauthorized),
+		)).Methods("GET")
+	}
+
+	// 存活、就绪检查和版本信息（在跳转路由之前注册，不会被跳转规则覆盖）
+	checker := health.NewChecker()
+	checker.Add("rules", health.RulesCheck(cfg))
+	checker.Add("access_log", accessLogger.Err)
+	if auditLogger != nil {
+		checker.Add("audit_log", auditLogger.Err)
+	}
+	checker.RegisterRoutes(router)
+	if adminRouter != router {
+		checker.RegisterRoutes(adminRouter)
+	}
 
 	// 跳转路由（所有其他请求）
 	router.PathPrefix("/").HandlerFunc(redirectHandler.HandleRedirect)
 
+	// 关闭时取消所有请求的上下文，结束实时日志流等长连接
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	baseContext := func(net.Listener) context.Context { return baseCtx }
+
+	// 启动管理服务器
+	var adminSrv *http.Server
+	if *adminAddr != "" {
+		mode, err := strconv.ParseUint(*adminSocketMode, 8, 32)
+		if err != nil {
+			log.Fatalf("Invalid admin socket mode %q: %v\n", *adminSocketMode, err)
+		}
+		adminListener, err := listenAdmin(*adminAddr, os.FileMode(mode))
+		if err != nil {
+			log.Fatalf("Failed to listen on admin address: %v\n", err)
+		}
+		adminSrv = &http.Server{Handler: adminRouter, BaseContext: baseContext}
+		adminSrv.RegisterOnShutdown(cancelRequests)
+		go func() {
+			if err := adminSrv.Serve(adminListener); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Admin server failed: %v\n", err)
+			}
+		}()
+		log.Printf("Admin API and manager listening on %s\n", *adminAddr)
+	}
+
 	// 启动服务器
 	addr := fmt.Sprintf(":%d", cfg.Port)
 	srv := &http.Server{
-		Addr:    addr,
-		Handler: router,
+		Addr:        addr,
+		Handler:     router,
+		BaseContext: baseContext,
 	}
+	srv.RegisterOnShutdown(cancelRequests)
 
-	// 优雅关闭
+	// 优雅关闭：停止接受新连接并等待进行中的请求完成，随后由 main 返回前的 defer
+	// 依次关闭 Webhook、访问统计、命中计数、审计日志、访问日志（写完全部缓冲日志）和规则存储
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 		<-sigChan
+		signal.Stop(sigChan) // 再次收到信号时立即退出
 
 		log.Println("Shutting down server...")
-
-		// 保存配置
-		if err := cfg.SaveToFile(); err != nil {
-			log.Printf("Failed to save config: %v\n", err)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		// 关闭管理监听（unix socket 会随之删除）
+		if adminSrv != nil {
+			if err := adminSrv.Shutdown(ctx); err != nil {
+				log.Printf("Failed to shut down admin server: %v\n", err)
+			}
 		}
-
-		// 刷新日志
-		if err := accessLogger.Flush(); err != nil {
-			log.Printf("Failed to flush logs: %v\n", err)
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Failed to shut down server: %v\n", err)
 		}
-
-		os.Exit(0)
 	}()
 
-	log.Printf("MiniJump HTTP Redirect Service starting on port %d\n", cfg.Port)
-	log.Printf("Config file: %s\n", cfg.ConfigFile)
-	log.Printf("Log file: %s\n", cfg.LogFile)
+	log.Printf("MiniJump HTTP Redirect Service %s starting on port %d\n", health.Version, cfg.Port)
+	log.Printf("Config file: %s (%s)\n", cfg.ConfigFile, cfg.StoreType)
+	log.Printf("Manager page: %s\n", managerHandler.BasePath())
+	if monitor != nil {
+		log.Printf("Metrics: %s\n", *metricsPath)
+	}
+	if cfg.LogFile == "" && len(cfg.LogSinks) == 0 {
+		log.Println("Warning: Access log is disabled")
+	} else {
+		log.Printf("Access log: %s\n", accessLogger)
+	}
+	if cfg.AuditLogFile != "" {
+		log.Printf("Audit log file: %s\n", cfg.AuditLogFile)
+	}
 
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Server failed: %v\n", err)
 	}
+	<-shutdownDone
+
+	// 保存配置
+	if err := cfg.Save(); err != nil {
+		log.Printf("Failed to save config: %v\n", err)
+	}
+	log.Println("Server stopped")
+}
+
+// listenAdmin 监听管理地址，支持 TCP 地址和 unix:/path 形式的 unix socket
+func listenAdmin(addr string, mode os.FileMode) (net.Listener, error) {
+	path, isUnix := strings.CutPrefix(addr, "unix:")
+	if !isUnix {
+		return net.Listen("tcp", addr)
+	}
+
+	// 清理上次异常退出残留的 socket 文件
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
 }
 
 // handleInstall 处理安装服务命令
@@ -128,9 +493,36 @@ func handleInstall() {
 	installFlags := flag.NewFlagSet("install", flag.ExitOnError)
 	port := installFlags.Int("port", 8080, "服务端口")
 	configFile := installFlags.String("config", "rules.json", "配置文件路径")
+	storeType := installFlags.String("store", config.StoreJSON, "规则存储类型（json/bolt）")
+	maxChain := installFlags.Int("max-chain", 3, "跳转链最大长度")
+	authFile := installFlags.String("auth-file", "", "认证凭据文件路径")
+	adminAddr := installFlags.String("admin-addr", "", "管理接口独立监听地址")
+	adminSocketMode := installFlags.String("admin-socket-mode", "0660", "管理接口 unix socket 文件权限")
+	managerPath := installFlags.String("manager-path", manager.DefaultPath, "管理页面路径")
+	managerDir := installFlags.String("manager-dir", "", "管理页面静态文件目录")
+	metricsPath := installFlags.String("metrics-path", "/metrics", "Prometheus 监控指标路径")
 	logFile := installFlags.String("log", "access.log", "日志文件路径")
-	logBufferSize := installFlags.Int("log-buffer", 1000, "日志缓冲大小")
+	var logSinks stringList
+	installFlags.Var(&logSinks, "log-sink", "访问日志的其他输出目标，可重复指定")
+	logBufferSize := installFlags.Int("log-buffer", 1000, "日志队列长度")
+	logOverflow := installFlags.String("log-overflow", "block", "日志队列已满时的处理策略")
+	logFsync := installFlags.String("log-fsync", "none", "日志同步到磁盘的策略")
+	logFsyncInterval := installFlags.Int("log-fsync-interval", 1, "按间隔同步时的同步间隔（秒）")
+	logSpill := installFlags.String("log-spill", "", "日志文件写入失败时的溢出文件路径")
 	logFlushInterval := installFlags.Int("log-flush", 180, "日志刷新间隔（秒）")
+	logMaxSize := installFlags.Int("log-max-size", 0, "访问日志和审计日志轮转大小（MB）")
+	logRotateDaily := installFlags.Bool("log-daily", false, "访问日志和审计日志每天轮转")
+	logMaxBackups := installFlags.Int("log-max-backups", 0, "最多保留的轮转文件数")
+	logMaxAge := installFlags.Int("log-max-age", 0, "轮转文件保留天数")
+	logCompress := installFlags.Bool("log-compress", true, "轮转后 gzip 压缩")
+	auditLogFile := installFlags.String("audit-log", "audit.log", "审计日志文件路径")
+	webhookDB := installFlags.String("webhook-db", "", "Webhook 存储文件路径")
+	hitsFile := installFlags.String("hits-file", "hits.json", "规则命中统计文件路径")
+	hitsFlushInterval := installFlags.Int("hits-flush", 60, "命中统计保存间隔（秒）")
+	analyticsDir := installFlags.String("analytics-dir", "analytics", "访问统计目录")
+	analyticsFlushInterval := installFlags.Int("analytics-flush", 60, "访问统计保存间隔（秒）")
+	analyticsRetention := installFlags.Int("analytics-retention", 90, "访问统计保留天数")
+	countryHeader := installFlags.String("country-header", "", "携带客户端国家/地区代码的请求头")
 	serviceName := installFlags.String("name", "MiniJump", "服务名称")
 	installFlags.Parse(os.Args[2:])
 
@@ -142,6 +534,30 @@ func handleInstall() {
 	if *configFile != "rules.json" {
 		args = append(args, fmt.Sprintf("-config=%s", *configFile))
 	}
+	if *storeType != config.StoreJSON {
+		args = append(args, fmt.Sprintf("-store=%s", *storeType))
+	}
+	if *maxChain != 3 {
+		args = append(args, fmt.Sprintf("-max-chain=%d", *maxChain))
+	}
+	if *authFile != "" {
+		args = append(args, fmt.Sprintf("-auth-file=%s", *authFile))
+	}
+	if *adminAddr != "" {
+		args = append(args, fmt.Sprintf("-admin-addr=%s", *adminAddr))
+	}
+	if *adminSocketMode != "0660" {
+		args = append(args, fmt.Sprintf("-admin-socket-mode=%s", *adminSocketMode))
+	}
+	if *managerPath != manager.DefaultPath {
+		args = append(args, fmt.Sprintf("-manager-path=%s", *managerPath))
+	}
+	if *managerDir != "" {
+		args = append(args, fmt.Sprintf("-manager-dir=%s", *managerDir))
+	}
+	if *metricsPath != "/metrics" {
+		args = append(args, fmt.Sprintf("-metrics-path=%s", *metricsPath))
+	}
 	if *logFile != "access.log" {
 		args = append(args, fmt.Sprintf("-log=%s", *logFile))
 	}
@@ -151,6 +567,60 @@ func handleInstall() {
 	if *logFlushInterval != 180 {
 		args = append(args, fmt.Sprintf("-log-flush=%d", *logFlushInterval))
 	}
+	if *logOverflow != "block" {
+		args = append(args, fmt.Sprintf("-log-overflow=%s", *logOverflow))
+	}
+	if *logFsync != "none" {
+		args = append(args, fmt.Sprintf("-log-fsync=%s", *logFsync))
+	}
+	if *logFsyncInterval != 1 {
+		args = append(args, fmt.Sprintf("-log-fsync-interval=%d", *logFsyncInterval))
+	}
+	if *logSpill != "" {
+		args = append(args, fmt.Sprintf("-log-spill=%s", *logSpill))
+	}
+	if *logMaxSize != 0 {
+		args = append(args, fmt.Sprintf("-log-max-size=%d", *logMaxSize))
+	}
+	if *logRotateDaily {
+		args = append(args, "-log-daily")
+	}
+	if *logMaxBackups != 0 {
+		args = append(args, fmt.Sprintf("-log-max-backups=%d", *logMaxBackups))
+	}
+	if *logMaxAge != 0 {
+		args = append(args, fmt.Sprintf("-log-max-age=%d", *logMaxAge))
+	}
+	if !*logCompress {
+		args = append(args, "-log-compress=false")
+	}
+	if *auditLogFile != "audit.log" {
+		args = append(args, fmt.Sprintf("-audit-log=%s", *auditLogFile))
+	}
+	if *webhookDB != "" {
+		args = append(args, fmt.Sprintf("-webhook-db=%s", *webhookDB))
+	}
+	if *hitsFile != "hits.json" {
+		args = append(args, fmt.Sprintf("-hits-file=%s", *hitsFile))
+	}
+	if *hitsFlushInterval != 60 {
+		args = append(args, fmt.Sprintf("-hits-flush=%d", *hitsFlushInterval))
+	}
+	if *analyticsDir != "analytics" {
+		args = append(args, fmt.Sprintf("-analytics-dir=%s", *analyticsDir))
+	}
+	if *analyticsFlushInterval != 60 {
+		args = append(args, fmt.Sprintf("-analytics-flush=%d", *analyticsFlushInterval))
+	}
+	if *analyticsRetention != 90 {
+		args = append(args, fmt.Sprintf("-analytics-retention=%d", *analyticsRetention))
+	}
+	if *countryHeader != "" {
+		args = append(args, fmt.Sprintf("-country-header=%s", *countryHeader))
+	}
+	for _, spec := range logSinks {
+		args = append(args, fmt.Sprintf("-log-sink=%s", spec))
+	}
 
 	// 检查权限
 	if runtime.GOOS == "windows" {
@@ -227,6 +697,112 @@ func handleUninstall() {
 	}
 }
 
+// handleMigrate 处理存储迁移命令
+func handleMigrate() {
+	migrateFlags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fromType := migrateFlags.String("from-store", config.StoreJSON, "源存储类型（json/bolt）")
+	fromPath := migrateFlags.String("from", "rules.json", "源存储文件路径")
+	toType := migrateFlags.String("to-store", config.StoreBolt, "目标存储类型（json/bolt）")
+	toPath := migrateFlags.String("to", "rules.db", "目标存储文件路径")
+	migrateFlags.Parse(os.Args[2:])
+
+	if *fromPath == *toPath {
+		fmt.Println("错误: 源存储和目标存储不能是同一个文件")
+		os.Exit(1)
+	}
+
+	src, err := config.OpenStore(*fromType, *fromPath)
+	if err != nil {
+		fmt.Printf("错误: 打开源存储失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer src.Close()
+
+	dst, err := config.OpenStore(*toType, *toPath)
+	if err != nil {
+		fmt.Printf("错误: 打开目标存储失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer dst.Close()
+
+	count, err := config.Migrate(src, dst)
+	var partial *config.LoadError
+	if errors.As(err, &partial) {
+		for _, item := range partial.Items {
+			fmt.Printf("警告: 跳过无效规则 %s: %s\n", item.Location(), item.Message)
+		}
+	} else if err != nil {
+		fmt.Printf("错误: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("迁移完成: %d 条规则 %s(%s) -> %s(%s)\n", count, *fromPath, *fromType, *toPath, *toType)
+}
+
+// handleHashPassword 生成密码的 bcrypt 哈希，用于写入凭据文件
+func handleHashPassword() {
+	var password string
+	if len(os.Args) > 2 {
+		password = os.Args[2]
+	} else {
+		fmt.Print("请输入密码: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Printf("错误: 读取密码失败: %v\n", err)
+			os.Exit(1)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		fmt.Println("错误: 密码不能为空")
+		os.Exit(1)
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		fmt.Printf("错误: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(hash)
+}
+
+// handleVersion 输出构建信息
+func handleVersion() {
+	info := health.GetBuildInfo()
+	fmt.Printf("MiniJump %s\n", info.Version)
+	if info.Commit != "" {
+		fmt.Printf("Commit:     %s\n", info.Commit)
+	}
+	if info.BuildTime != "" {
+		fmt.Printf("Build time: %s\n", info.BuildTime)
+	}
+	fmt.Printf("Go:         %s %s\n", info.GoVersion, info.Platform)
+}
+
+// handleGenToken 生成随机 API Token 及其哈希
+func handleGenToken() {
+	token := auth.GenerateToken()
+	fmt.Printf("Token: %s\n", token)
+	fmt.Printf("Hash:  %s\n", auth.HashToken(token))
+	fmt.Println("请将 Hash 写入凭据文件，Token 仅显示一次")
+}
+
+// logLoadError 输出规则加载错误，无效规则逐条列出位置
+func logLoadError(err error) {
+	var partial *config.LoadError
+	if !errors.As(err, &partial) {
+		log.Printf("Warning: Failed to load config: %v\n", err)
+		return
+	}
+	for _, item := range partial.Items {
+		if len(item.Fields) > 0 {
+			log.Printf("Warning: Skipped invalid rule at %s in %s: %v\n", item.Location(), partial.Source, item.Fields)
+		} else {
+			log.Printf("Warning: Skipped invalid rule at %s in %s: %s\n", item.Location(), partial.Source, item.Message)
+		}
+	}
+}
+
 // isAdminWindows 检查是否有 Windows 管理员权限
 func isAdminWindows() bool {
 	if runtime.GOOS != "windows" {
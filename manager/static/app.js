@@ -0,0 +1,696 @@
+let currentEditingId = null;
+// 正在编辑的规则版本（保存时通过 If-Match 校验）
+let currentETag = '';
+
+// 分页状态：cursors 为已访问页的游标栈，最后一个为当前页
+let pageCursors = [''];
+let nextCursor = '';
+
+// 当前会话的 CSRF Token（由页面模板写入）
+const csrfToken = document.querySelector('meta[name="csrf-token"]').content;
+
+// 调用管理 API，修改类请求自动携带 CSRF Token
+function apiFetch(url, options = {}) {
+    const headers = Object.assign({}, options.headers);
+    if (csrfToken) {
+        headers['X-CSRF-Token'] = csrfToken;
+    }
+    return fetch(url, Object.assign({}, options, {headers: headers, credentials: 'same-origin'}));
+}
+
+// 创建元素，文本内容一律通过 textContent 写入，避免 XSS
+function el(tag, className, text) {
+    const node = document.createElement(tag);
+    if (className) node.className = className;
+    if (text !== undefined && text !== null) node.textContent = text;
+    return node;
+}
+
+// 根据筛选条件构造查询参数
+function ruleQuery() {
+    const params = new URLSearchParams();
+    const fields = {q: 'filter-q', domain: 'filter-domain', type: 'filter-type', state: 'filter-state', sort: 'filter-sort', limit: 'page-size'};
+    Object.keys(fields).forEach(name => {
+        const value = document.getElementById(fields[name]).value.trim();
+        if (value) params.set(name, value);
+    });
+    const cursor = pageCursors[pageCursors.length - 1];
+    if (cursor) params.set('cursor', cursor);
+    return params.toString();
+}
+
+// 从第一页重新加载
+function reloadFirstPage() {
+    pageCursors = [''];
+    loadRules();
+}
+
+// 加载规则列表（当前页）
+async function loadRules() {
+    try {
+        const response = await apiFetch('/api/rules?' + ruleQuery());
+        if (response.status === 401) {
+            // 会话过期，刷新页面进入登录页
+            window.location.reload();
+            return;
+        }
+        const page = await response.json();
+        if (!response.ok) {
+            const fields = (page.fields || []).map(f => f.field + ': ' + f.message).join('; ');
+            throw new Error(fields || page.error || '加载失败');
+        }
+        nextCursor = page.next_cursor || '';
+        renderRules(page.rules);
+        renderPager(page);
+    } catch (error) {
+        showAlert('加载规则失败: ' + error.message, 'error');
+    }
+}
+
+// 渲染规则列表
+function renderRules(rules) {
+    const tbody = document.getElementById('rules-tbody');
+    tbody.replaceChildren();
+    if (rules.length === 0) {
+        const row = el('tr');
+        const cell = el('td', 'empty-row', '暂无规则');
+        cell.colSpan = 9;
+        row.appendChild(cell);
+        tbody.appendChild(row);
+        return;
+    }
+    const typeNames = {301: '301', 302: '302', 307: '307', 4: 'JS'};
+    rules.forEach(rule => {
+        const expiresAt = rule.expires_at ? new Date(rule.expires_at).toLocaleString('zh-CN') : '永不过期';
+        const isExpired = rule.expires_at && new Date(rule.expires_at) < new Date();
+        const row = el('tr', isExpired ? 'status-expired' : '');
+
+        row.appendChild(el('td', '', rule.id));
+        row.appendChild(el('td', '', rule.domain));
+        const pathCell = el('td');
+        pathCell.appendChild(rule.path ? document.createTextNode(rule.path) : el('span', 'muted', '（域名级别）'));
+        row.appendChild(pathCell);
+        row.appendChild(el('td', '', rule.target));
+        const typeCell = el('td');
+        typeCell.appendChild(el('span', 'badge badge-' + Number(rule.type), typeNames[rule.type] || rule.type));
+        row.appendChild(typeCell);
+        row.appendChild(el('td', '', expiresAt));
+        row.appendChild(hitsCell(rule, row));
+        row.appendChild(el('td', '', rule.description || '-'));
+
+        const actions = el('td');
+        const editBtn = el('button', 'btn btn-primary btn-small', '编辑');
+        editBtn.addEventListener('click', () => editRule(rule.id));
+        const deleteBtn = el('button', 'btn btn-danger btn-small', '删除');
+        deleteBtn.addEventListener('click', () => deleteRule(rule));
+        actions.append(editBtn, ' ', deleteBtn);
+        row.appendChild(actions);
+
+        tbody.appendChild(row);
+    });
+}
+
+// 命中统计单元格：累计次数、最后命中时间，超过指定天数未命中时标记
+function hitsCell(rule, row) {
+    const cell = el('td');
+    if (!rule.hits) {
+        cell.textContent = '-';
+        return cell;
+    }
+    cell.appendChild(document.createTextNode(rule.hits.total));
+    const lastHit = rule.hits.last_hit_at
+        ? '最后 ' + new Date(rule.hits.last_hit_at).toLocaleString('zh-CN')
+        : '从未命中';
+    cell.appendChild(el('div', 'muted', lastHit));
+
+    // 从未命中的规则从创建时间算起
+    const days = parseInt(document.getElementById('unused-days').value) || 0;
+    const since = new Date(rule.hits.last_hit_at || rule.created_at);
+    if (days > 0 && Date.now() - since.getTime() > days * 86400000) {
+        row.classList.add('status-unused');
+        cell.appendChild(el('span', 'badge badge-unused', '超过 ' + days + ' 天未使用'));
+    }
+    return cell;
+}
+
+// 渲染分页信息
+function renderPager(page) {
+    const size = parseInt(document.getElementById('page-size').value);
+    const first = (pageCursors.length - 1) * size + 1;
+    const last = first + page.rules.length - 1;
+    document.getElementById('page-info').textContent = page.total === 0
+        ? '共 0 条'
+        : '第 ' + first + '-' + last + ' 条，共 ' + page.total + ' 条';
+    document.getElementById('prev-page').disabled = pageCursors.length <= 1;
+    document.getElementById('next-page').disabled = !nextCursor;
+}
+
+// 上一页
+function prevPage() {
+    if (pageCursors.length <= 1) return;
+    pageCursors.pop();
+    loadRules();
+}
+
+// 下一页
+function nextPage() {
+    if (!nextCursor) return;
+    pageCursors.push(nextCursor);
+    loadRules();
+}
+
+// 打开创建模态框
+function openCreateModal() {
+    currentEditingId = null;
+    document.getElementById('modal-title').textContent = '添加规则';
+    document.getElementById('rule-form').reset();
+    document.getElementById('rule-id').value = '';
+    document.getElementById('modal-alert').replaceChildren();
+    document.getElementById('rule-modal').classList.add('active');
+}
+
+// 编辑规则
+async function editRule(id) {
+    try {
+        const response = await apiFetch('/api/rules/' + encodeURIComponent(id));
+        if (!response.ok) throw new Error('加载失败');
+        const rule = await response.json();
+        
+        currentEditingId = id;
+        document.getElementById('modal-title').textContent = '编辑规则';
+        fillRuleForm(rule, response.headers.get('ETag'));
+        document.getElementById('modal-alert').replaceChildren();
+        document.getElementById('rule-modal').classList.add('active');
+    } catch (error) {
+        showAlert('加载规则失败: ' + error.message, 'error');
+    }
+}
+
+// 将规则填入编辑表单
+function fillRuleForm(rule, etag) {
+    currentETag = etag || ruleETag(rule);
+    document.getElementById('rule-id').value = rule.id;
+    document.getElementById('rule-domain').value = rule.domain;
+    document.getElementById('rule-path').value = rule.path || '';
+    document.getElementById('rule-target').value = rule.target;
+    document.getElementById('rule-type').value = rule.type;
+    document.getElementById('rule-description').value = rule.description || '';
+
+    if (rule.expires_at) {
+        const date = new Date(rule.expires_at);
+        const localDate = new Date(date.getTime() - date.getTimezoneOffset() * 60000);
+        document.getElementById('rule-expires').value = localDate.toISOString().slice(0, 16);
+    } else {
+        document.getElementById('rule-expires').value = '';
+    }
+}
+
+// 规则修订号对应的 ETag
+function ruleETag(rule) {
+    return '"' + rule.revision + '"';
+}
+
+// 保存规则
+async function saveRule(event) {
+    event.preventDefault();
+    const alertDiv = document.getElementById('modal-alert');
+    alertDiv.replaceChildren();
+
+    const ruleData = {
+        domain: document.getElementById('rule-domain').value.trim(),
+        path: document.getElementById('rule-path').value.trim(),
+        target: document.getElementById('rule-target').value.trim(),
+        type: parseInt(document.getElementById('rule-type').value),
+        description: document.getElementById('rule-description').value.trim()
+    };
+
+    // 有效期为空时显式传 null，PATCH 时表示清除过期时间
+    const expiresValue = document.getElementById('rule-expires').value;
+    ruleData.expires_at = expiresValue ? new Date(expiresValue).toISOString() : null;
+
+    try {
+        let response;
+        if (currentEditingId) {
+            // 只提交表单中的字段，其他字段（如创建时间）由服务端保留
+            response = await apiFetch('/api/rules/' + encodeURIComponent(currentEditingId), {
+                method: 'PATCH',
+                headers: {'Content-Type': 'application/merge-patch+json', 'If-Match': currentETag},
+                body: JSON.stringify(ruleData)
+            });
+        } else {
+            response = await apiFetch('/api/rules', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify(ruleData)
+            });
+        }
+
+        const result = await response.json();
+        
+        if (response.status === 412) {
+            // 编辑期间规则已被其他人修改
+            showStaleDialog({
+                hint: '你编辑期间，这条规则已被其他人修改。请对比后选择：',
+                mineTitle: '你的修改',
+                currentLabel: '使用当前版本',
+                forceLabel: '覆盖保存',
+                mine: ruleData,
+                current: result.current,
+                onForce: () => {
+                    currentETag = ruleETag(result.current);
+                    document.getElementById('rule-form').requestSubmit();
+                },
+                onUseCurrent: () => {
+                    fillRuleForm(result.current);
+                    alertDiv.replaceChildren(el('div', 'alert alert-warning', '已载入最新版本，你的修改已丢弃'));
+                }
+            });
+            return;
+        }
+        if (!response.ok) {
+            const alert = el('div', 'alert alert-error');
+            if (response.status === 409) {
+                // 冲突错误
+                alert.appendChild(el('strong', '', '规则冲突！'));
+                alert.appendChild(el('br'));
+                alert.appendChild(document.createTextNode(result.error));
+                alertDiv.appendChild(alert);
+                if (result.conflicts && result.conflicts.length > 0) {
+                    const list = el('div', 'conflict-list');
+                    list.appendChild(el('strong', '', '冲突的规则：'));
+                    result.conflicts.forEach(conflict => {
+                        const conflictPath = conflict.path || '(域名级别)';
+                        list.appendChild(el('div', 'conflict-item',
+                            'ID: ' + conflict.id + ', 域名: ' + conflict.domain + ', 路径: ' + conflictPath));
+                    });
+                    alertDiv.appendChild(list);
+                }
+            } else if (result.fields && result.fields.length > 0) {
+                // 字段校验错误
+                alert.appendChild(el('strong', '', result.error));
+                result.fields.forEach(fieldError => {
+                    alert.appendChild(el('br'));
+                    alert.appendChild(document.createTextNode(fieldError.field + ': ' + fieldError.message));
+                });
+                alertDiv.appendChild(alert);
+            } else {
+                alert.textContent = '保存失败: ' + (result.error || '未知错误');
+                alertDiv.appendChild(alert);
+            }
+            return;
+        }
+
+        closeModal();
+        const warning = response.headers.get('Warning');
+        if (warning) {
+            showAlert('规则保存成功，但跳转链过长: ' + warning, 'error');
+        } else {
+            showAlert('规则保存成功', 'success');
+        }
+        loadRules();
+    } catch (error) {
+        alertDiv.replaceChildren(el('div', 'alert alert-error', '保存失败: ' + error.message));
+    }
+}
+
+// 删除规则（rule 为列表中显示的版本，confirmed 为 true 时不再确认）
+async function deleteRule(rule, confirmed) {
+    if (!confirmed && !confirm('确定要删除这条规则吗？')) return;
+    
+    try {
+        const response = await apiFetch('/api/rules/' + encodeURIComponent(rule.id), {
+            method: 'DELETE',
+            headers: {'If-Match': ruleETag(rule)}
+        });
+        if (response.status === 412) {
+            const result = await response.json();
+            showStaleDialog({
+                hint: '页面加载后，这条规则已被其他人修改。请确认是否仍要删除：',
+                mineTitle: '页面上的版本',
+                currentLabel: '刷新列表',
+                forceLabel: '仍然删除',
+                mine: rule,
+                current: result.current,
+                onForce: () => deleteRule(result.current, true),
+                onUseCurrent: loadRules
+            });
+            return;
+        }
+        if (!response.ok) throw new Error('删除失败');
+        showAlert('规则删除成功', 'success');
+        loadRules();
+    } catch (error) {
+        showAlert('删除失败: ' + error.message, 'error');
+    }
+}
+
+// 关闭模态框
+function closeModal() {
+    document.getElementById('rule-modal').classList.remove('active');
+}
+
+// 版本对比时显示的字段
+const diffFields = [
+    ['domain', '域名'],
+    ['path', '路径'],
+    ['target', '目标URL'],
+    ['type', '类型'],
+    ['expires_at', '有效期'],
+    ['description', '描述']
+];
+
+// 格式化字段值用于对比
+function formatField(rule, field) {
+    const value = rule[field];
+    if (field === 'type') {
+        return {301: '301', 302: '302', 307: '307', 4: 'JS'}[value] || String(value);
+    }
+    if (field === 'expires_at') {
+        return value ? new Date(value).toLocaleString('zh-CN') : '永不过期';
+    }
+    return value || '';
+}
+
+// 版本冲突对话框中各按钮的回调
+let staleActions = {};
+
+// 显示版本冲突对话框，逐字段对比本地版本（mine）与服务端当前版本（current）
+function showStaleDialog(options) {
+    staleActions = options;
+    document.getElementById('stale-hint').textContent = options.hint;
+    document.getElementById('stale-mine-title').textContent = options.mineTitle;
+    document.getElementById('stale-current-btn').textContent = options.currentLabel;
+    document.getElementById('stale-force-btn').textContent = options.forceLabel;
+
+    const tbody = document.getElementById('stale-diff');
+    tbody.replaceChildren();
+    diffFields.forEach(([field, label]) => {
+        const mine = formatField(options.mine, field);
+        const current = formatField(options.current, field);
+        const row = el('tr', mine === current ? '' : 'diff-changed');
+        row.append(el('td', '', label), el('td', '', mine), el('td', '', current));
+        tbody.appendChild(row);
+    });
+    document.getElementById('stale-modal').classList.add('active');
+}
+
+// 关闭版本冲突对话框并执行所选操作
+function closeStaleDialog(action) {
+    document.getElementById('stale-modal').classList.remove('active');
+    if (action) action();
+}
+
+// 重新加载配置
+async function reloadConfig() {
+    try {
+        const response = await apiFetch('/api/reload', {method: 'POST'});
+        if (!response.ok) throw new Error('重新加载失败');
+        showAlert('配置重新加载成功', 'success');
+        reloadFirstPage();
+    } catch (error) {
+        showAlert('重新加载失败: ' + error.message, 'error');
+    }
+}
+
+// 保存配置
+async function saveConfig() {
+    try {
+        const response = await apiFetch('/api/save', {method: 'POST'});
+        if (!response.ok) throw new Error('保存失败');
+        showAlert('配置保存成功', 'success');
+    } catch (error) {
+        showAlert('保存失败: ' + error.message, 'error');
+    }
+}
+
+// 测试 URL 由哪条规则处理
+async function testURL() {
+    const resultDiv = document.getElementById('test-result');
+    const testData = {
+        url: document.getElementById('test-url').value.trim(),
+        client_ip: document.getElementById('test-ip').value.trim()
+    };
+
+    try {
+        const response = await apiFetch('/api/test', {
+            method: 'POST',
+            headers: {'Content-Type': 'application/json'},
+            body: JSON.stringify(testData)
+        });
+        const result = await response.json();
+        if (!response.ok) {
+            const fields = (result.fields || []).map(f => f.field + ': ' + f.message).join('; ');
+            resultDiv.textContent = '测试失败: ' + (fields || result.error || '未知错误');
+            return;
+        }
+
+        const lines = [];
+        if (result.rule) {
+            lines.push('命中规则: ' + result.rule.id + '（' + result.status_code + ' → ' + result.target + '）');
+        } else {
+            lines.push('未命中任何规则（' + result.status_code + '）');
+        }
+        lines.push('客户端 IP: ' + result.client_ip);
+        (result.candidates || []).forEach(c => {
+            lines.push((c.matched ? '✔ ' : '✘ ') + c.key + ' [' + c.level + ']: ' + c.reason);
+        });
+        resultDiv.textContent = lines.join('\n');
+    } catch (error) {
+        resultDiv.textContent = '测试失败: ' + error.message;
+    }
+}
+
+// 访问统计的时间范围：查询时长和时间粒度
+const statsRanges = {
+    '24h': {hours: 24, interval: 'hour'},
+    '7d': {hours: 24 * 7, interval: 'day'},
+    '30d': {hours: 24 * 30, interval: 'day'}
+};
+
+// 创建 SVG 元素
+function svgEl(tag, attrs, text) {
+    const node = document.createElementNS('http://www.w3.org/2000/svg', tag);
+    Object.keys(attrs || {}).forEach(name => node.setAttribute(name, attrs[name]));
+    if (text !== undefined) node.textContent = text;
+    return node;
+}
+
+// 查询访问统计
+async function fetchStats(params) {
+    const response = await apiFetch('/api/stats?' + new URLSearchParams(params).toString());
+    const result = await response.json();
+    if (!response.ok) {
+        const fields = (result.fields || []).map(f => f.field + ': ' + f.message).join('; ');
+        throw new Error(fields || result.error || '查询失败');
+    }
+    return result;
+}
+
+// 加载访问统计图表
+async function loadStats() {
+    const range = statsRanges[document.getElementById('stats-range').value];
+    const from = new Date(Date.now() - range.hours * 3600000).toISOString();
+    try {
+        const [traffic, topRules] = await Promise.all([
+            fetchStats({from: from, interval: range.interval}),
+            fetchStats({from: from, group_by: 'rule', limit: 10})
+        ]);
+        document.getElementById('stats-total').textContent = '共 ' + traffic.total + ' 次访问';
+        renderTrafficChart(traffic.rows, range.interval);
+        renderTopRulesChart(topRules.rows);
+    } catch (error) {
+        // 未启用统计时隐藏面板
+        document.querySelector('.stats-box').classList.add('hidden');
+    }
+}
+
+// 绘制访问量趋势柱状图
+function renderTrafficChart(rows, interval) {
+    const chart = document.getElementById('traffic-chart');
+    const width = 600, height = 180, bottom = 20;
+    chart.setAttribute('viewBox', '0 0 ' + width + ' ' + height);
+    chart.replaceChildren();
+    const max = Math.max(1, ...rows.map(row => row.hits));
+    const step = width / Math.max(rows.length, 1);
+    const labelEvery = Math.ceil(rows.length / 8);
+    rows.forEach((row, i) => {
+        const time = new Date(row.time);
+        const label = interval === 'hour'
+            ? time.getHours() + ':00'
+            : (time.getMonth() + 1) + '/' + time.getDate();
+        const barHeight = (height - bottom - 10) * row.hits / max;
+        const bar = svgEl('rect', {
+            class: 'chart-bar',
+            x: i * step + 1,
+            y: height - bottom - barHeight,
+            width: Math.max(step - 2, 1),
+            height: barHeight
+        });
+        bar.appendChild(svgEl('title', {}, time.toLocaleString('zh-CN') + '：' + row.hits + ' 次'));
+        chart.appendChild(bar);
+        if (i % labelEvery === 0) {
+            chart.appendChild(svgEl('text', {x: i * step + 1, y: height - 5}, label));
+        }
+    });
+    chart.appendChild(svgEl('line', {class: 'chart-axis', x1: 0, x2: width, y1: height - bottom, y2: height - bottom}));
+}
+
+// 绘制访问最多的规则条形图
+function renderTopRulesChart(rows) {
+    const chart = document.getElementById('top-rules-chart');
+    const width = 600, rowHeight = 18, labelWidth = 160;
+    const height = Math.max(rows.length, 1) * rowHeight;
+    chart.setAttribute('viewBox', '0 0 ' + width + ' ' + height);
+    chart.replaceChildren();
+    if (rows.length === 0) {
+        chart.appendChild(svgEl('text', {x: 0, y: 13}, '暂无访问'));
+        return;
+    }
+    const max = Math.max(1, ...rows.map(row => row.hits));
+    rows.forEach((row, i) => {
+        const y = i * rowHeight;
+        const barWidth = (width - labelWidth - 60) * row.hits / max;
+        chart.appendChild(svgEl('text', {x: 0, y: y + 13}, row.dimensions.rule));
+        const bar = svgEl('rect', {class: 'chart-bar', x: labelWidth, y: y + 3, width: Math.max(barWidth, 1), height: rowHeight - 6});
+        bar.appendChild(svgEl('title', {}, row.dimensions.rule + '：' + row.hits + ' 次'));
+        chart.appendChild(bar);
+        chart.appendChild(svgEl('text', {x: labelWidth + barWidth + 6, y: y + 13}, row.hits));
+    });
+}
+
+// 实时访问日志：最多保留的行数
+const liveMaxRows = 200;
+let liveSource = null;
+
+// 开始或停止接收实时访问日志
+function toggleLive(event) {
+    event.preventDefault();
+    if (liveSource) {
+        stopLive('已停止');
+        return;
+    }
+
+    const params = new URLSearchParams();
+    const fields = {domain: 'live-domain', rule_id: 'live-rule', status: 'live-code'};
+    Object.keys(fields).forEach(name => {
+        const value = document.getElementById(fields[name]).value.trim();
+        if (value) params.set(name, value);
+    });
+    const state = document.getElementById('live-state');
+    liveSource = new EventSource('/api/live?' + params.toString(), {withCredentials: true});
+    liveSource.onopen = () => { state.textContent = '接收中'; };
+    liveSource.onerror = () => {
+        // 连接断开时浏览器会自动重连，请求被拒绝（如参数错误）时不再重连
+        if (liveSource.readyState === EventSource.CLOSED) {
+            stopLive('连接失败，请检查筛选条件');
+        } else {
+            state.textContent = '连接断开，正在重连...';
+        }
+    };
+    liveSource.addEventListener('access', e => appendLive(JSON.parse(e.data)));
+    liveSource.addEventListener('dropped', e => {
+        state.textContent = '接收中（处理不及时，已丢弃 ' + e.data + ' 条）';
+    });
+    document.getElementById('live-btn').textContent = '停止';
+}
+
+// 停止接收实时访问日志
+function stopLive(message) {
+    if (liveSource) {
+        liveSource.close();
+        liveSource = null;
+    }
+    document.getElementById('live-btn').textContent = '开始';
+    document.getElementById('live-state').textContent = message;
+}
+
+// 在实时访问表格顶部插入一行，超出上限时移除最旧的行
+function appendLive(entry) {
+    const tbody = document.getElementById('live-tbody');
+    const row = el('tr');
+    row.appendChild(el('td', '', new Date(entry.timestamp).toLocaleTimeString('zh-CN')));
+    row.appendChild(el('td', '', entry.ip));
+    row.appendChild(el('td', '', entry.domain));
+    row.appendChild(el('td', '', entry.path));
+    row.appendChild(el('td', '', entry.rule_id));
+    row.appendChild(el('td', '', entry.status_code));
+    row.appendChild(el('td', '', entry.target));
+    row.appendChild(el('td', '', entry.referer || '-'));
+    tbody.insertBefore(row, tbody.firstChild);
+    while (tbody.children.length > liveMaxRows) {
+        tbody.lastChild.remove();
+    }
+}
+
+// 显示提示信息
+function showAlert(message, type) {
+    const container = document.getElementById('alert-container');
+    const alert = document.createElement('div');
+    alert.className = 'alert alert-' + type;
+    alert.textContent = message;
+    container.replaceChildren(alert);
+    setTimeout(() => {
+        alert.remove();
+    }, 3000);
+}
+
+// 点击模态框外部关闭
+document.getElementById('rule-modal').addEventListener('click', function(e) {
+    if (e.target === this) {
+        closeModal();
+    }
+});
+
+// 显示当前登录用户
+async function loadSession() {
+    try {
+        const response = await apiFetch('/api/me');
+        if (!response.ok) return;
+        const me = await response.json();
+        if (me.method === 'session') {
+            const btn = document.getElementById('logout-btn');
+            btn.textContent = '退出登录（' + me.name + '）';
+            btn.classList.remove('hidden');
+        }
+    } catch (error) {
+        // 忽略
+    }
+}
+
+// 退出登录
+async function logout() {
+    await apiFetch('/api/logout', {method: 'POST'});
+    window.location.reload();
+}
+
+// 绑定页面事件（CSP 禁止内联事件处理器）
+document.getElementById('create-btn').addEventListener('click', openCreateModal);
+document.getElementById('reload-btn').addEventListener('click', reloadConfig);
+document.getElementById('save-btn').addEventListener('click', saveConfig);
+document.getElementById('logout-btn').addEventListener('click', logout);
+document.getElementById('test-btn').addEventListener('click', testURL);
+document.getElementById('cancel-btn').addEventListener('click', closeModal);
+document.getElementById('rule-form').addEventListener('submit', saveRule);
+document.getElementById('stale-cancel-btn').addEventListener('click', () => closeStaleDialog());
+document.getElementById('stale-current-btn').addEventListener('click', () => closeStaleDialog(staleActions.onUseCurrent));
+document.getElementById('stale-force-btn').addEventListener('click', () => closeStaleDialog(staleActions.onForce));
+document.getElementById('filter-form').addEventListener('submit', function(e) {
+    e.preventDefault();
+    reloadFirstPage();
+});
+['filter-type', 'filter-state', 'filter-sort', 'page-size', 'unused-days'].forEach(id => {
+    document.getElementById(id).addEventListener('change', reloadFirstPage);
+});
+document.getElementById('prev-page').addEventListener('click', prevPage);
+document.getElementById('next-page').addEventListener('click', nextPage);
+document.getElementById('stats-range').addEventListener('change', loadStats);
+document.getElementById('stats-refresh-btn').addEventListener('click', loadStats);
+document.getElementById('live-form').addEventListener('submit', toggleLive);
+document.getElementById('live-clear-btn').addEventListener('click', () => {
+    document.getElementById('live-tbody').replaceChildren();
+});
+
+// 页面加载时获取规则列表
+loadSession();
+loadRules();
+loadStats();
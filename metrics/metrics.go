@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// matchBuckets 规则匹配耗时的直方图桶（秒）
+var matchBuckets = []float64{0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01}
+
+// flushBuckets 日志刷新耗时的直方图桶（秒）
+var flushBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// 重新加载结果
+const (
+	ReloadSuccess = "success"
+	ReloadPartial = "partial" // 跳过了无效规则，其余规则已加载
+	ReloadFailure = "failure"
+)
+
+// Metrics MiniJump 的监控指标，以 Prometheus 文本格式输出
+// 方法在接收者为 nil 时不做任何事，未启用监控时无需判断
+type Metrics struct {
+	registry         Registry
+	redirects        *CounterVec
+	misses           *CounterVec
+	matchDuration    *HistogramVec
+	rules            *GaugeVec
+	logBuffer        *GaugeVec
+	logFlushDuration *HistogramVec
+	logFlushErrors   *CounterVec
+	logDropped       *GaugeVec
+	logPending       *GaugeVec
+	reloads          *CounterVec
+	apiRequests      *CounterVec
+}
+
+// New 创建监控指标
+func New() *Metrics {
+	m := &Metrics{}
+	r := &m.registry
+	m.redirects = r.NewCounterVec("minijump_redirects_total",
+		"Redirects served, by rule domain, redirect type and response status.", "domain", "type", "status")
+	m.misses = r.NewCounterVec("minijump_redirect_misses_total",
+		"Requests that matched no rule and were answered with 404.")
+	m.matchDuration = r.NewHistogramVec("minijump_match_duration_seconds",
+		"Time spent matching a request against the rules.", matchBuckets, "result")
+	m.rules = r.NewGaugeVec("minijump_rules",
+		"Number of rules currently loaded.")
+	m.logBuffer = r.NewGaugeVec("minijump_log_buffer_entries",
+		"Log entries queued in memory and not yet written to the sink.", "log", "sink")
+	m.logFlushDuration = r.NewHistogramVec("minijump_log_flush_duration_seconds",
+		"Time spent writing buffered log entries to the sink.", flushBuckets, "log", "sink")
+	m.logFlushErrors = r.NewCounterVec("minijump_log_flush_errors_total",
+		"Log flushes that failed to write to the sink.", "log", "sink")
+	m.logDropped = r.NewCounterFuncVec("minijump_log_dropped_total",
+		"Log entries dropped because the sink queue was full or they could not be written or kept for retry.", "log", "sink")
+	m.logPending = r.NewGaugeVec("minijump_log_pending_bytes",
+		"Bytes that failed to be written to the sink and are held for retry.", "log", "sink")
+	m.reloads = r.NewCounterVec("minijump_config_reloads_total",
+		"Rule reloads requested through the API, by result (success, partial, failure).", "result")
+	m.apiRequests = r.NewCounterVec("minijump_api_requests_total",
+		"Admin API requests, by method, route and response status.", "method", "route", "status")
+
+	m.misses.Init()
+	for _, result := range []string{ReloadSuccess, ReloadPartial, ReloadFailure} {
+		m.reloads.Init(result)
+	}
+
+	process := r.NewGaugeVec("minijump_start_time_seconds", "Start time of the process since unix epoch in seconds.")
+	start := float64(time.Now().Unix())
+	process.Set(func() float64 { return start })
+	goroutines := r.NewGaugeVec("go_goroutines", "Number of goroutines that currently exist.")
+	goroutines.Set(func() float64 { return float64(runtime.NumGoroutine()) })
+	return m
+}
+
+// Handler 返回输出全部指标的 HTTP 处理器
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 先输出到缓冲，避免输出一半时出错
+		var buf bytes.Buffer
+		m.registry.WriteTo(&buf)
+		w.Header().Set("Content-Type", ContentType)
+		w.Write(buf.Bytes())
+	})
+}
+
+// ObserveMatch 记录一次规则匹配的耗时
+func (m *Metrics) ObserveMatch(d time.Duration, hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.matchDuration.Observe(d.Seconds(), result)
+}
+
+// Redirect 记录一次跳转，domain 为规则的域名（而不是请求的 Host，避免标签无限增长）
+func (m *Metrics) Redirect(domain, redirectType string, status int) {
+	if m == nil {
+		return
+	}
+	m.redirects.Inc(domain, redirectType, strconv.Itoa(status))
+}
+
+// Miss 记录一次未命中任何规则的请求
+func (m *Metrics) Miss() {
+	if m == nil {
+		return
+	}
+	m.misses.Inc()
+}
+
+// SetRuleCount 设置获取当前规则数的回调
+func (m *Metrics) SetRuleCount(fn func() int) {
+	if m == nil {
+		return
+	}
+	m.rules.Set(func() float64 { return float64(fn()) })
+}
+
+// SetLogBuffer 设置获取日志队列长度的回调，name 区分访问日志、审计日志，sink 为输出目标名称
+func (m *Metrics) SetLogBuffer(name, sink string, fn func() int) {
+	if m == nil {
+		return
+	}
+	m.logBuffer.Set(func() float64 { return float64(fn()) }, name, sink)
+	m.logFlushErrors.Init(name, sink)
+}
+
+// SetLogDropped 设置获取日志丢弃条数的回调
+func (m *Metrics) SetLogDropped(name, sink string, fn func() int64) {
+	if m == nil {
+		return
+	}
+	m.logDropped.Set(func() float64 { return float64(fn()) }, name, sink)
+}
+
+// SetLogPending 设置获取等待重试字节数的回调
+func (m *Metrics) SetLogPending(name, sink string, fn func() int64) {
+	if m == nil {
+		return
+	}
+	m.logPending.Set(func() float64 { return float64(fn()) }, name, sink)
+}
+
+// ObserveLogFlush 记录一次日志刷新的耗时和结果
+func (m *Metrics) ObserveLogFlush(name, sink string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.logFlushDuration.Observe(d.Seconds(), name, sink)
+	if err != nil {
+		m.logFlushErrors.Inc(name, sink)
+	}
+}
+
+// Reload 记录一次重新加载规则的结果
+func (m *Metrics) Reload(result string) {
+	if m == nil {
+		return
+	}
+	m.reloads.Inc(result)
+}
+
+// APIRequest 记录一次管理 API 请求，route 为路由模板（如 /api/rules/{id}）
+func (m *Metrics) APIRequest(method, route string, status int) {
+	if m == nil {
+		return
+	}
+	m.apiRequests.Inc(method, route, strconv.Itoa(status))
+}
@@ -0,0 +1,285 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ContentType Prometheus 文本格式的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// labelSeparator 拼接标签值作为序列键（标签值中不会出现）
+const labelSeparator = "\xff"
+
+// collector 一个指标族，按 Prometheus 文本格式输出
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// register 注册指标族（按注册顺序输出）
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo 按 Prometheus 文本格式输出全部指标
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// CounterVec 按标签区分的计数器
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+	series sync.Map // 标签值键 -> *counterSeries
+}
+
+type counterSeries struct {
+	values []string
+	n      atomic.Uint64
+}
+
+// NewCounterVec 创建并注册计数器
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels}
+	r.register(c)
+	return c
+}
+
+// Inc 计数加一，values 与创建时的标签一一对应
+func (c *CounterVec) Inc(values ...string) {
+	c.get(values).n.Add(1)
+}
+
+// Init 创建计数为 0 的序列，使尚未发生的事件也能被查询到
+func (c *CounterVec) Init(values ...string) {
+	c.get(values)
+}
+
+// get 获取标签值对应的序列，不存在时创建
+func (c *CounterVec) get(values []string) *counterSeries {
+	key := strings.Join(values, labelSeparator)
+	if s, ok := c.series.Load(key); ok {
+		return s.(*counterSeries)
+	}
+	s, _ := c.series.LoadOrStore(key, &counterSeries{values: append([]string(nil), values...)})
+	return s.(*counterSeries)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	for _, s := range sortedSeries[*counterSeries](&c.series) {
+		writeSample(w, c.name, c.labels, s.values, "", "", float64(s.n.Load()))
+	}
+}
+
+// HistogramVec 按标签区分的直方图
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64 // 升序的桶上界（不含 +Inf）
+	series  sync.Map  // 标签值键 -> *histogramSeries
+}
+
+type histogramSeries struct {
+	values  []string
+	buckets []atomic.Uint64 // 各桶（非累计）的观测次数，最后一个为 +Inf
+	sum     atomic.Uint64   // float64 的位表示
+}
+
+// NewHistogramVec 创建并注册直方图
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets}
+	r.register(h)
+	return h
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	s := h.get(values)
+	s.buckets[sort.SearchFloat64s(h.buckets, v)].Add(1)
+	for {
+		old := s.sum.Load()
+		if s.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// get 获取标签值对应的序列，不存在时创建
+func (h *HistogramVec) get(values []string) *histogramSeries {
+	key := strings.Join(values, labelSeparator)
+	if s, ok := h.series.Load(key); ok {
+		return s.(*histogramSeries)
+	}
+	s, _ := h.series.LoadOrStore(key, &histogramSeries{
+		values:  append([]string(nil), values...),
+		buckets: make([]atomic.Uint64, len(h.buckets)+1),
+	})
+	return s.(*histogramSeries)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, s := range sortedSeries[*histogramSeries](&h.series) {
+		// 各桶按累计值输出，_count 与 +Inf 桶相同
+		var cumulative uint64
+		for i := range s.buckets {
+			cumulative += s.buckets[i].Load()
+			le := "+Inf"
+			if i < len(h.buckets) {
+				le = strconv.FormatFloat(h.buckets[i], 'g', -1, 64)
+			}
+			writeSample(w, h.name+"_bucket", h.labels, s.values, "le", le, float64(cumulative))
+		}
+		writeSample(w, h.name+"_sum", h.labels, s.values, "", "", math.Float64frombits(s.sum.Load()))
+		writeSample(w, h.name+"_count", h.labels, s.values, "", "", float64(cumulative))
+	}
+}
+
+// GaugeVec 按标签区分的仪表盘，取值在输出时通过回调获取
+type GaugeVec struct {
+	name   string
+	help   string
+	typ    string // gauge 或 counter
+	labels []string
+	series sync.Map // 标签值键 -> *gaugeSeries
+}
+
+type gaugeSeries struct {
+	values []string
+	fn     func() float64
+}
+
+// NewGaugeVec 创建并注册仪表盘
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{name: name, help: help, typ: "gauge", labels: labels}
+	r.register(g)
+	return g
+}
+
+// NewCounterFuncVec 创建并注册取值通过回调获取的计数器，用于其他组件自行维护的累计值
+func (r *Registry) NewCounterFuncVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{name: name, help: help, typ: "counter", labels: labels}
+	r.register(g)
+	return g
+}
+
+// Set 设置标签值对应序列的取值回调（已存在时替换）
+func (g *GaugeVec) Set(fn func() float64, values ...string) {
+	key := strings.Join(values, labelSeparator)
+	g.series.Store(key, &gaugeSeries{values: append([]string(nil), values...), fn: fn})
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	writeHeader(w, g.name, g.help, g.typ)
+	for _, s := range sortedSeries[*gaugeSeries](&g.series) {
+		writeSample(w, g.name, g.labels, s.values, "", "", s.fn())
+	}
+}
+
+// sortedSeries 按标签值键排序返回全部序列，保证输出稳定
+func sortedSeries[T any](m *sync.Map) []T {
+	var keys []string
+	values := make(map[string]T)
+	m.Range(func(key, value interface{}) bool {
+		keys = append(keys, key.(string))
+		values[key.(string)] = value.(T)
+		return true
+	})
+	sort.Strings(keys)
+	result := make([]T, len(keys))
+	for i, key := range keys {
+		result[i] = values[key]
+	}
+	return result
+}
+
+// writeHeader 输出指标族的 HELP 和 TYPE 行
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	w.WriteString("# HELP " + name + " " + helpReplacer.Replace(help) + "\n")
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// writeSample 输出一个样本，extraName 非空时追加一个标签（如直方图的 le）
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			var value string
+			if i < len(values) {
+				value = values[i]
+			}
+			w.WriteString(label + `="` + labelReplacer.Replace(value) + `"`)
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatValue(v))
+	w.WriteByte('\n')
+}
+
+// formatValue 格式化样本值
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// countingWriter 统计写入的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
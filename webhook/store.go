@@ -0,0 +1,199 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	endpointsBucket  = []byte("endpoints")
+	deliveriesBucket = []byte("deliveries")
+)
+
+// maxDeliveryLog 保留的已完成投递记录数（超过时删除最早的记录）
+const maxDeliveryLog = 1000
+
+// store 基于 bbolt 的 Webhook 持久化存储
+// 投递记录按 ID（时间有序）存放，未完成的记录即为待投递队列
+type store struct {
+	db *bolt.DB
+}
+
+// openStore 打开（或创建）存储文件
+func openStore(path string) (*store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{endpointsBucket, deliveriesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &store{db: db}, nil
+}
+
+// endpoints 返回全部端点
+func (s *store) endpoints() ([]*Endpoint, error) {
+	var endpoints []*Endpoint
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(endpointsBucket).ForEach(func(k, v []byte) error {
+			var ep Endpoint
+			if err := json.Unmarshal(v, &ep); err != nil {
+				return err
+			}
+			endpoints = append(endpoints, &ep)
+			return nil
+		})
+	})
+	return endpoints, err
+}
+
+// endpoint 按 ID 获取端点
+func (s *store) endpoint(id string) (*Endpoint, bool, error) {
+	var ep *Endpoint
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(endpointsBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		ep = &Endpoint{}
+		return json.Unmarshal(data, ep)
+	})
+	return ep, ep != nil, err
+}
+
+// putEndpoint 写入端点
+func (s *store) putEndpoint(ep *Endpoint) error {
+	data, err := json.Marshal(ep)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(endpointsBucket).Put([]byte(ep.ID), data)
+	})
+}
+
+// deleteEndpoint 删除端点，返回端点是否存在
+func (s *store) deleteEndpoint(id string) (bool, error) {
+	found := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(endpointsBucket)
+		found = b.Get([]byte(id)) != nil
+		return b.Delete([]byte(id))
+	})
+	return found, err
+}
+
+// putDeliveries 在单个事务内写入投递记录，并清理超出保留数量的已完成记录
+func (s *store) putDeliveries(deliveries ...*Delivery) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(deliveriesBucket)
+		for _, d := range deliveries {
+			data, err := json.Marshal(d)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(d.ID), data); err != nil {
+				return err
+			}
+		}
+		return trimDeliveries(b)
+	})
+}
+
+// trimDeliveries 从最早的记录开始删除已完成的记录，直到数量不超过 maxDeliveryLog
+// 待投递的记录不会被删除
+func trimDeliveries(b *bolt.Bucket) error {
+	c := b.Cursor()
+	count := 0
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		count++
+	}
+	excess := count - maxDeliveryLog
+	if excess <= 0 {
+		return nil
+	}
+	var stale [][]byte
+	for k, v := c.First(); k != nil && len(stale) < excess; k, v = c.Next() {
+		var d Delivery
+		if json.Unmarshal(v, &d) != nil || d.Status != StatusPending {
+			stale = append(stale, append([]byte(nil), k...))
+		}
+	}
+	for _, k := range stale {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delivery 按 ID 获取投递记录
+func (s *store) delivery(id string) (*Delivery, bool, error) {
+	var d *Delivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(deliveriesBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		d = &Delivery{}
+		return json.Unmarshal(data, d)
+	})
+	return d, d != nil, err
+}
+
+// deliveries 按时间倒序返回满足条件的投递记录
+func (s *store) deliveries(filter DeliveryFilter) ([]*Delivery, error) {
+	var deliveries []*Delivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(deliveriesBucket).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var d Delivery
+			if err := json.Unmarshal(v, &d); err != nil {
+				continue
+			}
+			if filter.EndpointID != "" && d.EndpointID != filter.EndpointID {
+				continue
+			}
+			if filter.Status != "" && d.Status != filter.Status {
+				continue
+			}
+			deliveries = append(deliveries, &d)
+			if filter.Limit > 0 && len(deliveries) >= filter.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	return deliveries, err
+}
+
+// pending 返回全部待投递记录（按创建顺序）
+func (s *store) pending() ([]*Delivery, error) {
+	var deliveries []*Delivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(deliveriesBucket).ForEach(func(k, v []byte) error {
+			var d Delivery
+			if err := json.Unmarshal(v, &d); err == nil && d.Status == StatusPending {
+				deliveries = append(deliveries, &d)
+			}
+			return nil
+		})
+	})
+	return deliveries, err
+}
+
+// close 关闭存储
+func (s *store) close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,534 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"mini_jump/config"
+)
+
+// 事件类型
+const (
+	EventRuleCreated    = "rule.created"
+	EventRuleUpdated    = "rule.updated"
+	EventRuleDeleted    = "rule.deleted"
+	EventRuleExpired    = "rule.expired"
+	EventConfigReloaded = "config.reloaded"
+	EventPing           = "ping" // 测试投递，发送给指定端点，不受订阅的事件限制（端点停用时不发送）
+)
+
+// Events 可订阅的事件类型
+var Events = []string{EventRuleCreated, EventRuleUpdated, EventRuleDeleted, EventRuleExpired, EventConfigReloaded}
+
+// 投递状态
+const (
+	StatusPending   = "pending"   // 等待投递或重试
+	StatusDelivered = "delivered" // 投递成功
+	StatusFailed    = "failed"    // 重试次数用尽，或端点已删除、已停用
+)
+
+// 投递请求头
+const (
+	HeaderEvent     = "X-MiniJump-Event"
+	HeaderDelivery  = "X-MiniJump-Delivery"
+	HeaderTimestamp = "X-MiniJump-Timestamp"
+	HeaderSignature = "X-MiniJump-Signature"
+)
+
+// 重试策略
+const (
+	maxAttempts    = 8                // 最多投递次数
+	retryBaseDelay = 10 * time.Second // 首次重试间隔，之后每次翻倍
+	retryMaxDelay  = time.Hour        // 最大重试间隔
+	requestTimeout = 10 * time.Second // 单次投递超时
+	maxErrorBody   = 512              // 失败时记录的响应体长度
+)
+
+// Endpoint Webhook 端点
+type Endpoint struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`              // 接收地址（http/https）
+	Secret      string    `json:"secret,omitempty"` // HMAC 签名密钥
+	Events      []string  `json:"events"`           // 订阅的事件（为空表示全部）
+	Active      bool      `json:"active"`           // 是否启用
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Redacted 返回隐藏密钥的副本（用于接口返回）
+func (e Endpoint) Redacted() Endpoint {
+	e.Secret = ""
+	return e
+}
+
+// Subscribes 判断端点是否订阅了指定事件
+func (e *Endpoint) Subscribes(eventType string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, t := range e.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 校验端点配置
+func (e *Endpoint) Validate() config.ValidationErrors {
+	var errs config.ValidationErrors
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, config.FieldError{Field: "url", Message: "必须是 http 或 https 开头且包含主机名的 URL"})
+	}
+	for _, t := range e.Events {
+		if !isEvent(t) {
+			errs = append(errs, config.FieldError{Field: "events", Message: fmt.Sprintf("未知的事件类型 %q", t)})
+		}
+	}
+	return errs
+}
+
+// isEvent 判断是否为可订阅的事件类型
+func isEvent(eventType string) bool {
+	for _, t := range Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event 规则变更事件（即投递的 JSON 内容）
+type Event struct {
+	ID        string               `json:"id"`
+	Type      string               `json:"type"`
+	Timestamp time.Time            `json:"timestamp"`
+	Actor     string               `json:"actor,omitempty"`    // 操作者（过期、启动等系统事件为空）
+	RuleID    string               `json:"rule_id,omitempty"`  // 变更的规则 ID
+	Rule      *config.RedirectRule `json:"rule,omitempty"`     // 变更后的规则（删除时为删除前的规则）
+	Previous  *config.RedirectRule `json:"previous,omitempty"` // 更新前的规则
+}
+
+// Delivery 投递记录
+type Delivery struct {
+	ID             string          `json:"id"`
+	EndpointID     string          `json:"endpoint_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttempt    time.Time       `json:"next_attempt"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	CompletedAt    *time.Time      `json:"completed_at,omitempty"`
+}
+
+// DeliveryFilter 投递记录查询条件
+type DeliveryFilter struct {
+	EndpointID string
+	Status     string
+	Limit      int
+}
+
+// Dispatcher Webhook 投递器
+// 事件按端点生成投递记录并持久化，后台协程按退避策略投递，重启后继续投递未完成的记录
+// 各端点独立投递，一个端点响应慢或超时不影响其他端点
+// 为 nil 时表示未启用 Webhook，Emit 直接忽略
+type Dispatcher struct {
+	store  *store
+	client *http.Client
+	wake   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	busyMu sync.Mutex
+	busy   map[string]bool // 正在投递的端点
+}
+
+// NewDispatcher 打开 Webhook 存储并启动后台投递
+func NewDispatcher(path string) (*Dispatcher, error) {
+	s, err := openStore(path)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		store:  s,
+		client: &http.Client{Timeout: requestTimeout},
+		wake:   make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+		busy:   make(map[string]bool),
+	}
+	d.wg.Add(1)
+	go d.run()
+	return d, nil
+}
+
+// Close 停止投递并关闭存储，未完成的投递在下次启动时继续
+func (d *Dispatcher) Close() error {
+	if d == nil {
+		return nil
+	}
+	d.cancel()
+	d.wg.Wait()
+	return d.store.close()
+}
+
+// Emit 为订阅了事件的启用端点生成投递记录
+// 同一请求产生的多个事件只读取一次端点，并在一个事务中写入全部投递记录
+func (d *Dispatcher) Emit(events ...*Event) {
+	if d == nil || len(events) == 0 {
+		return
+	}
+	endpoints, err := d.store.endpoints()
+	if err != nil {
+		log.Printf("Webhook: failed to load endpoints: %v\n", err)
+		return
+	}
+	var deliveries []*Delivery
+	for _, event := range events {
+		var targets []*Endpoint
+		for _, ep := range endpoints {
+			if ep.Active && ep.Subscribes(event.Type) {
+				targets = append(targets, ep)
+			}
+		}
+		// 投递记录按 ID（含创建时间）排序，每个事件单独取时间以保持事件顺序
+		batch, err := newDeliveries(event, targets, time.Now())
+		if err != nil {
+			log.Printf("Webhook: failed to queue %s event: %v\n", event.Type, err)
+			continue
+		}
+		deliveries = append(deliveries, batch...)
+	}
+	if err := d.save(deliveries); err != nil {
+		log.Printf("Webhook: failed to queue %d deliveries: %v\n", len(deliveries), err)
+	}
+}
+
+// Ping 向指定端点发送测试事件，返回投递记录
+// 与其他投递一样，端点停用时标记为失败，不发送
+func (d *Dispatcher) Ping(endpointID string) (*Delivery, error) {
+	ep, ok, err := d.store.endpoint(endpointID)
+	if err != nil || !ok {
+		return nil, err
+	}
+	deliveries, err := newDeliveries(&Event{Type: EventPing}, []*Endpoint{ep}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := d.save(deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries[0], nil
+}
+
+// save 持久化投递记录并唤醒投递协程
+func (d *Dispatcher) save(deliveries []*Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := d.store.putDeliveries(deliveries...); err != nil {
+		return err
+	}
+	d.notify()
+	return nil
+}
+
+// newDeliveries 为事件的每个目标端点创建待投递记录
+func newDeliveries(event *Event, endpoints []*Endpoint, now time.Time) ([]*Delivery, error) {
+	if len(endpoints) == 0 {
+		return nil, nil
+	}
+	if event.ID == "" {
+		event.ID = newID(now)
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = now
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*Delivery, 0, len(endpoints))
+	for _, ep := range endpoints {
+		deliveries = append(deliveries, &Delivery{
+			ID:          newID(now),
+			EndpointID:  ep.ID,
+			EventID:     event.ID,
+			EventType:   event.Type,
+			Payload:     payload,
+			Status:      StatusPending,
+			NextAttempt: now,
+			CreatedAt:   now,
+		})
+	}
+	return deliveries, nil
+}
+
+// Endpoints 返回全部端点
+func (d *Dispatcher) Endpoints() ([]*Endpoint, error) {
+	return d.store.endpoints()
+}
+
+// Endpoint 按 ID 获取端点
+func (d *Dispatcher) Endpoint(id string) (*Endpoint, bool, error) {
+	return d.store.endpoint(id)
+}
+
+// SaveEndpoint 创建或更新端点，ID 为空时自动生成，密钥为空时自动生成
+func (d *Dispatcher) SaveEndpoint(ep *Endpoint) error {
+	if ep.ID == "" {
+		ep.ID = newID(time.Now())
+	}
+	if ep.Secret == "" {
+		ep.Secret = randomHex(32)
+	}
+	if ep.CreatedAt.IsZero() {
+		ep.CreatedAt = time.Now()
+	}
+	return d.store.putEndpoint(ep)
+}
+
+// DeleteEndpoint 删除端点，其未完成的投递会被标记为失败
+func (d *Dispatcher) DeleteEndpoint(id string) (bool, error) {
+	found, err := d.store.deleteEndpoint(id)
+	if found {
+		d.notify()
+	}
+	return found, err
+}
+
+// Deliveries 按时间倒序查询投递记录
+func (d *Dispatcher) Deliveries(filter DeliveryFilter) ([]*Delivery, error) {
+	return d.store.deliveries(filter)
+}
+
+// Redeliver 将投递记录重新放入队列（重置重试次数）
+func (d *Dispatcher) Redeliver(id string) (*Delivery, bool, error) {
+	delivery, ok, err := d.store.delivery(id)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	delivery.Status = StatusPending
+	delivery.Attempts = 0
+	delivery.NextAttempt = time.Now()
+	delivery.CompletedAt = nil
+	if err := d.store.putDeliveries(delivery); err != nil {
+		return nil, true, err
+	}
+	d.notify()
+	return delivery, true, nil
+}
+
+// notify 唤醒投递协程
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run 投递循环：处理到期的投递，然后等待下一次到期或新事件
+func (d *Dispatcher) run() {
+	defer d.wg.Done()
+	for {
+		wait := d.deliverDue()
+		timer := time.NewTimer(wait)
+		select {
+		case <-d.ctx.Done():
+			timer.Stop()
+			return
+		case <-d.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// deliverDue 为每个有到期记录的端点启动投递协程，返回距离下一次到期的等待时间
+// 正在投递的端点跳过，其协程结束时会再次唤醒投递循环
+func (d *Dispatcher) deliverDue() time.Duration {
+	// 先取正在投递的端点再读取记录，避免读到协程刚投递完、尚未更新的记录而重复投递
+	busy := d.busyEndpoints()
+	pending, err := d.store.pending()
+	if err != nil {
+		log.Printf("Webhook: failed to load pending deliveries: %v\n", err)
+		return retryBaseDelay
+	}
+
+	wait := retryMaxDelay
+	due := make(map[string][]*Delivery)
+	for _, delivery := range pending {
+		if busy[delivery.EndpointID] {
+			continue
+		}
+		if until := time.Until(delivery.NextAttempt); until > 0 {
+			wait = min(wait, until)
+			continue
+		}
+		due[delivery.EndpointID] = append(due[delivery.EndpointID], delivery)
+	}
+
+	d.busyMu.Lock()
+	for endpointID := range due {
+		d.busy[endpointID] = true
+	}
+	d.busyMu.Unlock()
+	for endpointID, deliveries := range due {
+		d.wg.Add(1)
+		go d.deliverEndpoint(endpointID, deliveries)
+	}
+	return max(wait, 0)
+}
+
+// busyEndpoints 返回正在投递的端点
+func (d *Dispatcher) busyEndpoints() map[string]bool {
+	d.busyMu.Lock()
+	defer d.busyMu.Unlock()
+	busy := make(map[string]bool, len(d.busy))
+	for endpointID := range d.busy {
+		busy[endpointID] = true
+	}
+	return busy
+}
+
+// deliverEndpoint 按创建顺序投递同一端点的到期记录，结束后唤醒投递循环
+func (d *Dispatcher) deliverEndpoint(endpointID string, deliveries []*Delivery) {
+	defer d.wg.Done()
+	defer func() {
+		d.busyMu.Lock()
+		delete(d.busy, endpointID)
+		d.busyMu.Unlock()
+		d.notify()
+	}()
+
+	for _, delivery := range deliveries {
+		d.attempt(delivery)
+		if d.ctx.Err() != nil {
+			// 关闭期间中断的投递保持原状，下次启动时重试
+			return
+		}
+		if err := d.store.putDeliveries(delivery); err != nil {
+			log.Printf("Webhook: failed to save delivery %s: %v\n", delivery.ID, err)
+		}
+	}
+}
+
+// attempt 执行一次投递并更新记录状态
+func (d *Dispatcher) attempt(delivery *Delivery) {
+	ep, ok, err := d.store.endpoint(delivery.EndpointID)
+	if err != nil {
+		delivery.LastError = err.Error()
+		delivery.NextAttempt = time.Now().Add(retryBaseDelay)
+		return
+	}
+	now := time.Now()
+	if !ok || !ep.Active {
+		// 停用的端点与已删除的端点一样不再发送，重新启用后可以手动重新投递
+		delivery.Status = StatusFailed
+		delivery.LastError = "端点已删除"
+		if ok {
+			delivery.LastError = "端点已停用"
+		}
+		delivery.CompletedAt = &now
+		return
+	}
+
+	delivery.Attempts++
+	status, err := d.send(ep, delivery)
+	delivery.LastStatusCode = status
+	now = time.Now()
+	if err == nil {
+		delivery.Status = StatusDelivered
+		delivery.LastError = ""
+		delivery.CompletedAt = &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = StatusFailed
+		delivery.CompletedAt = &now
+		log.Printf("Webhook: delivery %s to %s failed after %d attempts: %v\n", delivery.ID, ep.URL, delivery.Attempts, err)
+		return
+	}
+	delivery.NextAttempt = now.Add(backoff(delivery.Attempts))
+}
+
+// send 发送签名的投递请求，2xx 视为成功
+func (d *Dispatcher) send(ep *Endpoint, delivery *Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, ep.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MiniJump-Webhook")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(ep.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign 计算投递签名：sha256=HMAC-SHA256(secret, timestamp + "." + body) 的十六进制
+// 接收方应使用相同方法计算并以常量时间比较，同时检查时间戳防止重放
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff 第 n 次失败后的重试间隔
+func backoff(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
+
+// newID 生成按时间排序的 ID
+func newID(t time.Time) string {
+	return fmt.Sprintf("%016x%s", t.UnixNano(), randomHex(4))
+}
+
+// randomHex 生成指定字节数的随机十六进制串
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
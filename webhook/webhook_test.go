@@ -0,0 +1,344 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// received 接收方收到的一次投递
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver 本地 Webhook 接收方，按 status 返回状态码，收到的请求依次放入 requests
+type receiver struct {
+	*httptest.Server
+	status   atomic.Int32
+	requests chan received
+}
+
+// newReceiver 启动返回 200 的接收方
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{requests: make(chan received, 16)}
+	r.status.Store(http.StatusOK)
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.requests <- received{header: req.Header.Clone(), body: body}
+		w.WriteHeader(int(r.status.Load()))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// next 等待下一次投递
+func (r *receiver) next(t *testing.T) received {
+	t.Helper()
+	select {
+	case req := <-r.requests:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery received")
+		return received{}
+	}
+}
+
+// none 确认一段时间内没有收到投递
+func (r *receiver) none(t *testing.T) {
+	t.Helper()
+	select {
+	case req := <-r.requests:
+		t.Fatalf("unexpected delivery %s", req.header.Get(HeaderDelivery))
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// openDispatcher 在临时目录中打开投递器，测试结束时关闭
+func openDispatcher(t *testing.T, path string) *Dispatcher {
+	t.Helper()
+	d, err := NewDispatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+// addEndpoint 注册指向 url 的启用端点
+func addEndpoint(t *testing.T, d *Dispatcher, url string) *Endpoint {
+	t.Helper()
+	ep := &Endpoint{URL: url, Secret: "test-secret", Active: true}
+	if err := d.SaveEndpoint(ep); err != nil {
+		t.Fatal(err)
+	}
+	return ep
+}
+
+// waitDelivery 等待投递记录满足条件
+func waitDelivery(t *testing.T, d *Dispatcher, id string, done func(*Delivery) bool) *Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		delivery, ok, err := d.store.delivery(id)
+		if err != nil || !ok {
+			t.Fatalf("delivery %s: found=%v err=%v", id, ok, err)
+		}
+		if done(delivery) {
+			return delivery
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivery %s: status=%s attempts=%d last_error=%q", id, delivery.Status, delivery.Attempts, delivery.LastError)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDeliverySignature(t *testing.T) {
+	recv := newReceiver(t)
+	d := openDispatcher(t, filepath.Join(t.TempDir(), "webhooks.db"))
+	ep := addEndpoint(t, d, recv.URL)
+
+	d.Emit(&Event{Type: EventRuleCreated, RuleID: "a"})
+	req := recv.next(t)
+
+	if got := req.header.Get(HeaderEvent); got != EventRuleCreated {
+		t.Errorf("%s = %q, want %q", HeaderEvent, got, EventRuleCreated)
+	}
+	if req.header.Get(HeaderDelivery) == "" {
+		t.Errorf("missing %s", HeaderDelivery)
+	}
+	timestamp := req.header.Get(HeaderTimestamp)
+	mac := hmac.New(sha256.New, []byte(ep.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(req.body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := req.header.Get(HeaderSignature); got != want {
+		t.Errorf("%s = %q, want %q", HeaderSignature, got, want)
+	}
+	if got := Sign("other-secret", timestamp, req.body); got == want {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+func TestRetryAfterServerError(t *testing.T) {
+	recv := newReceiver(t)
+	recv.status.Store(http.StatusInternalServerError)
+	d := openDispatcher(t, filepath.Join(t.TempDir(), "webhooks.db"))
+	addEndpoint(t, d, recv.URL)
+
+	d.Emit(&Event{Type: EventRuleUpdated, RuleID: "a"})
+	id := recv.next(t).header.Get(HeaderDelivery)
+	failed := waitDelivery(t, d, id, func(dl *Delivery) bool { return dl.Attempts == 1 && dl.LastStatusCode != 0 })
+	if failed.Status != StatusPending || failed.LastStatusCode != http.StatusInternalServerError || failed.LastError == "" {
+		t.Fatalf("after 500: status=%s code=%d error=%q", failed.Status, failed.LastStatusCode, failed.LastError)
+	}
+	if wait := time.Until(failed.NextAttempt); wait < retryBaseDelay-time.Second || wait > retryBaseDelay {
+		t.Errorf("next attempt in %v, want about %v", wait, retryBaseDelay)
+	}
+	recv.none(t)
+
+	// 将重试时间提前到现在，模拟退避间隔已过
+	recv.status.Store(http.StatusOK)
+	failed.NextAttempt = time.Now()
+	if err := d.store.putDeliveries(failed); err != nil {
+		t.Fatal(err)
+	}
+	d.notify()
+	if got := recv.next(t).header.Get(HeaderDelivery); got != id {
+		t.Errorf("retried delivery %s, want %s", got, id)
+	}
+	delivered := waitDelivery(t, d, id, func(dl *Delivery) bool { return dl.Status != StatusPending })
+	if delivered.Status != StatusDelivered || delivered.Attempts != 2 || delivered.LastError != "" {
+		t.Errorf("after retry: status=%s attempts=%d error=%q", delivered.Status, delivered.Attempts, delivered.LastError)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for _, tt := range []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, retryBaseDelay},
+		{2, 2 * retryBaseDelay},
+		{3, 4 * retryBaseDelay},
+		{maxAttempts, retryBaseDelay << (maxAttempts - 1)},
+		{100, retryMaxDelay},
+	} {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDeliverySurvivesRestart(t *testing.T) {
+	recv := newReceiver(t)
+	path := filepath.Join(t.TempDir(), "webhooks.db")
+
+	// 只持久化投递记录、不启动投递协程，模拟事件入队后服务立即退出
+	s, err := openStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped := &Dispatcher{store: s, wake: make(chan struct{}, 1)}
+	addEndpoint(t, stopped, recv.URL)
+	stopped.Emit(&Event{Type: EventRuleDeleted, RuleID: "a"})
+	pending, err := s.pending()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending = %d, err = %v", len(pending), err)
+	}
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d := openDispatcher(t, path)
+	if got := recv.next(t).header.Get(HeaderDelivery); got != pending[0].ID {
+		t.Errorf("delivered %s, want %s", got, pending[0].ID)
+	}
+	delivered := waitDelivery(t, d, pending[0].ID, func(dl *Delivery) bool { return dl.Status != StatusPending })
+	if delivered.Status != StatusDelivered {
+		t.Errorf("status = %s, want %s", delivered.Status, StatusDelivered)
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	recv := newReceiver(t)
+	d := openDispatcher(t, filepath.Join(t.TempDir(), "webhooks.db"))
+	ep := addEndpoint(t, d, recv.URL)
+
+	delivery, err := d.Ping(ep.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := recv.next(t)
+	waitDelivery(t, d, delivery.ID, func(dl *Delivery) bool { return dl.Status == StatusDelivered })
+
+	if _, ok, err := d.Redeliver(delivery.ID); !ok || err != nil {
+		t.Fatalf("Redeliver: found=%v err=%v", ok, err)
+	}
+	second := recv.next(t)
+	if got := second.header.Get(HeaderDelivery); got != delivery.ID {
+		t.Errorf("redelivered %s, want %s", got, delivery.ID)
+	}
+	if string(second.body) != string(first.body) {
+		t.Errorf("redelivered payload %s, want %s", second.body, first.body)
+	}
+	redelivered := waitDelivery(t, d, delivery.ID, func(dl *Delivery) bool { return dl.Status == StatusDelivered })
+	if redelivered.Attempts != 1 {
+		t.Errorf("attempts = %d, want 1 (reset by redeliver)", redelivered.Attempts)
+	}
+
+	if _, ok, err := d.Redeliver("missing"); ok || err != nil {
+		t.Errorf("Redeliver(missing): found=%v err=%v", ok, err)
+	}
+}
+
+func TestInactiveEndpointNotDelivered(t *testing.T) {
+	recv := newReceiver(t)
+	d := openDispatcher(t, filepath.Join(t.TempDir(), "webhooks.db"))
+	ep := addEndpoint(t, d, recv.URL)
+	ep.Active = false
+	if err := d.SaveEndpoint(ep); err != nil {
+		t.Fatal(err)
+	}
+
+	// 停用的端点不订阅新事件
+	d.Emit(&Event{Type: EventRuleCreated, RuleID: "a"})
+	// 停用前已入队或手动发送的投递标记为失败，不发送
+	delivery, err := d.Ping(ep.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := waitDelivery(t, d, delivery.ID, func(dl *Delivery) bool { return dl.Status != StatusPending })
+	if failed.Status != StatusFailed || failed.LastError != "端点已停用" || failed.Attempts != 0 {
+		t.Errorf("status=%s error=%q attempts=%d", failed.Status, failed.LastError, failed.Attempts)
+	}
+	recv.none(t)
+}
+
+func TestEmitBatchKeepsOrder(t *testing.T) {
+	s, err := openStore(filepath.Join(t.TempDir(), "webhooks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.close() })
+	stopped := &Dispatcher{store: s, wake: make(chan struct{}, 1)}
+	addEndpoint(t, stopped, "http://a.invalid/")
+	addEndpoint(t, stopped, "http://b.invalid/")
+
+	// 一次写入两个端点 × 三个事件的投递记录，按事件顺序排列
+	stopped.Emit(
+		&Event{Type: EventRuleCreated, RuleID: "1"},
+		&Event{Type: EventRuleUpdated, RuleID: "2"},
+		&Event{Type: EventRuleDeleted, RuleID: "3"},
+	)
+	pending, err := s.pending()
+	if err != nil || len(pending) != 6 {
+		t.Fatalf("pending = %d, err = %v", len(pending), err)
+	}
+	var events []string
+	for _, delivery := range pending {
+		if n := len(events); n == 0 || events[n-1] != delivery.EventType {
+			events = append(events, delivery.EventType)
+		}
+	}
+	if want := []string{EventRuleCreated, EventRuleUpdated, EventRuleDeleted}; !reflect.DeepEqual(events, want) {
+		t.Errorf("delivery order = %v, want %v", events, want)
+	}
+}
+
+func TestSlowEndpointDoesNotBlockOthers(t *testing.T) {
+	release := make(chan struct{})
+	var slowCalls atomic.Int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		slowCalls.Add(1)
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+	fast := newReceiver(t)
+	d := openDispatcher(t, filepath.Join(t.TempDir(), "webhooks.db"))
+	addEndpoint(t, d, slow.URL)
+	addEndpoint(t, d, fast.URL)
+
+	// 慢端点的投递进行中时，其他端点照常收到之后的事件
+	d.Emit(&Event{Type: EventRuleCreated, RuleID: "a"})
+	if got := fast.next(t).header.Get(HeaderEvent); got != EventRuleCreated {
+		t.Fatalf("event = %s", got)
+	}
+	d.Emit(&Event{Type: EventRuleDeleted, RuleID: "a"})
+	if got := fast.next(t).header.Get(HeaderEvent); got != EventRuleDeleted {
+		t.Fatalf("event = %s", got)
+	}
+
+	// 慢端点完成后依次投递两个事件，各只发送一次
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pending, err := d.store.pending()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d deliveries still pending", len(pending))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := slowCalls.Load(); n != 2 {
+		t.Errorf("slow endpoint received %d requests, want 2", n)
+	}
+	fast.none(t)
+}